@@ -1,15 +1,42 @@
 package argonhasher
 
+import (
+	"fmt"
+
+	"github.com/markstanden/securerandom"
+)
+
+/*
+	The minimum salt and key lengths (in bytes) accepted by KDFconfig.Encode
+*/
+const (
+	MinSaltLength = 16
+	MinKeyLen     = 16
+)
+
+/*
+	The maximum time and memory (in kilobytes) accepted by KDFconfig.Encode and ParseHash.
+	A stored hash is read from the database, so a corrupted or crafted hash must not
+	be able to make Confirm allocate terabytes of memory, or loop for hours.
+*/
+const (
+	MaxTime   = 128
+	MaxMemory = 4 * 1024 * 1024
+)
+
 /*
 	KDFconfig is the base struct for argonhasher, the Argon2id wrapper.
 	It uses the standard library's argon2 IDKey function:
 	func IDKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte
 	$argon2id$v=19$t=10,m=65536,p=8$SALT$HASH
+	Hashes created with a pepper also record the pepper version:
+	$argon2id$v=19$t=10,m=65536,p=8,keyid=PEPPERID$SALT$HASH
 */
 type KDFconfig struct {
 
 	/*
 		Salt is the base64 string used to salt our derived keys.
+		It is populated by ParseHash, Encode always generates a new salt.
 	*/
 	Salt []byte
 
@@ -42,4 +69,84 @@ type KDFconfig struct {
 		128 bit (16 bytes) sufficient for most applications
 	*/
 	KeyLen uint32
+
+	/*
+		PepperID is the version of the pepper mixed into new hashes.
+		It is recorded in the hash as the PHC keyid argument so the same
+		pepper can be looked up when the hash is confirmed.
+		An empty PepperID creates hashes without a pepper.
+	*/
+	PepperID string
+
+	/*
+		Pepper is the callback used to obtain a particular version of the pepper,
+		a server side secret stored away from the hashes.
+		It is called with the PepperID when encoding,
+		and with the keyid recorded in the hash when confirming,
+		so hashes made with older pepper versions can be confirmed during rotation.
+		The callback should return an empty string if the version cannot be found.
+	*/
+	Pepper func(pepperID string) (pepper string)
+
+	/*
+		Random generates the salt for new hashes.
+		The zero value uses crypto/rand, tests can supply a deterministic
+		or failing source to exercise ErrSaltGeneration.
+	*/
+	Random securerandom.Generator
+}
+
+/*
+	RecommendedConfig returns the OWASP recommended minimum argon2id configuration
+	m=19MiB, t=2, p=1
+	with a 16 byte salt and a 32 byte key.
+	It is intended for memory constrained hosts, where more memory is available
+	Calibrate can be used to find stronger parameters.
+*/
+func RecommendedConfig() KDFconfig {
+	return KDFconfig{
+		SaltLength: 16,
+		Time:       2,
+		Memory:     19 * 1024,
+		Threads:    1,
+		KeyLen:     32,
+	}
+}
+
+/*
+	Validate checks that the config parameters are within safe bounds
+	for creating a new hash, returning an error describing the first
+	parameter found to be out of bounds.
+*/
+func (c KDFconfig) Validate() error {
+	if c.SaltLength < MinSaltLength {
+		return fmt.Errorf("%w: %d bytes, minimum %d", ErrSaltTooShort, c.SaltLength, MinSaltLength)
+	}
+	if c.KeyLen < MinKeyLen {
+		return fmt.Errorf("%w: %d bytes, minimum %d", ErrKeyTooShort, c.KeyLen, MinKeyLen)
+	}
+	if c.Time < 1 {
+		return ErrInvalidTime
+	}
+	if c.Threads < 1 {
+		return ErrInvalidThreads
+	}
+	if c.Memory < 8*uint32(c.Threads) {
+		return fmt.Errorf("%w: m=%d, p=%d", ErrInsufficientMemory, c.Memory, c.Threads)
+	}
+	if c.Time > MaxTime {
+		return fmt.Errorf("%w: t=%d, maximum %d", ErrExcessiveTime, c.Time, MaxTime)
+	}
+	if c.Memory > MaxMemory {
+		return fmt.Errorf("%w: m=%d, maximum %d", ErrExcessiveMemory, c.Memory, MaxMemory)
+	}
+	if c.PepperID != "" {
+		if !validPepperID(c.PepperID) {
+			return fmt.Errorf("%w: %q", ErrInvalidPepperID, c.PepperID)
+		}
+		if c.Pepper == nil {
+			return ErrFailedPepper
+		}
+	}
+	return nil
 }
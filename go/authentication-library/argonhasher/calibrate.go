@@ -0,0 +1,112 @@
+package argonhasher
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+/*
+	measure times a single argon2id key derivation using the config parameters.
+	It is a variable so the tests can replace the host's hashing speed.
+*/
+var measure = func(c KDFconfig) time.Duration {
+	salt := make([]byte, c.SaltLength)
+	start := time.Now()
+	argon2.IDKey([]byte("calibrate"), salt, c.Time, c.Memory, c.Threads, c.KeyLen)
+	return time.Since(start)
+}
+
+/*
+	Calibrate benchmarks argon2id on the current host, and returns the strongest
+	KDFconfig that hashes a password within the target duration,
+	using at most maxMemoryKiB of memory and the supplied number of threads.
+	Memory is the preferred resource, so the full memory budget is used and the time
+	(iterations) increased until the target is reached.  If a single iteration
+	using the full budget exceeds the target, the memory is halved until it fits.
+	The time is capped at MaxTime, so a small memory budget on a fast host may finish early.
+	The returned config uses a 16 byte salt and a 32 byte key.
+	Calibrate returns ErrCalibrationFailed if even the minimum memory for
+	the number of threads cannot be hashed within the target.
+*/
+func Calibrate(targetDuration time.Duration, maxMemoryKiB uint32, threads uint8) (config KDFconfig, err error) {
+
+	config = KDFconfig{
+		SaltLength: 16,
+		Time:       1,
+		Memory:     maxMemoryKiB,
+		Threads:    threads,
+		KeyLen:     32,
+	}
+
+	if targetDuration <= 0 {
+		return KDFconfig{}, fmt.Errorf("%w: target duration %v", ErrCalibrationFailed, targetDuration)
+	}
+	if err := config.Validate(); err != nil {
+		return KDFconfig{}, err
+	}
+
+	/*
+		Find the largest memory that can be hashed once within the target
+	*/
+	minMemory := 8 * uint32(threads)
+	took := sample(config)
+	for took > targetDuration {
+		if config.Memory == minMemory {
+			return KDFconfig{}, fmt.Errorf("%w: m=%d,p=%d took %v, longer than the target %v", ErrCalibrationFailed, config.Memory, threads, took, targetDuration)
+		}
+		config.Memory /= 2
+		if config.Memory < minMemory {
+			config.Memory = minMemory
+		}
+		took = sample(config)
+	}
+
+	/*
+		Each iteration takes roughly the same time, so estimate the
+		number of iterations that fit within the target from the last
+		measurement, and refine the estimate a few times, as the fixed
+		overhead of a hash makes a single iteration a poor guide.
+	*/
+	for i := 0; i < 3; i++ {
+		if took <= 0 {
+			took = time.Nanosecond
+		}
+		estimate := uint64(targetDuration) * uint64(config.Time) / uint64(took)
+		if estimate < 1 {
+			estimate = 1
+		}
+		if estimate > MaxTime {
+			estimate = MaxTime
+		}
+		if uint32(estimate) == config.Time {
+			break
+		}
+		config.Time = uint32(estimate)
+		took = sample(config)
+	}
+
+	/*
+		The estimate can overshoot on a noisy host, so step back until the target is met
+	*/
+	for config.Time > 1 && took > targetDuration {
+		config.Time--
+		took = sample(config)
+	}
+
+	return config, nil
+}
+
+/*
+	sample measures the config twice and returns the faster of the two,
+	to reduce the effect of a busy host on the result.
+*/
+func sample(c KDFconfig) time.Duration {
+	first := measure(c)
+	second := measure(c)
+	if second < first {
+		return second
+	}
+	return first
+}
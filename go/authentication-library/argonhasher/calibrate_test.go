@@ -0,0 +1,66 @@
+package argonhasher
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+
+	/*
+		Replace the host with one that takes 1ms per iteration per MiB of memory
+	*/
+	defer func(m func(KDFconfig) time.Duration) { measure = m }(measure)
+	measure = func(c KDFconfig) time.Duration {
+		return time.Duration(c.Time) * time.Duration(c.Memory) * time.Millisecond / 1024
+	}
+
+	tests := []struct {
+		desc    string
+		target  time.Duration
+		memory  uint32
+		threads uint8
+		time    uint32
+		mem     uint32
+		err     error
+	}{
+		{desc: "Increases time to fill target", target: 500 * time.Millisecond, memory: 64 * 1024, threads: 1, time: 7, mem: 64 * 1024},
+		{desc: "Exact target", target: 128 * time.Millisecond, memory: 64 * 1024, threads: 1, time: 2, mem: 64 * 1024},
+		{desc: "Halves memory to fit target", target: 20 * time.Millisecond, memory: 64 * 1024, threads: 1, time: 1, mem: 16 * 1024},
+		{desc: "Multiple threads", target: 100 * time.Millisecond, memory: 32 * 1024, threads: 4, time: 3, mem: 32 * 1024},
+		{desc: "Target too short", target: time.Nanosecond, memory: 64 * 1024, threads: 1, err: ErrCalibrationFailed},
+		{desc: "Zero target", target: 0, memory: 64 * 1024, threads: 1, err: ErrCalibrationFailed},
+		{desc: "Zero threads", target: time.Second, memory: 64 * 1024, threads: 0, err: ErrInvalidThreads},
+		{desc: "Memory below 8 * threads", target: time.Second, memory: 15, threads: 2, err: ErrInsufficientMemory},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config, err := Calibrate(test.target, test.memory, test.threads)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if config.Time != test.time || config.Memory != test.mem || config.Threads != test.threads {
+				t.Errorf("incorrect parameters:\nWanted: t=%d,m=%d,p=%d\nGot: t=%d,m=%d,p=%d",
+					test.time, test.mem, test.threads, config.Time, config.Memory, config.Threads)
+			}
+			if err := config.Validate(); err != nil {
+				t.Errorf("calibrated config is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestCalibrateHost(t *testing.T) {
+	config, err := Calibrate(50*time.Millisecond, 1024, 1)
+	if err != nil {
+		t.Fatalf("failed to calibrate: %v", err)
+	}
+	if _, err := config.Encode("password"); err != nil {
+		t.Errorf("failed to encode with calibrated config: %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+/*
+	argonhasher is a command line tool for working with argon2id password hashes.
+
+	Usage:
+		argonhasher hash [-t 2] [-m 19456] [-p 1] [-salt 16] [-key 32] [-pepper-env NAME -pepper-id ID] [-json]
+		argonhasher verify [-pepper-env NAME] [-json] HASH
+		argonhasher inspect [-json] HASH
+		argonhasher calibrate [-target 500ms] [-memory 65536] [-threads 1]
+
+	hash creates a hash of the password, i.e. for a seeded admin account.
+	verify checks the password against a stored hash, exiting with status 1 if it doesn't match.
+	inspect prints the parameters a hash was created with.
+	calibrate benchmarks argon2id on the current host and prints the strongest
+	parameters that hash a password within the target duration, as the ARGON2_*
+	environment variables read by the authentication service.
+
+	Passwords are read from the terminal without echo, or from the first line of stdin
+	when it is not a terminal, so they never appear in the shell history or process list.
+	Peppered hashes need the pepper, which is read from the environment variable named by -pepper-env.
+*/
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/markstanden/argonhasher"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+var (
+	errUsage = errors.New(`usage:
+	argonhasher hash [-t 2] [-m 19456] [-p 1] [-salt 16] [-key 32] [-pepper-env NAME -pepper-id ID] [-json]
+	argonhasher verify [-pepper-env NAME] [-json] HASH
+	argonhasher inspect [-json] HASH
+	argonhasher calibrate [-target duration] [-memory KiB] [-threads n]`)
+	errMismatch = errors.New("password does not match")
+)
+
+func main() {
+	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr, os.LookupEnv); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+/*
+	run executes the command in args, with the streams and environment
+	passed in so the commands can be tested
+*/
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer, lookupEnv func(key string) (string, bool)) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	switch args[1] {
+	case "hash":
+		return hash(args[2:], stdin, stdout, stderr, lookupEnv)
+	case "verify":
+		return verify(args[2:], stdin, stdout, stderr, lookupEnv)
+	case "inspect":
+		return inspect(args[2:], stdout, stderr)
+	case "calibrate":
+		return calibrate(args[2:], stdout, stderr)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", args[1], errUsage)
+	}
+}
+
+/*
+	hash encodes the password using the parameters from the flags,
+	which default to the recommended config
+*/
+func hash(args []string, stdin io.Reader, stdout, stderr io.Writer, lookupEnv func(key string) (string, bool)) error {
+	config := argonhasher.RecommendedConfig()
+
+	flags := flag.NewFlagSet("hash", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	t := flags.Uint("t", uint(config.Time), "time (iterations)")
+	m := flags.Uint("m", uint(config.Memory), "memory in KiB")
+	p := flags.Uint("p", uint(config.Threads), "threads (parallelism)")
+	saltLength := flags.Uint("salt", config.SaltLength, "salt length in bytes")
+	keyLen := flags.Uint("key", uint(config.KeyLen), "key length in bytes")
+	pepperEnv := flags.String("pepper-env", "", "environment variable holding the pepper")
+	pepperID := flags.String("pepper-id", "", "version of the pepper, stored in the hash")
+	asJSON := flags.Bool("json", false, "output JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *p > 255 || *t > 1<<32-1 || *m > 1<<32-1 || *keyLen > 1<<32-1 {
+		return errUsage
+	}
+	if (*pepperEnv == "") != (*pepperID == "") {
+		return errors.New("-pepper-env and -pepper-id must be used together")
+	}
+
+	config.Time = uint32(*t)
+	config.Memory = uint32(*m)
+	config.Threads = uint8(*p)
+	config.SaltLength = *saltLength
+	config.KeyLen = uint32(*keyLen)
+	config.PepperID = *pepperID
+	if *pepperEnv != "" {
+		pepper, err := lookupPepper(lookupEnv, *pepperEnv)
+		if err != nil {
+			return err
+		}
+		config.Pepper = pepper
+	}
+
+	/*
+		Validate before asking for the password
+	*/
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	password, err := readPassword(stdin, stderr, true)
+	if err != nil {
+		return err
+	}
+
+	h, err := config.Encode(password)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return writeJSON(stdout, struct {
+			Hash string `json:"hash"`
+		}{h})
+	}
+	fmt.Fprintln(stdout, h)
+	return nil
+}
+
+/*
+	verify confirms the password against the hash
+*/
+func verify(args []string, stdin io.Reader, stdout, stderr io.Writer, lookupEnv func(key string) (string, bool)) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pepperEnv := flags.String("pepper-env", "", "environment variable holding the pepper")
+	asJSON := flags.Bool("json", false, "output JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	h := flags.Arg(0)
+
+	var config argonhasher.KDFconfig
+	if *pepperEnv != "" {
+		pepper, err := lookupPepper(lookupEnv, *pepperEnv)
+		if err != nil {
+			return err
+		}
+		config.Pepper = pepper
+	}
+
+	password, err := readPassword(stdin, stderr, false)
+	if err != nil {
+		return err
+	}
+	match := config.Confirm(password, h)
+
+	if *asJSON {
+		if err := writeJSON(stdout, struct {
+			Match     bool   `json:"match"`
+			Algorithm string `json:"algorithm"`
+		}{match, argonhasher.Identifier(h)}); err != nil {
+			return err
+		}
+	} else if match {
+		fmt.Fprintln(stdout, "match")
+	}
+
+	if !match {
+		return errMismatch
+	}
+	return nil
+}
+
+/*
+	hashInfo is the description of a hash output by inspect
+*/
+type hashInfo struct {
+	Algorithm  string `json:"algorithm"`
+	Version    int    `json:"version,omitempty"`
+	Time       uint32 `json:"time,omitempty"`
+	Memory     uint32 `json:"memory,omitempty"`
+	Threads    uint8  `json:"threads,omitempty"`
+	SaltLength uint   `json:"salt_length,omitempty"`
+	KeyLength  uint32 `json:"key_length,omitempty"`
+	PepperID   string `json:"pepper_id,omitempty"`
+}
+
+/*
+	inspect prints the parameters used to create the hash.
+	Only argon2id hashes can be broken down, other schemes
+	are reported by their identifier.
+*/
+func inspect(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "output JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errUsage
+	}
+	h := flags.Arg(0)
+
+	info := hashInfo{Algorithm: argonhasher.Identifier(h)}
+	if info.Algorithm == "" {
+		return argonhasher.ErrMalformedHash
+	}
+	if info.Algorithm == "argon2id" {
+		config, key, err := argonhasher.ParseHash(h)
+		if err != nil {
+			return err
+		}
+		info.Version = argon2.Version
+		info.Time = config.Time
+		info.Memory = config.Memory
+		info.Threads = config.Threads
+		info.SaltLength = config.SaltLength
+		info.KeyLength = uint32(len(key))
+		info.PepperID = config.PepperID
+	}
+
+	if *asJSON {
+		return writeJSON(stdout, info)
+	}
+
+	fmt.Fprintf(stdout, "algorithm:   %v\n", info.Algorithm)
+	if info.Algorithm != "argon2id" {
+		return nil
+	}
+	fmt.Fprintf(stdout, "version:     %v\n", info.Version)
+	fmt.Fprintf(stdout, "time:        %v\n", info.Time)
+	fmt.Fprintf(stdout, "memory:      %v KiB\n", info.Memory)
+	fmt.Fprintf(stdout, "threads:     %v\n", info.Threads)
+	fmt.Fprintf(stdout, "salt length: %v bytes\n", info.SaltLength)
+	fmt.Fprintf(stdout, "key length:  %v bytes\n", info.KeyLength)
+	if info.PepperID != "" {
+		fmt.Fprintf(stdout, "pepper id:   %v\n", info.PepperID)
+	}
+	return nil
+}
+
+/*
+	calibrate finds the recommended parameters for the host,
+	and prints them with the time taken to hash a password using them
+*/
+func calibrate(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	target := flags.Duration("target", 500*time.Millisecond, "maximum time to hash a single password")
+	memory := flags.Uint("memory", 64*1024, "maximum memory to use per hash, in KiB")
+	threads := flags.Uint("threads", 1, "number of threads to use per hash")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *threads > 255 {
+		return fmt.Errorf("threads must be less than 256, got %d", *threads)
+	}
+	if *memory > 1<<32-1 {
+		return fmt.Errorf("memory must be less than 4 TiB, got %d KiB", *memory)
+	}
+
+	config, err := argonhasher.Calibrate(*target, uint32(*memory), uint8(*threads))
+	if err != nil {
+		return err
+	}
+
+	/*
+		Time a real hash, so the printed duration includes the salt generation
+		and encoding overhead
+	*/
+	start := time.Now()
+	if _, err := config.Encode("calibrate"); err != nil {
+		return err
+	}
+	took := time.Since(start)
+
+	fmt.Fprintf(stdout, "argon2id parameters for a %v target (measured %v):\n", *target, took.Round(time.Millisecond))
+	fmt.Fprintf(stdout, "ARGON2_TIME=%d\n", config.Time)
+	fmt.Fprintf(stdout, "ARGON2_MEMORY=%d\n", config.Memory)
+	fmt.Fprintf(stdout, "ARGON2_THREADS=%d\n", config.Threads)
+	fmt.Fprintf(stdout, "ARGON2_SALT_LENGTH=%d\n", config.SaltLength)
+	fmt.Fprintf(stdout, "ARGON2_KEY_LENGTH=%d\n", config.KeyLen)
+	return nil
+}
+
+/*
+	readPassword reads the password from the terminal without echo,
+	asking for it twice if confirm is set, or from the first line of stdin
+	if it is not a terminal.
+*/
+func readPassword(stdin io.Reader, stderr io.Writer, confirm bool) (password string, err error) {
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(stderr, "Password: ")
+		pw, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(stderr)
+		if err != nil {
+			return "", err
+		}
+
+		if confirm {
+			fmt.Fprint(stderr, "Confirm password: ")
+			again, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(stderr)
+			if err != nil {
+				return "", err
+			}
+			if string(again) != string(pw) {
+				return "", errors.New("passwords do not match")
+			}
+		}
+		password = string(pw)
+	} else {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		return "", argonhasher.ErrEmptyPassword
+	}
+	return password, nil
+}
+
+/*
+	lookupPepper returns a pepper lookup that returns the pepper
+	held in the environment variable, whatever the pepper version
+*/
+func lookupPepper(lookupEnv func(key string) (string, bool), name string) (func(pepperID string) string, error) {
+	pepper, ok := lookupEnv(name)
+	if !ok || pepper == "" {
+		return nil, fmt.Errorf("pepper environment variable %v is not set", name)
+	}
+	return func(pepperID string) string { return pepper }, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/markstanden/argonhasher"
+)
+
+/*
+	env is a fake environment for the pepper lookups
+*/
+type env map[string]string
+
+func (e env) lookup(key string) (string, bool) {
+	value, ok := e[key]
+	return value, ok
+}
+
+/*
+	hashFor runs the hash command with fast parameters, returning the created hash
+*/
+func hashFor(t *testing.T, password string, e env, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	args = append([]string{"argonhasher", "hash", "-t", "1", "-m", "64", "-p", "1"}, args...)
+	if err := run(args, strings.NewReader(password+"\n"), &out, &bytes.Buffer{}, e.lookup); err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return strings.TrimSpace(out.String())
+}
+
+func TestHashThenVerify(t *testing.T) {
+	e := env{"PEPPER": "pepper"}
+	plain := hashFor(t, "correct horse", e)
+	peppered := hashFor(t, "correct horse", e, "-pepper-env", "PEPPER", "-pepper-id", "1")
+
+	tests := []struct {
+		desc     string
+		args     []string
+		password string
+		env      env
+		want     string
+		err      error
+	}{
+		{desc: "Match", args: []string{plain}, password: "correct horse", want: "match\n"},
+		{desc: "Mismatch", args: []string{plain}, password: "incorrect", want: "", err: errMismatch},
+		{desc: "Match JSON", args: []string{"-json", plain}, password: "correct horse", want: "{\n  \"match\": true,\n  \"algorithm\": \"argon2id\"\n}\n"},
+		{desc: "Mismatch JSON", args: []string{"-json", plain}, password: "incorrect", want: "{\n  \"match\": false,\n  \"algorithm\": \"argon2id\"\n}\n", err: errMismatch},
+		{desc: "Peppered Match", args: []string{"-pepper-env", "PEPPER", peppered}, password: "correct horse", env: e, want: "match\n"},
+		{desc: "Wrong Pepper", args: []string{"-pepper-env", "PEPPER", peppered}, password: "correct horse", env: env{"PEPPER": "salt"}, err: errMismatch},
+		{desc: "Missing Pepper", args: []string{peppered}, password: "correct horse", err: errMismatch},
+		{desc: "Legacy Mismatch", args: []string{"pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA="}, password: "incorrect", err: errMismatch},
+		{desc: "Legacy Match", args: []string{"pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA="}, password: "correct horse", want: "match\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"argonhasher", "verify"}, test.args...)
+			err := run(args, strings.NewReader(test.password+"\n"), &out, &bytes.Buffer{}, test.env.lookup)
+
+			/*
+				main exits with status 1 for any error, so a mismatch must return one
+			*/
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if out.String() != test.want {
+				t.Errorf("incorrect output:\nWanted: %q\nGot: %q", test.want, out.String())
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	peppered := hashFor(t, "correct horse", env{"PEPPER": "pepper"}, "-salt", "24", "-key", "48", "-pepper-env", "PEPPER", "-pepper-id", "v2")
+
+	tests := []struct {
+		desc string
+		args []string
+		want string
+		err  error
+	}{
+		{desc: "argon2id", args: []string{peppered},
+			want: "algorithm:   argon2id\nversion:     19\ntime:        1\nmemory:      64 KiB\nthreads:     1\nsalt length: 24 bytes\nkey length:  48 bytes\npepper id:   v2\n"},
+		{desc: "argon2id JSON", args: []string{"-json", peppered},
+			want: "{\n  \"algorithm\": \"argon2id\",\n  \"version\": 19,\n  \"time\": 1,\n  \"memory\": 64,\n  \"threads\": 1,\n  \"salt_length\": 24,\n  \"key_length\": 48,\n  \"pepper_id\": \"v2\"\n}\n"},
+		{desc: "bcrypt", args: []string{"$2b$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy"},
+			want: "algorithm:   2b\n"},
+		{desc: "pbkdf2_sha256", args: []string{"pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA="},
+			want: "algorithm:   pbkdf2_sha256\n"},
+		{desc: "scrypt JSON", args: []string{"-json", "scrypt$1024$seasalt123$8$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg=="},
+			want: "{\n  \"algorithm\": \"scrypt\"\n}\n"},
+		{desc: "No Identifier", args: []string{"5f4dcc3b5aa765d61d8327deb882cf99"}, err: argonhasher.ErrMalformedHash},
+		{desc: "Malformed argon2id", args: []string{"$argon2id$v=19$m=64,t=1,p=1$c2FsdA"}, err: argonhasher.ErrMalformedHash},
+		{desc: "Excessive Memory", args: []string{"$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"}, err: argonhasher.ErrMalformedParams},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"argonhasher", "inspect"}, test.args...)
+			err := run(args, strings.NewReader(""), &out, &bytes.Buffer{}, env{}.lookup)
+			if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if out.String() != test.want {
+				t.Errorf("incorrect output:\nWanted: %q\nGot: %q", test.want, out.String())
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	var out bytes.Buffer
+	args := []string{"argonhasher", "calibrate", "-target", "20ms", "-memory", "64", "-threads", "1"}
+	if err := run(args, strings.NewReader(""), &out, &bytes.Buffer{}, env{}.lookup); err != nil {
+		t.Fatalf("failed to calibrate: %v", err)
+	}
+
+	/*
+		The printed parameters are read by the authentication service, so must be a valid config
+	*/
+	values := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n")[1:] {
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			t.Fatalf("incorrect output line: %q", line)
+		}
+		values[parts[0]] = parts[1]
+	}
+	for _, name := range []string{"ARGON2_TIME", "ARGON2_MEMORY", "ARGON2_THREADS", "ARGON2_SALT_LENGTH", "ARGON2_KEY_LENGTH"} {
+		if _, ok := values[name]; !ok {
+			t.Errorf("%v not printed:\n%v", name, out.String())
+		}
+	}
+	if values["ARGON2_MEMORY"] != "64" || values["ARGON2_THREADS"] != "1" {
+		t.Errorf("memory or threads not within the budget:\n%v", out.String())
+	}
+}
+
+/*
+	Bad flags and arguments are rejected before the password is read,
+	so stdin is a reader that fails if it is used
+*/
+func TestBadUsage(t *testing.T) {
+	unread := errors.New("password read")
+
+	tests := []struct {
+		desc string
+		args []string
+	}{
+		{desc: "No Command", args: []string{}},
+		{desc: "Unknown Command", args: []string{"rehash"}},
+		{desc: "Unknown Flag", args: []string{"hash", "-x"}},
+		{desc: "Hash Argument", args: []string{"hash", "password"}},
+		{desc: "Hash Threads above 255", args: []string{"hash", "-p", "256"}},
+		{desc: "Hash Zero Time", args: []string{"hash", "-t", "0"}},
+		{desc: "Hash Time above maximum", args: []string{"hash", "-t", "129"}},
+		{desc: "Hash Short Salt", args: []string{"hash", "-salt", "8"}},
+		{desc: "Hash Pepper without ID", args: []string{"hash", "-pepper-env", "PEPPER"}},
+		{desc: "Hash Pepper not set", args: []string{"hash", "-pepper-env", "MISSING", "-pepper-id", "1"}},
+		{desc: "Verify No Hash", args: []string{"verify"}},
+		{desc: "Verify Two Hashes", args: []string{"verify", "a$b", "c$d"}},
+		{desc: "Verify Pepper not set", args: []string{"verify", "-pepper-env", "MISSING", "a$b"}},
+		{desc: "Inspect No Hash", args: []string{"inspect"}},
+		{desc: "Inspect Bad Flag", args: []string{"inspect", "-yaml", "a$b"}},
+		{desc: "Calibrate Threads above 255", args: []string{"calibrate", "-threads", "256"}},
+		{desc: "Calibrate Memory above 4 TiB", args: []string{"calibrate", "-memory", "4294967296"}},
+		{desc: "Calibrate Zero Target", args: []string{"calibrate", "-target", "0s"}},
+		{desc: "Calibrate Bad Duration", args: []string{"calibrate", "-target", "fast"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"argonhasher"}, test.args...)
+			err := run(args, iotest.ErrReader(unread), &out, &bytes.Buffer{}, env{"PEPPER": "pepper"}.lookup)
+			if err == nil || errors.Is(err, unread) || errors.Is(err, errMismatch) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("unexpected output: %q", out.String())
+			}
+		})
+	}
+}
+
+/*
+	The JSON output of hash can be used by scripts
+*/
+func TestHashJSON(t *testing.T) {
+	var out bytes.Buffer
+	args := []string{"argonhasher", "hash", "-t", "1", "-m", "64", "-p", "1", "-json"}
+	if err := run(args, strings.NewReader("correct horse\n"), &out, &bytes.Buffer{}, env{}.lookup); err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	var result struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%v", err, out.String())
+	}
+	if !argonhasher.Confirm("correct horse", result.Hash) {
+		t.Errorf("hash does not confirm the password: %q", result.Hash)
+	}
+}
@@ -1,10 +1,12 @@
 package argonhasher
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strings"
 
-	"github.com/markstanden/securerandom"
 	"golang.org/x/crypto/argon2"
 )
 
@@ -15,54 +17,130 @@ import (
 	A cost set to zero will provide a strong default option, and is recommended
 	The function returns a standard format argon2 hash string if the hash completes without error,
 	otherwise an empty string is returned.
+	To set the parameters explicitly, and receive an error on failure, use KDFconfig.Encode
 */
 func Encode(pw string, cost uint) (hashWithConfig string) {
 
-	if pw == "" {
+	/*
+		The hashing parameters for the supplied cost,
+		a cost of zero returns the default parameters.
+	*/
+	policy := CostPolicy(cost)
+
+	newArgon := KDFconfig{
+		SaltLength: policy.SaltLength,
+		Time:       policy.Time,
+		Memory:     policy.Memory,
+		Threads:    policy.Threads,
+		KeyLen:     policy.KeyLen,
+	}
+
+	hash, err := newArgon.Encode(pw)
+	if err != nil {
 		return ""
 	}
+	return hash
+}
 
-	/*
-		Set the default option cost to be a
-		sensible encoding time on modern hardware.
-	*/
-	if cost == 0 {
-		cost = 3
+/*
+	Encode creates an argon2 hash from a plaintext password,
+	using the parameters set in the config.
+	A new salt of SaltLength bytes is generated for every hash,
+	so the Salt field of the config is ignored.
+	The parameters are checked with Validate before hashing,
+	and an error returned if they are out of bounds.
+*/
+func (c KDFconfig) Encode(password string) (hashWithConfig string, err error) {
+
+	if password == "" {
+		return "", ErrEmptyPassword
 	}
 
-	newArgon := KDFconfig{
-		SaltLength: 64,
-		Time:       uint32(2 * cost),
-		Memory:     uint32((1 + cost) * 32 * 1024),
-		Threads:    uint8(1 + (cost * 3 / 2)),
-		KeyLen:     16,
+	if err := c.Validate(); err != nil {
+		return "", err
 	}
 
 	/*
 		call our salt generator function to produce a cryptographically secure salt
 		that is the specified length.
 	*/
-	newArgon.Salt = securerandom.ByteSlice(newArgon.SaltLength)
-	if newArgon.Salt == nil {
-		return ""
+	c.Salt, err = c.Random.Bytes(c.SaltLength)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrSaltGeneration, err)
+	}
+
+	key, err := c.deriveKey(password)
+	if err != nil {
+		return "", err
+	}
+
+	return c.format(key), nil
+}
+
+/*
+	deriveKey produces the argon2id key for the password using the config parameters and salt.
+	If the config has a PepperID the pepper is looked up using the Pepper callback,
+	and the password is replaced by its HMAC-SHA256 keyed with the pepper before hashing,
+	so the key cannot be reproduced from the hash string alone.
+*/
+func (c KDFconfig) deriveKey(password string) (key []byte, err error) {
+
+	input := []byte(password)
+
+	if c.PepperID != "" {
+		if c.Pepper == nil {
+			return nil, ErrFailedPepper
+		}
+		pepper := c.Pepper(c.PepperID)
+		if pepper == "" {
+			return nil, fmt.Errorf("%w: version %q", ErrFailedPepper, c.PepperID)
+		}
+		mac := hmac.New(sha256.New, []byte(pepper))
+		mac.Write(input)
+		input = mac.Sum(nil)
 	}
 
 	/*
 		from "golang.org/x/crypto/argon2"
 		func IDKey(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte
 	*/
-	key := argon2.IDKey([]byte(pw), newArgon.Salt, newArgon.Time, newArgon.Memory, newArgon.Threads, newArgon.KeyLen)
+	return argon2.IDKey(input, c.Salt, c.Time, c.Memory, c.Threads, c.KeyLen), nil
+}
+
+/*
+	format encodes the config and derived key as a standard format argon2 hash string
+	$argon2id$v=19$t=10,m=65536,p=8$SALT$HASH
+	with the pepper version added to the arguments if the hash is peppered
+	$argon2id$v=19$t=10,m=65536,p=8,keyid=PEPPERID$SALT$HASH
+*/
+func (c KDFconfig) format(key []byte) (hash string) {
+	params := fmt.Sprintf("t=%v,m=%v,p=%v", c.Time, c.Memory, c.Threads)
+	if c.PepperID != "" {
+		params += ",keyid=" + c.PepperID
+	}
 
-	hash := fmt.Sprintf("$argon2id$v=%v$t=%v,m=%v,p=%v$%s$%s",
+	return fmt.Sprintf("$argon2id$v=%v$%s$%s$%s",
 		argon2.Version,
-		newArgon.Time,
-		newArgon.Memory,
-		newArgon.Threads,
-		base64.RawStdEncoding.EncodeToString(newArgon.Salt),
+		params,
+		base64.RawStdEncoding.EncodeToString(c.Salt),
 		base64.RawStdEncoding.EncodeToString(key))
+}
 
-	if !ValidHash(hash) {
-		return ""
+/*
+	validPepperID checks the pepper version only contains characters that
+	can be safely stored within the hash arguments,
+	the base64 (standard and URL) alphabets and full stops.
+*/
+func validPepperID(id string) bool {
+	const valid = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_."
+
+	if id == "" || len(id) > 64 {
+		return false
 	}
-	return hash
+	for _, r := range id {
+		if !strings.ContainsRune(valid, r) {
+			return false
+		}
+	}
+	return true
 }
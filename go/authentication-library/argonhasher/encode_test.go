@@ -1,10 +1,79 @@
 package argonhasher
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"testing"
+	"testing/iotest"
+
+	"github.com/markstanden/securerandom"
 )
 
+/***
+*
+*	Tests
+*
+***/
+
+func TestConfigEncode(t *testing.T) {
+	valid := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 2, KeyLen: 16}
+
+	tests := []struct {
+		desc   string
+		pw     string
+		config KDFconfig
+		err    error
+	}{
+		{desc: "Valid Config", pw: "password", config: valid, err: nil},
+		{desc: "Recommended Config", pw: "password", config: RecommendedConfig(), err: nil},
+		{desc: "Empty Password", pw: "", config: valid, err: ErrEmptyPassword},
+		{desc: "Zero Config", pw: "password", config: KDFconfig{}, err: ErrSaltTooShort},
+		{desc: "Short Salt", pw: "password", config: KDFconfig{SaltLength: 8, Time: 1, Memory: 64, Threads: 2, KeyLen: 16}, err: ErrSaltTooShort},
+		{desc: "Short Key", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 2, KeyLen: 8}, err: ErrKeyTooShort},
+		{desc: "Zero Time", pw: "password", config: KDFconfig{SaltLength: 16, Time: 0, Memory: 64, Threads: 2, KeyLen: 16}, err: ErrInvalidTime},
+		{desc: "Zero Threads", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 0, KeyLen: 16}, err: ErrInvalidThreads},
+		{desc: "Memory below 8 * threads", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 15, Threads: 2, KeyLen: 16}, err: ErrInsufficientMemory},
+		{desc: "Time above maximum", pw: "password", config: KDFconfig{SaltLength: 16, Time: MaxTime + 1, Memory: 64, Threads: 2, KeyLen: 16}, err: ErrExcessiveTime},
+		{desc: "Memory above maximum", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: MaxMemory + 1, Threads: 2, KeyLen: 16}, err: ErrExcessiveMemory},
+		{desc: "Injected Entropy", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 2, KeyLen: 16, Random: securerandom.Generator{Source: bytes.NewReader(make([]byte, 16))}}, err: nil},
+		{desc: "Failing Entropy", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 2, KeyLen: 16, Random: securerandom.Generator{Source: iotest.ErrReader(errors.New("entropy unavailable"))}}, err: ErrSaltGeneration},
+		{desc: "Exhausted Entropy", pw: "password", config: KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 2, KeyLen: 16, Random: securerandom.Generator{Source: bytes.NewReader(make([]byte, 8))}}, err: ErrSaltGeneration},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			hash, err := test.config.Encode(test.pw)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if err != nil {
+				if hash != "" {
+					t.Errorf("hash returned with error: %v", hash)
+				}
+				return
+			}
+
+			/*
+				The created hash should parse back to the config used to create it
+			*/
+			parsed, _, err := ParseHash(hash)
+			if err != nil {
+				t.Fatalf("failed to parse created hash:\n%v", err)
+			}
+			if parsed.Policy() != test.config.Policy() {
+				t.Errorf("hash parameters do not match config:\nWanted: %+v\nGot: %+v", test.config.Policy(), parsed.Policy())
+			}
+			if !Confirm(test.pw, hash) {
+				t.Error("failed to confirm password against created hash")
+			}
+			if NeedsRehash(hash, test.config.Policy()) {
+				t.Error("hash created with config needs rehash against the same config")
+			}
+		})
+	}
+}
+
 /***
 *
 *	Benchmarks
@@ -61,3 +130,33 @@ func ExampleEncode() {
 	// Output:
 	// false
 }
+
+func ExampleKDFconfig_Encode() {
+	/*
+		Set the hashing parameters explicitly, here m=19MiB, t=2, p=1
+	*/
+	config := KDFconfig{
+		SaltLength: 16,
+		Time:       2,
+		Memory:     19 * 1024,
+		Threads:    1,
+		KeyLen:     32,
+	}
+
+	hashedPassword, err := config.Encode("c2BDNoW38DStXvzP")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(Confirm("c2BDNoW38DStXvzP", hashedPassword))
+
+	/*
+		Out of bounds parameters return an error
+	*/
+	config.Threads = 0
+	_, err = config.Encode("c2BDNoW38DStXvzP")
+	fmt.Println(err)
+	// Output:
+	// true
+	// threads must be at least 1
+}
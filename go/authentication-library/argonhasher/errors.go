@@ -0,0 +1,53 @@
+package argonhasher
+
+import "errors"
+
+// Errors
+var (
+	ErrInputInvalid = errors.New("input arguments invalid")
+
+	/*
+		ParseHash errors, returned when a stored hash string
+		cannot be broken down into its component parts.
+	*/
+	ErrMalformedHash    = errors.New("malformed hash string")
+	ErrUnknownAlgorithm = errors.New("unknown hash algorithm")
+	ErrVersionMismatch  = errors.New("argon2 version mismatch")
+	ErrMalformedParams  = errors.New("malformed hash parameters")
+
+	/*
+		Encode errors, returned when the config is out of bounds
+		or the hash could not be created.
+	*/
+	ErrEmptyPassword      = errors.New("empty password")
+	ErrSaltTooShort       = errors.New("salt length too short")
+	ErrKeyTooShort        = errors.New("key length too short")
+	ErrInvalidTime        = errors.New("time must be at least 1")
+	ErrInvalidThreads     = errors.New("threads must be at least 1")
+	ErrInsufficientMemory = errors.New("memory must be at least 8 * threads")
+	ErrExcessiveTime      = errors.New("time exceeds the maximum")
+	ErrExcessiveMemory    = errors.New("memory exceeds the maximum")
+	ErrSaltGeneration     = errors.New("failed to generate salt")
+
+	/*
+		Calibrate errors
+	*/
+	ErrCalibrationFailed = errors.New("unable to calibrate parameters for the target")
+
+	/*
+		Hasher errors
+	*/
+	ErrBusy = errors.New("too many password hashing operations in progress")
+
+	/*
+		Register errors
+	*/
+	ErrInvalidIdentifier  = errors.New("invalid hash identifier")
+	ErrReservedIdentifier = errors.New("hash identifier is reserved")
+
+	/*
+		Pepper errors
+	*/
+	ErrInvalidPepperID = errors.New("invalid pepper id")
+	ErrFailedPepper    = errors.New("failed to retrieve pepper")
+)
@@ -3,6 +3,9 @@ module github.com/markstanden/argonhasher
 go 1.16
 
 require (
-	github.com/markstanden/securerandom v0.4.0
+	github.com/markstanden/securerandom v0.4.1
 	golang.org/x/crypto v0.0.0-20210220033148-5ea612d1eb83
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
 )
+
+replace github.com/markstanden/securerandom => ../securerandom-generator
@@ -0,0 +1,195 @@
+package argonhasher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/*
+	Hasher limits the number of argon2 operations running at once.
+	Each operation allocates the config's full Memory, so a burst of
+	unbounded concurrent hashes can exhaust the memory of the host.
+	Callers beyond the in-flight limit wait in a bounded queue until a slot
+	is free or their context is done, and are rejected with ErrBusy
+	when the queue is full, so the caller can shed load (i.e. respond with a 503).
+	A Hasher is safe for concurrent use, and must be created with NewHasher.
+*/
+type Hasher struct {
+	slots chan struct{}
+	queue chan struct{}
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+/*
+	Stats is a snapshot of a Hasher's load and admission history
+*/
+type Stats struct {
+
+	/*
+		InFlight and Queued are the current number of running and waiting operations
+	*/
+	InFlight int
+	Queued   int
+
+	/*
+		Admitted operations were given a slot to run in,
+		Rejected were turned away because the queue was full,
+		Cancelled gave up waiting when their context was done.
+	*/
+	Admitted  uint64
+	Rejected  uint64
+	Cancelled uint64
+
+	/*
+		TotalWait and MaxWait are the sum and maximum of the time admitted
+		operations spent waiting for a slot
+	*/
+	TotalWait time.Duration
+	MaxWait   time.Duration
+}
+
+/*
+	MeanWait returns the average time admitted operations waited for a slot
+*/
+func (s Stats) MeanWait() time.Duration {
+	if s.Admitted == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Admitted)
+}
+
+/*
+	NewHasher returns a Hasher that runs at most maxInFlight operations at once,
+	with at most maxQueued callers waiting for a slot.
+	maxInFlight is raised to 1 if less, as a Hasher must be able to hash.
+	A maxQueued of zero rejects any call that cannot run immediately.
+*/
+func NewHasher(maxInFlight, maxQueued int) *Hasher {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &Hasher{
+		slots: make(chan struct{}, maxInFlight),
+		queue: make(chan struct{}, maxQueued),
+	}
+}
+
+/*
+	Encode hashes the password using the config, as KDFconfig.Encode,
+	once a slot is available.
+	ErrBusy is returned if the queue is full, and the context's error
+	if it is done before a slot becomes available.
+*/
+func (h *Hasher) Encode(ctx context.Context, c KDFconfig, password string) (hash string, err error) {
+	if err := h.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer h.release()
+	return c.Encode(password)
+}
+
+/*
+	Confirm checks the password against the hash, as KDFconfig.Confirm,
+	once a slot is available.
+	ErrBusy is returned if the queue is full, and the context's error
+	if it is done before a slot becomes available.
+*/
+func (h *Hasher) Confirm(ctx context.Context, c KDFconfig, password string, hash string) (valid bool, err error) {
+	if err := h.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer h.release()
+	return c.Confirm(password, hash), nil
+}
+
+/*
+	Stats returns a snapshot of the Hasher's current load and admission history
+*/
+func (h *Hasher) Stats() Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.stats
+	s.InFlight = len(h.slots)
+	s.Queued = len(h.queue)
+	return s
+}
+
+/*
+	acquire takes a slot, waiting in the queue if none are free.
+*/
+func (h *Hasher) acquire(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		h.count(&h.stats.Cancelled)
+		return err
+	}
+
+	/*
+		Take a free slot without queueing if possible
+	*/
+	select {
+	case h.slots <- struct{}{}:
+		h.admit(0)
+		return nil
+	default:
+	}
+
+	/*
+		Join the queue if there is room, otherwise reject
+	*/
+	select {
+	case h.queue <- struct{}{}:
+	default:
+		h.count(&h.stats.Rejected)
+		return ErrBusy
+	}
+	defer func() { <-h.queue }()
+
+	start := time.Now()
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		h.count(&h.stats.Cancelled)
+		return ctx.Err()
+	}
+
+	/*
+		A slot freed as the context finished may be chosen over ctx.Done,
+		so check again rather than hash for a caller that has gone.
+	*/
+	if err := ctx.Err(); err != nil {
+		h.release()
+		h.count(&h.stats.Cancelled)
+		return err
+	}
+	h.admit(time.Since(start))
+	return nil
+}
+
+/*
+	release frees the slot taken by acquire
+*/
+func (h *Hasher) release() {
+	<-h.slots
+}
+
+func (h *Hasher) admit(wait time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Admitted++
+	h.stats.TotalWait += wait
+	if wait > h.stats.MaxWait {
+		h.stats.MaxWait = wait
+	}
+}
+
+func (h *Hasher) count(counter *uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*counter++
+}
@@ -0,0 +1,136 @@
+package argonhasher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHasher(t *testing.T) {
+
+	/*
+		The pepper lookup runs inside the hashing slot,
+		so blocking it holds the slot until release is closed.
+	*/
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16, PepperID: "v1",
+		Pepper: func(pepperID string) string {
+			started <- struct{}{}
+			<-release
+			return "pepper"
+		},
+	}
+	fast := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16}
+
+	h := NewHasher(1, 1)
+
+	/*
+		Fill the only slot
+	*/
+	holding := make(chan error)
+	go func() {
+		_, err := h.Encode(context.Background(), blocking, "password")
+		holding <- err
+	}()
+	<-started
+
+	/*
+		Fill the only queue position
+	*/
+	queued := make(chan error)
+	go func() {
+		_, err := h.Encode(context.Background(), fast, "password")
+		queued <- err
+	}()
+	for h.Stats().Queued != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Run("Queue Full", func(t *testing.T) {
+		if _, err := h.Confirm(context.Background(), fast, "password", "hash"); !errors.Is(err, ErrBusy) {
+			t.Errorf("unexpected error:\nWanted: %v\nGot: %v", ErrBusy, err)
+		}
+	})
+
+	t.Run("Stats Under Load", func(t *testing.T) {
+		s := h.Stats()
+		if s.InFlight != 1 || s.Queued != 1 || s.Rejected != 1 || s.Admitted != 1 {
+			t.Errorf("unexpected stats: %+v", s)
+		}
+	})
+
+	t.Run("Queued Call Runs When Slot Free", func(t *testing.T) {
+		close(release)
+		if err := <-holding; err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		if err := <-queued; err != nil {
+			t.Fatalf("failed to encode queued call: %v", err)
+		}
+		s := h.Stats()
+		if s.InFlight != 0 || s.Queued != 0 || s.Admitted != 2 || s.MaxWait <= 0 || s.MeanWait() <= 0 {
+			t.Errorf("unexpected stats: %+v", s)
+		}
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		hash, err := h.Encode(context.Background(), fast, "password")
+		if err != nil {
+			t.Fatalf("failed to encode: %v", err)
+		}
+		if valid, err := h.Confirm(context.Background(), fast, "password", hash); !valid || err != nil {
+			t.Errorf("failed to confirm password: %v", err)
+		}
+		if valid, err := h.Confirm(context.Background(), fast, "incorrect", hash); valid || err != nil {
+			t.Errorf("confirmed incorrect password: %v", err)
+		}
+	})
+}
+
+func TestHasherContext(t *testing.T) {
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16, PepperID: "v1",
+		Pepper: func(pepperID string) string {
+			close(started)
+			<-release
+			return "pepper"
+		},
+	}
+	fast := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16}
+
+	h := NewHasher(1, 4)
+	done := make(chan struct{})
+	go func() {
+		h.Encode(context.Background(), blocking, "password")
+		close(done)
+	}()
+	<-started
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	t.Run("Deadline While Queued", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := h.Encode(ctx, fast, "password"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("unexpected error:\nWanted: %v\nGot: %v", context.DeadlineExceeded, err)
+		}
+	})
+
+	t.Run("Cancelled Before Queueing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := h.Confirm(ctx, fast, "password", "hash"); !errors.Is(err, context.Canceled) {
+			t.Errorf("unexpected error:\nWanted: %v\nGot: %v", context.Canceled, err)
+		}
+	})
+
+	if s := h.Stats(); s.Cancelled != 2 || s.Queued != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
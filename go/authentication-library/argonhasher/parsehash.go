@@ -0,0 +1,160 @@
+package argonhasher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+/*
+	ParseHash breaks down a PHC format argon2id hash string into the
+	configuration used to create it, and the derived key.
+	$argon2id$v=19$t=10,m=65536,p=8$SALT$HASH
+	If the hash was peppered, the pepper version is returned as the config's PepperID
+	$argon2id$v=19$t=10,m=65536,p=8,keyid=PEPPERID$SALT$HASH
+	Every section is checked, so a truncated or corrupted hash returns
+	an error rather than a partially filled config:
+		- ErrMalformedHash if the string does not have the expected sections,
+		or the salt or key are missing or not valid base64
+		- ErrUnknownAlgorithm if the hash was not created with argon2id
+		- ErrVersionMismatch if the hash was created with a different argon2 version
+		- ErrMalformedParams if the version, time, memory, thread or keyid arguments are
+		missing, duplicated, unknown or out of range, including above MaxTime and MaxMemory
+*/
+func ParseHash(hash string) (config KDFconfig, key []byte, err error) {
+
+	/*
+		The hash is prefixed with a `$`, so the first section will be empty
+		[ "", "argon2id", "v=19", "t=2,m=65536,p=2", SALT, HASH ]
+	*/
+	sections := strings.Split(hash, "$")
+	if len(sections) != 6 || sections[0] != "" {
+		return KDFconfig{}, nil, ErrMalformedHash
+	}
+
+	if sections[1] != "argon2id" {
+		return KDFconfig{}, nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, sections[1])
+	}
+
+	version, err := parseVersion(sections[2])
+	if err != nil {
+		return KDFconfig{}, nil, err
+	}
+	if version != argon2.Version {
+		return KDFconfig{}, nil, fmt.Errorf("%w: got %d, want %d", ErrVersionMismatch, version, argon2.Version)
+	}
+
+	if config, err = parseParams(sections[3]); err != nil {
+		return KDFconfig{}, nil, err
+	}
+
+	config.Salt, err = decodeSection(sections[4])
+	if err != nil {
+		return KDFconfig{}, nil, fmt.Errorf("%w: salt %v", ErrMalformedHash, err)
+	}
+	config.SaltLength = uint(len(config.Salt))
+
+	key, err = decodeSection(sections[5])
+	if err != nil {
+		return KDFconfig{}, nil, fmt.Errorf("%w: key %v", ErrMalformedHash, err)
+	}
+	config.KeyLen = uint32(len(key))
+
+	return config, key, nil
+}
+
+/*
+	parseVersion reads the argon2 version from the "v=19" section of the hash
+*/
+func parseVersion(section string) (version int, err error) {
+	if !strings.HasPrefix(section, "v=") {
+		return 0, fmt.Errorf("%w: missing version", ErrMalformedParams)
+	}
+
+	v, err := strconv.ParseUint(section[2:], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: version %q", ErrMalformedParams, section[2:])
+	}
+	return int(v), nil
+}
+
+/*
+	parseParams reads the comma separated key=value arguments
+	t=time, m=memory, p=threads, and the optional keyid=pepper version
+	Each argument must appear at most once, in any order.
+*/
+func parseParams(section string) (config KDFconfig, err error) {
+
+	seen := make(map[string]bool)
+
+	for _, param := range strings.Split(section, ",") {
+
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return KDFconfig{}, fmt.Errorf("%w: %q", ErrMalformedParams, param)
+		}
+		if seen[kv[0]] {
+			return KDFconfig{}, fmt.Errorf("%w: duplicate argument %q", ErrMalformedParams, kv[0])
+		}
+		seen[kv[0]] = true
+
+		switch kv[0] {
+		case "t":
+			t, err := strconv.ParseUint(kv[1], 10, 32)
+			if err != nil {
+				return KDFconfig{}, fmt.Errorf("%w: time %q", ErrMalformedParams, kv[1])
+			}
+			config.Time = uint32(t)
+		case "m":
+			m, err := strconv.ParseUint(kv[1], 10, 32)
+			if err != nil {
+				return KDFconfig{}, fmt.Errorf("%w: memory %q", ErrMalformedParams, kv[1])
+			}
+			config.Memory = uint32(m)
+		case "p":
+			p, err := strconv.ParseUint(kv[1], 10, 8)
+			if err != nil {
+				return KDFconfig{}, fmt.Errorf("%w: threads %q", ErrMalformedParams, kv[1])
+			}
+			config.Threads = uint8(p)
+		case "keyid":
+			if !validPepperID(kv[1]) {
+				return KDFconfig{}, fmt.Errorf("%w: keyid %q", ErrMalformedParams, kv[1])
+			}
+			config.PepperID = kv[1]
+		default:
+			return KDFconfig{}, fmt.Errorf("%w: unknown argument %q", ErrMalformedParams, kv[0])
+		}
+	}
+
+	if !seen["t"] || !seen["m"] || !seen["p"] {
+		return KDFconfig{}, fmt.Errorf("%w: missing argument", ErrMalformedParams)
+	}
+
+	/*
+		argon2.IDKey panics with zero time or threads,
+		and silently raises memory below 8 * threads,
+		so neither could have produced a hash we want to trust.
+		Above MaxTime or MaxMemory, confirming the hash would exhaust the host.
+	*/
+	if config.Time < 1 || config.Threads < 1 || config.Memory < 8*uint32(config.Threads) ||
+		config.Time > MaxTime || config.Memory > MaxMemory {
+		return KDFconfig{}, fmt.Errorf("%w: t=%d,m=%d,p=%d out of range", ErrMalformedParams, config.Time, config.Memory, config.Threads)
+	}
+
+	return config, nil
+}
+
+/*
+	decodeSection decodes the salt or key sections of the hash,
+	which are unpadded standard base64 strings
+*/
+func decodeSection(section string) (bs []byte, err error) {
+	if section == "" {
+		return nil, fmt.Errorf("missing")
+	}
+	return base64.RawStdEncoding.Strict().DecodeString(section)
+}
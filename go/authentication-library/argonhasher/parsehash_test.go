@@ -0,0 +1,87 @@
+package argonhasher
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseHash(t *testing.T) {
+
+	const salt = "RfA+5nFy7ASo+3pk0A2X3ANgYCTt/LvT15n2m9Ctj76ok0+AO9xUKhev4YGAb2c6ne48DKGFaErxzOTbjn2qcg"
+	const key = "ReE+hbfju1q1AsV/YEimBA"
+
+	tests := []struct {
+		desc string
+		hash string
+		err  error
+	}{
+		{desc: "Valid Hash", hash: "$argon2id$v=19$t=2,m=65537,p=2$" + salt + "$" + key, err: nil},
+		{desc: "Arguments out of order", hash: "$argon2id$v=19$p=2,t=2,m=65537$" + salt + "$" + key, err: nil},
+		{desc: "Empty Hash", hash: "", err: ErrMalformedHash},
+		{desc: "Truncated Hash", hash: "$argon2id$v=19$t=2", err: ErrMalformedHash},
+		{desc: "Missing leading $", hash: "argon2id$v=19$t=2,m=65537,p=2$" + salt + "$" + key + "$", err: ErrMalformedHash},
+		{desc: "Extra Section", hash: "$argon2id$v=19$t=2,m=65537,p=2$" + salt + "$" + key + "$" + key, err: ErrMalformedHash},
+		{desc: "Missing Salt", hash: "$argon2id$v=19$t=2,m=65537,p=2$$" + key, err: ErrMalformedHash},
+		{desc: "Missing Key", hash: "$argon2id$v=19$t=2,m=65537,p=2$" + salt + "$", err: ErrMalformedHash},
+		{desc: "Invalid base64 Key", hash: "$argon2id$v=19$t=2,m=65537,p=2$" + salt + "$" + key + "==", err: ErrMalformedHash},
+		{desc: "argon2i", hash: "$argon2i$v=19$t=2,m=65537,p=2$" + salt + "$" + key, err: ErrUnknownAlgorithm},
+		{desc: "bcrypt", hash: "$2b$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", err: ErrMalformedHash},
+		{desc: "Old Version", hash: "$argon2id$v=16$t=2,m=65537,p=2$" + salt + "$" + key, err: ErrVersionMismatch},
+		{desc: "Missing Version", hash: "$argon2id$v=$t=2,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Version not prefixed", hash: "$argon2id$19$t=2,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Missing Argument, t", hash: "$argon2id$v=19$t=,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Absent Argument, m", hash: "$argon2id$v=19$t=2,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Duplicate Argument", hash: "$argon2id$v=19$t=2,t=3,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Unknown Argument", hash: "$argon2id$v=19$t=2,m=65537,p=2,x=1$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Non numeric Argument", hash: "$argon2id$v=19$t=two,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Threads overflow", hash: "$argon2id$v=19$t=2,m=65537,p=256$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Zero time", hash: "$argon2id$v=19$t=0,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Zero threads", hash: "$argon2id$v=19$t=2,m=65537,p=0$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Memory below 8 * threads", hash: "$argon2id$v=19$t=2,m=15,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Time above maximum", hash: "$argon2id$v=19$t=4294967295,m=65537,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+		{desc: "Memory above maximum", hash: "$argon2id$v=19$t=2,m=4294967295,p=2$" + salt + "$" + key, err: ErrMalformedParams},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config, k, err := ParseHash(test.hash)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if config.Time != 2 || config.Memory != 65537 || config.Threads != 2 {
+				t.Errorf("incorrect parameters parsed: t=%d,m=%d,p=%d", config.Time, config.Memory, config.Threads)
+			}
+			if config.SaltLength != 64 || len(config.Salt) != 64 {
+				t.Errorf("incorrect salt length: %d", len(config.Salt))
+			}
+			if config.KeyLen != 16 || len(k) != 16 {
+				t.Errorf("incorrect key length: %d", len(k))
+			}
+		})
+	}
+}
+
+func TestConfirmMalformedHash(t *testing.T) {
+	/*
+		Hashes that previously caused Confirm to index out of range
+	*/
+	tests := []string{
+		"$",
+		"$argon2id",
+		"$argon2id$v=19",
+		"$argon2id$v=19$t=2",
+		"$argon2id$v=19$t",
+		"$argon2id$v=19$t=2,m=65537,p=2",
+		"$argon2id$v=19$t=2,m=65537,p=2$c2FsdA",
+	}
+	for _, hash := range tests {
+		t.Run(hash, func(t *testing.T) {
+			if Confirm("password", hash) {
+				t.Errorf("confirmed password against malformed hash %q", hash)
+			}
+		})
+	}
+}
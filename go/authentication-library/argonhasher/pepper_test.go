@@ -0,0 +1,152 @@
+package argonhasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+/*
+	pepperLookup is a stand in for the secret store,
+	returning the pepper for the requested version from the map
+*/
+func pepperLookup(peppers map[string]string) func(pepperID string) string {
+	return func(pepperID string) string {
+		return peppers[pepperID]
+	}
+}
+
+func TestPepper(t *testing.T) {
+
+	peppers := map[string]string{
+		"v1": "first pepper",
+		"v2": "second pepper",
+	}
+
+	config := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16}
+	config.Pepper = pepperLookup(peppers)
+	config.PepperID = "v1"
+
+	const pw = "password"
+
+	hash, err := config.Encode(pw)
+	if err != nil {
+		t.Fatalf("failed to encode peppered hash:\n%v", err)
+	}
+
+	t.Run("Pepper version recorded", func(t *testing.T) {
+		if !strings.Contains(hash, ",keyid=v1$") {
+			t.Fatalf("pepper version not recorded in hash: %v", hash)
+		}
+		parsed, _, err := ParseHash(hash)
+		if err != nil {
+			t.Fatalf("failed to parse peppered hash:\n%v", err)
+		}
+		if parsed.PepperID != "v1" {
+			t.Errorf("incorrect pepper version parsed: %q", parsed.PepperID)
+		}
+	})
+
+	t.Run("Confirm with pepper", func(t *testing.T) {
+		if !config.Confirm(pw, hash) {
+			t.Error("failed to confirm peppered hash")
+		}
+		if config.Confirm("incorrect", hash) {
+			t.Error("confirmed incorrect password against peppered hash")
+		}
+	})
+
+	t.Run("Confirm without pepper", func(t *testing.T) {
+		if Confirm(pw, hash) {
+			t.Error("confirmed peppered hash without the pepper")
+		}
+	})
+
+	t.Run("Confirm with wrong pepper", func(t *testing.T) {
+		wrong := config
+		wrong.Pepper = pepperLookup(map[string]string{"v1": "not the pepper"})
+		if wrong.Confirm(pw, hash) {
+			t.Error("confirmed peppered hash with the wrong pepper")
+		}
+	})
+
+	t.Run("Confirm with missing pepper version", func(t *testing.T) {
+		missing := config
+		missing.Pepper = pepperLookup(map[string]string{"v2": "second pepper"})
+		if missing.Confirm(pw, hash) {
+			t.Error("confirmed peppered hash without the pepper version")
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		rotated := config
+		rotated.PepperID = "v2"
+
+		/* hashes made with the old pepper version are still confirmed */
+		if !rotated.Confirm(pw, hash) {
+			t.Error("failed to confirm hash made with previous pepper version")
+		}
+
+		/* but need upgrading to the new pepper version */
+		if !NeedsRehash(hash, rotated.Policy()) {
+			t.Error("hash made with previous pepper version does not need rehash")
+		}
+
+		upgraded, err := rotated.Encode(pw)
+		if err != nil {
+			t.Fatalf("failed to encode with new pepper version:\n%v", err)
+		}
+		if NeedsRehash(upgraded, rotated.Policy()) {
+			t.Error("upgraded hash needs rehash")
+		}
+		if !rotated.Confirm(pw, upgraded) {
+			t.Error("failed to confirm upgraded hash")
+		}
+	})
+
+	t.Run("Unpeppered hashes need rehash", func(t *testing.T) {
+		unpeppered := config
+		unpeppered.PepperID = ""
+		plain, err := unpeppered.Encode(pw)
+		if err != nil {
+			t.Fatalf("failed to encode without pepper:\n%v", err)
+		}
+		if !config.Confirm(pw, plain) {
+			t.Error("failed to confirm unpeppered hash with pepper config")
+		}
+		if !NeedsRehash(plain, config.Policy()) {
+			t.Error("unpeppered hash does not need rehash")
+		}
+	})
+}
+
+func TestPepperErrors(t *testing.T) {
+	base := KDFconfig{SaltLength: 16, Time: 1, Memory: 64, Threads: 1, KeyLen: 16}
+
+	tests := []struct {
+		desc     string
+		pepperID string
+		pepper   func(string) string
+		err      error
+	}{
+		{desc: "No lookup", pepperID: "v1", pepper: nil, err: ErrFailedPepper},
+		{desc: "Empty pepper", pepperID: "v1", pepper: pepperLookup(nil), err: ErrFailedPepper},
+		{desc: "Invalid version", pepperID: "v1$", pepper: pepperLookup(map[string]string{"v1$": "pepper"}), err: ErrInvalidPepperID},
+		{desc: "Version with separator", pepperID: "v1,t=3", pepper: pepperLookup(map[string]string{"v1,t=3": "pepper"}), err: ErrInvalidPepperID},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			config := base
+			config.PepperID = test.pepperID
+			config.Pepper = test.pepper
+			hash, err := config.Encode("password")
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if hash != "" {
+				t.Errorf("hash returned with error: %v", hash)
+			}
+		})
+	}
+}
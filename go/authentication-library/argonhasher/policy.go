@@ -0,0 +1,106 @@
+package argonhasher
+
+/*
+	Policy is the set of hashing parameters that the current
+	password hashes are expected to have been created with.
+	When the parameters used for new hashes are changed, existing hashes
+	can be checked against the new policy with NeedsRehash, and upgraded
+	the next time the plaintext password is available (i.e. at login).
+*/
+type Policy struct {
+
+	/*
+		Time (i.e. iterations) - t
+	*/
+	Time uint32
+
+	/*
+		Memory (in kilobytes) - m
+	*/
+	Memory uint32
+
+	/*
+		Threads (parallelism) - p
+	*/
+	Threads uint8
+
+	/*
+		KeyLen, the length in bytes of the derived key
+	*/
+	KeyLen uint32
+
+	/*
+		SaltLength, the length in bytes of the random salt
+	*/
+	SaltLength uint
+
+	/*
+		PepperID, the version of the pepper mixed into the hash,
+		empty if hashes should not be peppered.
+	*/
+	PepperID string
+}
+
+/*
+	Policy returns the hashing parameters of the config as a Policy,
+	so hashes can be checked against the config used to create new hashes.
+*/
+func (c KDFconfig) Policy() Policy {
+	return Policy{
+		Time:       c.Time,
+		Memory:     c.Memory,
+		Threads:    c.Threads,
+		KeyLen:     c.KeyLen,
+		SaltLength: c.SaltLength,
+		PepperID:   c.PepperID,
+	}
+}
+
+/*
+	CostPolicy returns the Policy used by Encode for the supplied cost,
+	so hashes created with Encode(pw, cost) can be checked with
+	NeedsRehash(hash, CostPolicy(cost)).
+	A cost of zero returns the default policy.
+*/
+func CostPolicy(cost uint) Policy {
+
+	/*
+		Set the default option cost to be a
+		sensible encoding time on modern hardware.
+	*/
+	if cost == 0 {
+		cost = 3
+	}
+
+	return Policy{
+		Time:       uint32(2 * cost),
+		Memory:     uint32((1 + cost) * 32 * 1024),
+		Threads:    uint8(1 + (cost * 3 / 2)),
+		KeyLen:     16,
+		SaltLength: 64,
+	}
+}
+
+/*
+	NeedsRehash returns true if the supplied hash was not created
+	using the parameters in the supplied policy.
+	Any difference counts, so lowering a parameter in the policy
+	will also cause existing hashes to be replaced.
+	Changing the pepper version in the policy upgrades hashes to the new pepper,
+	so old pepper versions can be retired once the upgraded hashes have replaced them.
+	Hashes that cannot be parsed also need replacing, so return true.
+*/
+func NeedsRehash(hash string, policy Policy) bool {
+
+	config, _, err := ParseHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return config.Time != policy.Time ||
+		config.Memory != policy.Memory ||
+		config.Threads != policy.Threads ||
+		config.KeyLen != policy.KeyLen ||
+		config.SaltLength != policy.SaltLength ||
+		config.PepperID != policy.PepperID
+}
@@ -0,0 +1,38 @@
+package argonhasher
+
+import "testing"
+
+func TestNeedsRehash(t *testing.T) {
+
+	/*
+		Hash the password once at the lowest cost,
+		and check it against a range of policies
+	*/
+	hash := Encode("password", 1)
+	current := CostPolicy(1)
+
+	tests := []struct {
+		desc   string
+		hash   string
+		policy Policy
+		rehash bool
+	}{
+		{desc: "Matching Policy", hash: hash, policy: current, rehash: false},
+		{desc: "Raised Cost", hash: hash, policy: CostPolicy(2), rehash: true},
+		{desc: "Default Cost", hash: hash, policy: CostPolicy(0), rehash: true},
+		{desc: "Increased Time", hash: hash, policy: Policy{Time: current.Time + 1, Memory: current.Memory, Threads: current.Threads, KeyLen: current.KeyLen, SaltLength: current.SaltLength}, rehash: true},
+		{desc: "Reduced Memory", hash: hash, policy: Policy{Time: current.Time, Memory: current.Memory / 2, Threads: current.Threads, KeyLen: current.KeyLen, SaltLength: current.SaltLength}, rehash: true},
+		{desc: "Increased Threads", hash: hash, policy: Policy{Time: current.Time, Memory: current.Memory, Threads: current.Threads + 1, KeyLen: current.KeyLen, SaltLength: current.SaltLength}, rehash: true},
+		{desc: "Longer Key", hash: hash, policy: Policy{Time: current.Time, Memory: current.Memory, Threads: current.Threads, KeyLen: 32, SaltLength: current.SaltLength}, rehash: true},
+		{desc: "Shorter Salt", hash: hash, policy: Policy{Time: current.Time, Memory: current.Memory, Threads: current.Threads, KeyLen: current.KeyLen, SaltLength: 16}, rehash: true},
+		{desc: "Malformed Hash", hash: "$argon2id$v=19$t=2", policy: current, rehash: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if NeedsRehash(test.hash, test.policy) != test.rehash {
+				t.Errorf("NeedsRehash returned %v, wanted %v", !test.rehash, test.rehash)
+			}
+		})
+	}
+}
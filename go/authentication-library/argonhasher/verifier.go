@@ -0,0 +1,186 @@
+package argonhasher
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+	The maximum parameters accepted by the legacy verifiers.
+	As with MaxTime and MaxMemory, a corrupted or crafted stored hash must not be able
+	to pin a core, or exhaust the host's memory, on a single login.
+	Django's defaults, around 1,000,000 PBKDF2 iterations and scrypt N=2^14,r=8,p=1,
+	are well within them.
+*/
+const (
+	maxPBKDF2Iterations = 5000000
+	maxLegacyKeyLen     = 64
+
+	maxScryptN = 1 << 20
+	maxScryptR = 32
+	maxScryptP = 16
+
+	// N·r·p, scrypt uses 128·N·r bytes of memory, and p times the work, so 2GiB at most
+	maxScryptCost = 1 << 24
+)
+
+/*
+	Verifier checks a password against a hash created by another
+	password hashing scheme, allowing legacy hashes to be confirmed
+	until they are replaced by an argon2id hash.
+	Verifiers must reject malformed hashes rather than panicking.
+*/
+type Verifier func(password string, hash string) (valid bool)
+
+/*
+	verifiers is the registry of non argon2id verifiers,
+	keyed by the identifier returned by Identifier.
+*/
+var verifiers = struct {
+	sync.RWMutex
+	m map[string]Verifier
+}{
+	m: map[string]Verifier{
+		"2a":            verifyBcrypt,
+		"2b":            verifyBcrypt,
+		"2y":            verifyBcrypt,
+		"pbkdf2_sha256": verifyDjangoPBKDF2,
+		"scrypt":        verifyDjangoScrypt,
+	},
+}
+
+/*
+	Register adds a verifier for hashes with the supplied identifier,
+	replacing any verifier already registered for it.
+	The identifier is the PHC / modular crypt identifier without the $ delimiters,
+	e.g. "2b" for "$2b$10$...", or the Django algorithm name, e.g. "pbkdf2_sha256".
+	argon2id hashes are always confirmed by the package, so cannot be registered.
+*/
+func Register(identifier string, v Verifier) error {
+	if identifier == "" || strings.Contains(identifier, "$") {
+		return fmt.Errorf("%w: %q", ErrInvalidIdentifier, identifier)
+	}
+	if identifier == "argon2id" {
+		return fmt.Errorf("%w: %q", ErrReservedIdentifier, identifier)
+	}
+	if v == nil {
+		return fmt.Errorf("%w: nil verifier", ErrInputInvalid)
+	}
+
+	verifiers.Lock()
+	defer verifiers.Unlock()
+	verifiers.m[identifier] = v
+	return nil
+}
+
+/*
+	Identifier returns the identifier of the scheme used to create the hash.
+	PHC and modular crypt hashes are prefixed with the identifier between $ delimiters
+	$argon2id$v=19$... returns "argon2id"
+	$2b$10$... returns "2b"
+	Django hashes are prefixed with the identifier and a single $ delimiter
+	pbkdf2_sha256$260000$... returns "pbkdf2_sha256"
+	An empty string is returned if the hash has no identifier.
+*/
+func Identifier(hash string) string {
+	hash = strings.TrimPrefix(hash, "$")
+	i := strings.Index(hash, "$")
+	if i < 0 {
+		return ""
+	}
+	return hash[:i]
+}
+
+/*
+	verifierFor returns the registered verifier for the identifier,
+	or nil if there isn't one.
+*/
+func verifierFor(identifier string) Verifier {
+	verifiers.RLock()
+	defer verifiers.RUnlock()
+	return verifiers.m[identifier]
+}
+
+/*
+	verifyBcrypt confirms bcrypt hashes in the $2a$, $2b$ and $2y$ formats
+	$2b$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy
+*/
+func verifyBcrypt(password string, hash string) (valid bool) {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+/*
+	verifyDjangoPBKDF2 confirms Django's PBKDF2-SHA256 hashes
+	pbkdf2_sha256$ITERATIONS$SALT$BASE64HASH
+	The salt is used as is, and the hash is padded standard base64.
+*/
+func verifyDjangoPBKDF2(password string, hash string) (valid bool) {
+
+	sections := strings.Split(hash, "$")
+	if len(sections) != 4 || sections[2] == "" {
+		return false
+	}
+
+	iterations, err := strconv.ParseUint(sections[1], 10, 31)
+	if err != nil || iterations < 1 || iterations > maxPBKDF2Iterations {
+		return false
+	}
+
+	key, err := base64.StdEncoding.DecodeString(sections[3])
+	if err != nil || len(key) == 0 || len(key) > maxLegacyKeyLen {
+		return false
+	}
+
+	newKey := pbkdf2.Key([]byte(password), []byte(sections[2]), int(iterations), len(key), sha256.New)
+	return subtle.ConstantTimeCompare(key, newKey) == 1
+}
+
+/*
+	verifyDjangoScrypt confirms Django's scrypt hashes
+	scrypt$N$SALT$R$P$BASE64HASH
+	The salt is used as is, and the hash is padded standard base64.
+*/
+func verifyDjangoScrypt(password string, hash string) (valid bool) {
+
+	sections := strings.Split(hash, "$")
+	if len(sections) != 6 || sections[2] == "" {
+		return false
+	}
+
+	var params [3]int
+	for i, section := range []string{sections[1], sections[3], sections[4]} {
+		p, err := strconv.ParseUint(section, 10, 31)
+		if err != nil {
+			return false
+		}
+		params[i] = int(p)
+	}
+
+	n, r, p := params[0], params[1], params[2]
+	if n > maxScryptN || r > maxScryptR || p > maxScryptP || n*r*p > maxScryptCost {
+		return false
+	}
+
+	key, err := base64.StdEncoding.DecodeString(sections[5])
+	if err != nil || len(key) == 0 || len(key) > maxLegacyKeyLen {
+		return false
+	}
+
+	/*
+		scrypt.Key returns an error for out of range parameters
+	*/
+	newKey, err := scrypt.Key([]byte(password), []byte(sections[2]), n, r, p, len(key))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, newKey) == 1
+}
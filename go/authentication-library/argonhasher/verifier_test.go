@@ -0,0 +1,131 @@
+package argonhasher
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestConfirmLegacy(t *testing.T) {
+
+	const pw = "correct horse"
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to generate bcrypt hash: %v", err)
+	}
+
+	tests := []struct {
+		desc  string
+		pw    string
+		hash  string
+		valid bool
+	}{
+		{desc: "bcrypt $2a$", pw: pw, hash: string(bcryptHash), valid: true},
+		{desc: "bcrypt $2b$", pw: pw, hash: "$2b$" + string(bcryptHash[4:]), valid: true},
+		{desc: "bcrypt $2y$", pw: pw, hash: "$2y$" + string(bcryptHash[4:]), valid: true},
+		{desc: "bcrypt incorrect password", pw: "incorrect", hash: string(bcryptHash), valid: false},
+		{desc: "bcrypt truncated", pw: pw, hash: string(bcryptHash[:20]), valid: false},
+		{desc: "pbkdf2_sha256", pw: pw, hash: "pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: true},
+		{desc: "pbkdf2_sha256 incorrect password", pw: "incorrect", hash: "pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: false},
+		{desc: "pbkdf2_sha256 altered iterations", pw: pw, hash: "pbkdf2_sha256$1001$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: false},
+		{desc: "pbkdf2_sha256 zero iterations", pw: pw, hash: "pbkdf2_sha256$0$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: false},
+		{desc: "pbkdf2_sha256 missing salt", pw: pw, hash: "pbkdf2_sha256$1000$$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: false},
+		{desc: "pbkdf2_sha256 truncated", pw: pw, hash: "pbkdf2_sha256$1000$seasalt123", valid: false},
+		{desc: "scrypt", pw: pw, hash: "scrypt$1024$seasalt123$8$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: true},
+		{desc: "scrypt incorrect password", pw: "incorrect", hash: "scrypt$1024$seasalt123$8$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt N not a power of 2", pw: pw, hash: "scrypt$1000$seasalt123$8$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt truncated", pw: pw, hash: "scrypt$1024$seasalt123$8$1", valid: false},
+
+		/*
+			Oversized parameters would pin a core, or exhaust the host's memory,
+			so are rejected before the key is derived
+		*/
+		{desc: "pbkdf2_sha256 iterations above maximum", pw: pw, hash: "pbkdf2_sha256$2147483647$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA=", valid: false},
+		{desc: "pbkdf2_sha256 key above maximum", pw: pw, hash: "pbkdf2_sha256$1000$seasalt123$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", valid: false},
+		{desc: "scrypt N above maximum", pw: pw, hash: "scrypt$1073741824$seasalt123$8$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt r above maximum", pw: pw, hash: "scrypt$1024$seasalt123$33$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt p above maximum", pw: pw, hash: "scrypt$1024$seasalt123$8$17$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt N·r·p above maximum", pw: pw, hash: "scrypt$1048576$seasalt123$32$1$WjiJW2R7EFXFvJWL7gYnIcr1h3m9El4JvqodWswOHtcvChnARzIflnX3B2jbmgsaKg4DDVVfqCo1Iv5DvScVYg==", valid: false},
+		{desc: "scrypt key above maximum", pw: pw, hash: "scrypt$1024$seasalt123$8$1$AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", valid: false},
+		{desc: "Unregistered identifier", pw: pw, hash: "$1$saltsalt$qjXMvbEw8oaL.CzflDugX/", valid: false},
+		{desc: "No identifier", pw: pw, hash: "5f4dcc3b5aa765d61d8327deb882cf99", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := Confirm(test.pw, test.hash); got != test.valid {
+				t.Errorf("Confirm(%q, %q):\nWanted: %v\nGot: %v", test.pw, test.hash, test.valid, got)
+			}
+			if !NeedsRehash(test.hash, CostPolicy(0)) {
+				t.Errorf("legacy hash does not need rehashing: %q", test.hash)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+
+	const pw = "password"
+	const hash = "plain$" + pw
+
+	if Confirm(pw, hash) {
+		t.Fatalf("confirmed password with unregistered identifier")
+	}
+
+	err := Register("plain", func(password string, hash string) bool {
+		return strings.TrimPrefix(hash, "plain$") == password
+	})
+	if err != nil {
+		t.Fatalf("failed to register verifier: %v", err)
+	}
+	defer func() {
+		verifiers.Lock()
+		delete(verifiers.m, "plain")
+		verifiers.Unlock()
+	}()
+
+	if !Confirm(pw, hash) {
+		t.Errorf("registered verifier failed to confirm password")
+	}
+	if Confirm("incorrect", hash) {
+		t.Errorf("registered verifier confirmed incorrect password")
+	}
+
+	errTests := []struct {
+		desc string
+		id   string
+		v    Verifier
+		err  error
+	}{
+		{desc: "Empty identifier", id: "", v: verifyBcrypt, err: ErrInvalidIdentifier},
+		{desc: "Identifier with delimiter", id: "$2b$", v: verifyBcrypt, err: ErrInvalidIdentifier},
+		{desc: "argon2id", id: "argon2id", v: verifyBcrypt, err: ErrReservedIdentifier},
+		{desc: "Nil verifier", id: "nil", v: nil, err: ErrInputInvalid},
+	}
+	for _, test := range errTests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := Register(test.id, test.v); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"$argon2id$v=19$t=2,m=65536,p=2$c2FsdA$a2V5": "argon2id",
+		"$2b$10$N9qo8uLOickgx2ZMRZoMyeI":             "2b",
+		"pbkdf2_sha256$260000$salt$hash":             "pbkdf2_sha256",
+		"scrypt$16384$salt$8$1$hash":                 "scrypt",
+		"5f4dcc3b5aa765d61d8327deb882cf99":           "",
+		"":                                           "",
+	}
+	for hash, want := range tests {
+		if got := Identifier(hash); got != want {
+			t.Errorf("Identifier(%q):\nWanted: %q\nGot: %q", hash, want, got)
+		}
+	}
+}
@@ -1,14 +1,35 @@
 package accesstoken
 
 import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/markstanden/authentication"
 	"github.com/markstanden/jwt"
+	"github.com/markstanden/jwt/paseto"
 	"github.com/markstanden/securerandom"
 )
 
+/*
+	Secret store key names for the signing keys of each algorithm
+*/
+const (
+	hmacKeyName    = "JWT"
+	ed25519KeyName = "JWT_EDDSA"
+)
+
+/*
+	Token formats
+*/
+const (
+	FormatJWT    = "jwt"
+	FormatPASETO = "paseto"
+)
+
 /*
 	** AccessToken **
 	This struct holds the config for the creation, verification, and decoding
@@ -58,6 +79,33 @@ type AccessToken struct {
 		before this will time will be automatically discarded
 	*/
 	StartTime int64
+
+	/*
+		Random generates the unique identifier "jti" for each token.
+		The zero value uses crypto/rand.
+	*/
+	Random securerandom.Generator
+
+	/*
+		Algorithm is the algorithm used to sign and verify tokens, jwt.HS512 (the default) or jwt.EdDSA.
+		HS512 tokens can only be verified by holders of the shared secret,
+		EdDSA tokens can be verified by other services using the public keys published by JWKS.
+	*/
+	Algorithm string
+
+	/*
+		Leeway allows for clock skew between the server instances issuing and verifying tokens,
+		so a token issued by an instance with a clock a few seconds ahead is not rejected.
+	*/
+	Leeway time.Duration
+
+	/*
+		Format is the token format, FormatJWT (the default) or FormatPASETO.
+		PASETO tokens are v4.public when the Algorithm is jwt.EdDSA, verifiable with the
+		public keys published by JWKS, and v4.local, encrypted with a key derived from the
+		HMAC secret, otherwise.  The same claims, and checks, are used for both formats.
+	*/
+	Format string
 }
 
 func New() (at AccessToken) {
@@ -77,13 +125,19 @@ func (ts *AccessToken) Create(userID string) (jwtString, jwtID string, err error
 	}
 
 	// The unique identifier for this particular token
-	jwtID = securerandom.String(64)
-	if jwtID == "" {
-		return "", "", fmt.Errorf("failed to create 'jti' :\n%v", err)
+	jti, err := ts.Random.UUIDv4()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create 'jti' :\n%w", err)
 	}
+	jwtID = jti.String()
 
 	// the unique identifier for the secret
-	keyID := ts.Secret.GetKeyID("JWT")
+	keyID := ts.Secret.GetKeyID(ts.keyName())
+
+	signer, err := ts.signer(keyID)
+	if err != nil {
+		return "", "", err
+	}
 
 	// the number of seconds the token is valid for
 	validFor := minsToSeconds(ts.MinsValid)
@@ -91,7 +145,11 @@ func (ts *AccessToken) Create(userID string) (jwtString, jwtID string, err error
 	//create the token, and return
 	t := jwt.NewToken(ts.Issuer, ts.Audience, userID, jwtID, keyID, validFor)
 
-	jwtString, err = t.Create(ts.Secret.GetSecret("JWT"))
+	if ts.Format == FormatPASETO {
+		jwtString, err = ts.createPASETO(t)
+	} else {
+		jwtString, err = t.Sign(signer)
+	}
 	if err != nil {
 		return "", "", err
 	}
@@ -111,20 +169,196 @@ func (ts *AccessToken) Decode(jwtString string) (tokenUserID, jwtID string, err
 	data := new(jwt.Token)
 	data.Config.Lifespan = minsToSeconds(ts.MinsValid)
 
-	err = jwt.Decode(jwtString, ts.Secret.GetSecret("JWT"), data)
-	if err != nil {
-		return "", "", fmt.Errorf("authentication/tokenhandler/token: Failed to decode JWT: \n%v", err)
+	options := []jwt.DecodeOption{
+		jwt.WithIssuer(ts.Issuer),
+		jwt.WithAudience(ts.Audience),
+		jwt.WithLeeway(ts.Leeway),
 	}
-	if data.Audience != ts.Audience {
-		return "", "", fmt.Errorf("authentication/tokenhandler/token: token is invalid - incorrect audience: \nWanted: %v, Got: %v", ts.Audience, data.Audience)
+
+	if ts.Format == FormatPASETO {
+		err = paseto.Decode(jwtString, ts.pasetoKeys(), data, options...)
+	} else {
+		/*
+			Tokens issued before the key ID moved to the header carry it in the payload,
+			so are accepted until they expire
+		*/
+		err = jwt.Decode(jwtString, nil, data, append(options,
+			jwt.AllowPayloadKeyID(),
+			jwt.WithAlgorithms(ts.algorithm()),
+			jwt.WithKeyLookup(ts.lookup))...)
 	}
-	if data.Issuer != ts.Issuer {
-		return "", "", fmt.Errorf("authentication/tokenhandler/token: token is invalid - incorrect issuer: \nWanted: %v, Got: %v", ts.Issuer, data.Issuer)
+	if err != nil {
+		return "", "", fmt.Errorf("authentication/tokenhandler/token: Failed to decode JWT: \n%w", err)
 	}
 
 	return data.UserID, data.JwtID, nil
 }
 
+/*
+	** JWKS **
+	JWKS returns the public keys for the next, current and previous EdDSA signing keys,
+	for other services to verify our tokens.
+	HMAC secrets are never published, so the set is empty unless the Algorithm is jwt.EdDSA.
+	JWKS only reads the existing keys, new keys are created when tokens are signed.
+*/
+func (ts *AccessToken) JWKS() (keySet jwt.JWKS, err error) {
+	keySet.Keys = []jwt.JWK{}
+	if ts.algorithm() != jwt.EdDSA {
+		return keySet, nil
+	}
+
+	for _, keyID := range ts.Secret.GetKeyIDs(ed25519KeyName) {
+		key, err := ts.ed25519Key(keyID)
+		if err != nil {
+			return jwt.JWKS{}, err
+		}
+		jwk, err := jwt.NewJWK(keyID, jwt.EdDSA, key.Public())
+		if err != nil {
+			return jwt.JWKS{}, err
+		}
+		keySet.Keys = append(keySet.Keys, jwk)
+	}
+	return keySet, nil
+}
+
+/*
+	** algorithm **
+	algorithm returns the configured algorithm, defaulting to HS512
+*/
+func (ts *AccessToken) algorithm() string {
+	if ts.Algorithm == "" {
+		return jwt.HS512
+	}
+	return ts.Algorithm
+}
+
+/*
+	** keyName **
+	keyName returns the secret store key name for the configured algorithm
+*/
+func (ts *AccessToken) keyName() string {
+	if ts.algorithm() == jwt.EdDSA {
+		return ed25519KeyName
+	}
+	return hmacKeyName
+}
+
+/*
+	** signer **
+	signer returns the jwt.Signer for the configured algorithm, using the key version keyID
+*/
+func (ts *AccessToken) signer(keyID string) (signer jwt.Signer, err error) {
+	switch ts.algorithm() {
+	case jwt.HS512:
+		secret := ts.Secret.GetSecret(hmacKeyName)(keyID)
+		if secret == "" {
+			return nil, jwt.ErrFailedSecret
+		}
+		return jwt.NewHMAC(jwt.HS512, secret)
+	case jwt.EdDSA:
+		key, err := ts.ed25519Key(keyID)
+		if err != nil {
+			return nil, err
+		}
+		return jwt.NewEd25519Signer(key)
+	}
+	return nil, fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, ts.Algorithm)
+}
+
+/*
+	** lookup **
+	lookup is the jwt.KeyLookup used to verify tokens, returning the
+	verifier for the key version keyID.  The algorithm has already been
+	checked against the configured algorithm by jwt.Decode.
+*/
+func (ts *AccessToken) lookup(keyID, algorithm string) (verifier jwt.Verifier, err error) {
+	signer, err := ts.signer(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// both of the supported signers verify their own tokens
+	verifier, ok := signer.(jwt.Verifier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, algorithm)
+	}
+	return verifier, nil
+}
+
+/*
+	** createPASETO **
+	createPASETO creates a v4.public token for jwt.EdDSA, or a v4.local token for jwt.HS512,
+	using the key version in the token's KeyID
+*/
+func (ts *AccessToken) createPASETO(t *jwt.Token) (token string, err error) {
+	switch ts.algorithm() {
+	case jwt.HS512:
+		key, err := ts.localKey(t.KeyID)
+		if err != nil {
+			return "", err
+		}
+		return paseto.CreateLocal(t, key)
+	case jwt.EdDSA:
+		key, err := ts.ed25519Key(t.KeyID)
+		if err != nil {
+			return "", err
+		}
+		return paseto.CreatePublic(t, key)
+	}
+	return "", fmt.Errorf("%w: %q", jwt.ErrUnsupportedAlgorithm, ts.Algorithm)
+}
+
+/*
+	** pasetoKeys **
+	pasetoKeys returns the keys for the PASETO purpose matching the configured algorithm,
+	so only tokens of that purpose are accepted
+*/
+func (ts *AccessToken) pasetoKeys() (keys paseto.Keys) {
+	switch ts.algorithm() {
+	case jwt.HS512:
+		keys.Local = ts.localKey
+	case jwt.EdDSA:
+		keys.Public = func(keyID string) (ed25519.PublicKey, error) {
+			key, err := ts.ed25519Key(keyID)
+			if err != nil {
+				return nil, err
+			}
+			return key.Public().(ed25519.PublicKey), nil
+		}
+	}
+	return keys
+}
+
+/*
+	** localKey **
+	localKey derives the PASETO v4.local key for the key version keyID
+	from the HMAC secret, so it differs from the key used to sign JWTs
+*/
+func (ts *AccessToken) localKey(keyID string) (key []byte, err error) {
+	secret := ts.Secret.GetSecret(hmacKeyName)(keyID)
+	if secret == "" {
+		return nil, jwt.ErrFailedSecret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("paseto v4.local"))
+	return mac.Sum(nil), nil
+}
+
+/*
+	** ed25519Key **
+	ed25519Key derives the Ed25519 private key for the key version keyID
+	from its secret, so the signing keys are stored and rotated by the
+	secret store in the same way as the HMAC secrets.
+*/
+func (ts *AccessToken) ed25519Key(keyID string) (key ed25519.PrivateKey, err error) {
+	secret := ts.Secret.GetSecret(ed25519KeyName)(keyID)
+	if secret == "" {
+		return nil, jwt.ErrFailedSecret
+	}
+	seed := sha256.Sum256([]byte(secret))
+	return ed25519.NewKeyFromSeed(seed[:]), nil
+}
+
 /*
 	** minsToSeconds **
 	minsToSeconds returns the minutes valid (int) in seconds (int64)
@@ -1,16 +1,35 @@
 package accesstoken
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/markstanden/authentication/datastores/postgres"
 	"github.com/markstanden/authentication/datastores/secretstore"
+	"github.com/markstanden/jwt"
+	"github.com/markstanden/jwt/paseto"
 	"github.com/markstanden/securerandom"
 )
 
+/*
+	Tokens must not be issued without a unique "jti"
+*/
+func TestCreateRandomFailure(t *testing.T) {
+	ts := New()
+	ts.Random = securerandom.Generator{Source: iotest.ErrReader(errors.New("no entropy"))}
+
+	jwtString, jwtID, err := ts.Create("tokenuserid")
+	if !errors.Is(err, securerandom.ErrEntropy) || jwtString != "" || jwtID != "" {
+		t.Errorf("unexpected result with a failing generator: %q, %q, %v", jwtString, jwtID, err)
+	}
+}
+
 /*
 	Tests for the creation of a valid JWT
 */
@@ -92,3 +111,240 @@ func TestCreate(t *testing.T) {
 		})
 	}
 }
+
+/*
+	memSecrets is an in-memory authentication.SecretDataStore,
+	where rotate simulates the secret store creating a new key
+*/
+type memSecrets struct {
+	keyIDs  map[string][]string
+	secrets map[string]string
+}
+
+func newMemSecrets() *memSecrets {
+	ms := &memSecrets{}
+	ms.FullReset()
+	return ms
+}
+
+func (ms *memSecrets) FullReset() error {
+	ms.keyIDs = make(map[string][]string)
+	ms.secrets = make(map[string]string)
+	return nil
+}
+
+func (ms *memSecrets) GetSecret(keyName string) func(keyID string) string {
+	return func(keyID string) string {
+		return ms.secrets[keyName+keyID]
+	}
+}
+
+func (ms *memSecrets) GetKeyID(keyName string) string {
+	if len(ms.keyIDs[keyName]) == 0 {
+		ms.rotate(keyName)
+	}
+	return ms.keyIDs[keyName][0]
+}
+
+func (ms *memSecrets) GetKeyIDs(keyName string) []string {
+	keyIDs := ms.keyIDs[keyName]
+	if len(keyIDs) > 2 {
+		keyIDs = keyIDs[:2]
+	}
+	return keyIDs
+}
+
+func (ms *memSecrets) rotate(keyName string) {
+	keyID := securerandom.String(16)
+	ms.keyIDs[keyName] = append([]string{keyID}, ms.keyIDs[keyName]...)
+	ms.secrets[keyName+keyID] = securerandom.String(128)
+}
+
+/*
+	EdDSA tokens must be verifiable by other services
+	using only the published JWK Set
+*/
+func TestEdDSA(t *testing.T) {
+	secrets := newMemSecrets()
+	ts := New()
+	ts.Audience = "Test"
+	ts.Issuer = "Test Issuer"
+	ts.MinsValid = 10
+	ts.Secret = secrets
+	ts.Algorithm = jwt.EdDSA
+
+	/*
+		Publishing the keys must not create one
+	*/
+	keySet, err := ts.JWKS()
+	if err != nil || len(keySet.Keys) != 0 || len(secrets.keyIDs[ed25519KeyName]) != 0 {
+		t.Fatalf("JWKS created a key: %+v, %v", keySet, err)
+	}
+
+	jwtString, jwtID, err := ts.Create("tokenuserid")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	userID, decodedID, err := ts.Decode(jwtString)
+	if err != nil || userID != "tokenuserid" || decodedID != jwtID {
+		t.Fatalf("failed to decode token: %q, %q, %v", userID, decodedID, err)
+	}
+
+	/*
+		Tokens for another audience or issuer are rejected
+	*/
+	other := ts
+	other.Audience = "Other"
+	if _, _, err := other.Decode(jwtString); !errors.Is(err, jwt.ErrInvalidAudience) {
+		t.Errorf("unexpected error for another audience:\nWanted: %v\nGot: %v", jwt.ErrInvalidAudience, err)
+	}
+	other = ts
+	other.Issuer = "Other Issuer"
+	if _, _, err := other.Decode(jwtString); !errors.Is(err, jwt.ErrInvalidIssuer) {
+		t.Errorf("unexpected error for another issuer:\nWanted: %v\nGot: %v", jwt.ErrInvalidIssuer, err)
+	}
+
+	/*
+		A downstream service verifies the token with the published keys
+	*/
+	verify := func(jwtString string) error {
+		keySet, err := ts.JWKS()
+		if err != nil {
+			return err
+		}
+		document, err := json.Marshal(keySet)
+		if err != nil {
+			return err
+		}
+		ks, err := jwt.ParseJWKS(document)
+		if err != nil {
+			return err
+		}
+		token := jwt.Token{Config: jwt.Config{Lifespan: 600}}
+		return jwt.Decode(jwtString, nil, &token, jwt.WithAlgorithms(jwt.EdDSA), jwt.WithKeyLookup(ks.Lookup))
+	}
+	if err := verify(jwtString); err != nil {
+		t.Errorf("failed to verify token using the JWKS: %v", err)
+	}
+
+	/*
+		After a rotation, tokens signed with the previous key remain valid
+	*/
+	secrets.rotate(ed25519KeyName)
+	keySet, err = ts.JWKS()
+	if err != nil || len(keySet.Keys) != 2 {
+		t.Fatalf("JWKS should hold the current and previous keys: %+v, %v", keySet, err)
+	}
+	if err := verify(jwtString); err != nil {
+		t.Errorf("failed to verify token signed with the previous key: %v", err)
+	}
+
+	/*
+		Once the key is no longer published, its tokens are rejected
+	*/
+	secrets.rotate(ed25519KeyName)
+	if err := verify(jwtString); !errors.Is(err, jwt.ErrFailedSecret) {
+		t.Errorf("verified a token signed with an unpublished key:\nWanted: %v\nGot: %v", jwt.ErrFailedSecret, err)
+	}
+
+	/*
+		HS512 tokens must not be accepted by an EdDSA service, or vice versa
+	*/
+	hs := ts
+	hs.Algorithm = ""
+	hsToken, _, err := hs.Create("tokenuserid")
+	if err != nil {
+		t.Fatalf("failed to create HS512 token: %v", err)
+	}
+	if _, _, err := ts.Decode(hsToken); err == nil {
+		t.Error("EdDSA service accepted an HS512 token")
+	}
+	if _, _, err := hs.Decode(jwtString); err == nil {
+		t.Error("HS512 service accepted an EdDSA token")
+	}
+
+	/*
+		HMAC secrets are never published
+	*/
+	keySet, err = hs.JWKS()
+	if err != nil || len(keySet.Keys) != 0 {
+		t.Errorf("published keys for HS512: %+v, %v", keySet, err)
+	}
+}
+
+/*
+	*** TestPASETO ***
+	TestPASETO checks PASETO tokens can be created and decoded for each algorithm,
+	and are only accepted by services configured for the same format and algorithm
+*/
+func TestPASETO(t *testing.T) {
+	tests := []struct {
+		desc      string
+		algorithm string
+		header    string
+	}{
+		{desc: "HS512", algorithm: jwt.HS512, header: paseto.V4Local},
+		{desc: "EdDSA", algorithm: jwt.EdDSA, header: paseto.V4Public},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ts := New()
+			ts.Audience = "Test"
+			ts.Issuer = "Test Issuer"
+			ts.MinsValid = 10
+			ts.Secret = newMemSecrets()
+			ts.Algorithm = test.algorithm
+			ts.Format = FormatPASETO
+
+			token, jwtID, err := ts.Create("tokenuserid")
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			if !strings.HasPrefix(token, test.header) {
+				t.Errorf("incorrect token header:\nWanted: %v\nGot: %v", test.header, token)
+			}
+
+			userID, decodedID, err := ts.Decode(token)
+			if err != nil || userID != "tokenuserid" || decodedID != jwtID {
+				t.Fatalf("failed to decode token: %q, %q, %v", userID, decodedID, err)
+			}
+
+			other := ts
+			other.Audience = "Other"
+			if _, _, err := other.Decode(token); !errors.Is(err, jwt.ErrInvalidAudience) {
+				t.Errorf("unexpected error for another audience:\nWanted: %v\nGot: %v", jwt.ErrInvalidAudience, err)
+			}
+
+			/*
+				JWT services don't accept PASETO tokens, and PASETO services don't accept JWTs
+			*/
+			other = ts
+			other.Format = FormatJWT
+			if _, _, err := other.Decode(token); !errors.Is(err, jwt.ErrInvalidToken) {
+				t.Errorf("JWT service accepted a PASETO token:\nWanted: %v\nGot: %v", jwt.ErrInvalidToken, err)
+			}
+			jwtString, _, err := other.Create("tokenuserid")
+			if err != nil {
+				t.Fatalf("failed to create JWT: %v", err)
+			}
+			if _, _, err := ts.Decode(jwtString); !errors.Is(err, jwt.ErrInvalidToken) {
+				t.Errorf("PASETO service accepted a JWT:\nWanted: %v\nGot: %v", jwt.ErrInvalidToken, err)
+			}
+		})
+	}
+
+	/*
+		A v4.public service must not accept v4.local tokens, or vice versa
+	*/
+	local, public := New(), New()
+	local.Secret, public.Secret = newMemSecrets(), newMemSecrets()
+	local.Format, public.Format = FormatPASETO, FormatPASETO
+	public.Algorithm = jwt.EdDSA
+	localToken, _, err := local.Create("tokenuserid")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if _, _, err := public.Decode(localToken); !errors.Is(err, jwt.ErrInvalidToken) {
+		t.Errorf("v4.public service accepted a v4.local token:\nWanted: %v\nGot: %v", jwt.ErrInvalidToken, err)
+	}
+}
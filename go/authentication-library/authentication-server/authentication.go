@@ -1,6 +1,11 @@
 package authentication
 
-import "errors"
+import (
+	"context"
+	"errors"
+
+	"github.com/markstanden/jwt"
+)
 
 //User is the base struct for our User model.
 //	UniqueID string
@@ -49,6 +54,8 @@ type SecretDataStore interface {
 	// Takes directly from the store
 	GetSecret(keyName string) func(keyID string) (secret string)
 	GetKeyID(keyName string) (keyID string)
+	// The next (once created), current and previous KeyIDs, newest first
+	GetKeyIDs(keyName string) (keyIDs []string)
 }
 
 /*
@@ -61,11 +68,27 @@ type DataStore interface {
 
 /*
 	** PasswordHash **
-	specifies the requirements of the passord hashing module
+	specifies the requirements of the passord hashing module.
+	NeedsRehash reports stored hashes that were created with outdated parameters,
+	so they can be replaced while the plain text password is available at login.
+	EncodeContext and CompareContext give up waiting to hash once the context is done,
+	i.e. when the client disconnects, so the request's context should be passed when there is one.
 */
 type PasswordHash interface {
-	Encode(plainTextPassword string) string
-	Compare(plainTextPassword, hashedPassword string) bool
+	Encode(plainTextPassword string) (hashedPassword string, err error)
+	Compare(plainTextPassword, hashedPassword string) (match bool, err error)
+	EncodeContext(ctx context.Context, plainTextPassword string) (hashedPassword string, err error)
+	CompareContext(ctx context.Context, plainTextPassword, hashedPassword string) (match bool, err error)
+	NeedsRehash(hashedPassword string) bool
+}
+
+/*
+	** BreachedPasswords **
+	specifies the requirements of the breached password screening module,
+	used to reject new passwords that have appeared in a data breach.
+*/
+type BreachedPasswords interface {
+	Breached(plainTextPassword string) (breached bool, err error)
 }
 
 /*
@@ -79,6 +102,15 @@ type AccessTokenService interface {
 	//GetSecret(version string) (secret string)
 }
 
+/*
+	** Key Set Service **
+	Publishes the public keys used to verify access tokens as a JWK Set,
+	so other services can verify tokens without holding our secrets.
+*/
+type KeySetService interface {
+	JWKS() (keySet jwt.JWKS, err error)
+}
+
 /*
 	** Refresh Token Service **
 	The required methods to issue and verify the refresh token implementation
@@ -98,6 +130,8 @@ type RefreshTokenService interface {
 type UserService interface {
 	NewUser(name, email, password string) (user *User, err error)
 	Login(email, password string) (user *User, err error)
+	NewUserContext(ctx context.Context, name, email, password string) (user *User, err error)
+	LoginContext(ctx context.Context, email, password string) (user *User, err error)
 }
 
 /*
@@ -108,10 +142,14 @@ var (
 	// users
 	ErrUserNotFound      = errors.New("user not found")
 	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrBreachedPassword  = errors.New("password has appeared in a data breach")
 
 	// tokens
 	ErrExpiredToken = errors.New("expired token")
 
 	// internal error
 	ErrInternalServerError = errors.New("internal server error")
+
+	// overloaded, the request can be retried later
+	ErrServiceBusy = errors.New("service busy")
 )
@@ -0,0 +1,141 @@
+/*
+	breachindex screens passwords against a corpus of breached passwords,
+	such as the Have I Been Pwned SHA-1 dump or a list of the most common passwords,
+	without any network calls at runtime.
+	The corpus is built into a compact index file of sorted SHA-1 hashes by an external
+	merge sort, and the index searched on disk, so the corpus never needs to fit in memory.
+
+	Index file format:
+		8 byte header "BRCHIDX1"
+		the sorted, unique, 20 byte SHA-1 hashes of the breached passwords
+*/
+package breachindex
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	ErrInvalidIndex = errors.New("invalid breached password index")
+)
+
+const (
+	header   = "BRCHIDX1"
+	hashSize = sha1.Size
+)
+
+/*
+	** Index **
+	Index is an open breached password index file.
+	It implements authentication.BreachedPasswords, and is safe for concurrent use.
+*/
+type Index struct {
+	file  *os.File
+	count int64
+}
+
+/*
+	** Open **
+	Open opens the index file at path, checking it was created by Build
+*/
+func Open(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := newIndex(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %v: %v", ErrInvalidIndex, path, err)
+	}
+	return index, nil
+}
+
+func newIndex(file *os.File) (*Index, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	size := info.Size() - int64(len(header))
+	if size < 0 || size%hashSize != 0 {
+		return nil, fmt.Errorf("unexpected file size %d", info.Size())
+	}
+
+	h := make([]byte, len(header))
+	if _, err := file.ReadAt(h, 0); err != nil {
+		return nil, err
+	}
+	if string(h) != header {
+		return nil, fmt.Errorf("unexpected header %q", h)
+	}
+
+	return &Index{file: file, count: size / hashSize}, nil
+}
+
+/*
+	** Close **
+	Close closes the index file
+*/
+func (idx *Index) Close() error {
+	return idx.file.Close()
+}
+
+/*
+	** Len **
+	Len returns the number of breached password hashes within the index
+*/
+func (idx *Index) Len() int64 {
+	return idx.count
+}
+
+/*
+	** Breached **
+	Breached returns true if the password is within the index
+*/
+func (idx *Index) Breached(plainTextPassword string) (breached bool, err error) {
+	hash := sha1.Sum([]byte(plainTextPassword))
+	return idx.Contains(hash)
+}
+
+/*
+	** Contains **
+	Contains binary searches the index file for the SHA-1 hash
+*/
+func (idx *Index) Contains(hash [hashSize]byte) (found bool, err error) {
+	record := make([]byte, hashSize)
+	low, high := int64(0), idx.count
+	for low < high {
+		mid := low + (high-low)/2
+		if _, err := idx.file.ReadAt(record, int64(len(header))+mid*hashSize); err != nil {
+			return false, err
+		}
+		switch bytes.Compare(record, hash[:]) {
+		case 0:
+			return true, nil
+		case -1:
+			low = mid + 1
+		default:
+			high = mid
+		}
+	}
+	return false, nil
+}
+
+/*
+	** Build **
+	Build sorts the corpus hashes, and writes them to w in the index file format.
+	The hashes are sorted in memory, so building needs 20 bytes of memory per hash,
+	use a Builder for corpora too large to hold in memory.
+	It returns the number of unique hashes written.
+*/
+func Build(w io.Writer, hashes [][hashSize]byte) (count int, err error) {
+	sortHashes(hashes)
+	return writeIndex(w, &sliceReader{hashes: hashes})
+}
@@ -0,0 +1,199 @@
+package breachindex_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/authentication"
+	"github.com/markstanden/authentication/breachindex"
+)
+
+var _ authentication.BreachedPasswords = &breachindex.Index{}
+
+/*
+	buildIndex builds an index file from the corpus in a temporary directory
+*/
+func buildIndex(t *testing.T, corpus string, config breachindex.CorpusConfig) *breachindex.Index {
+	t.Helper()
+
+	hashes, err := breachindex.ReadCorpus(strings.NewReader(corpus), config)
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "breached.idx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := breachindex.Build(f, hashes); err != nil {
+		t.Fatalf("failed to build index: %v", err)
+	}
+	f.Close()
+
+	index, err := breachindex.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+func TestIndex(t *testing.T) {
+
+	/*
+		SHA-1 hashes of "password", "123456" and "qwerty", unsorted, in HIBP format
+	*/
+	const hibp = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577\r\n" +
+		"b1b3773a05c0ed0176787a4f1574ff0075f7521e:5\r\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577\r\n"
+
+	tests := []struct {
+		desc     string
+		corpus   string
+		config   breachindex.CorpusConfig
+		count    int64
+		breached []string
+		safe     []string
+	}{
+		{desc: "HIBP hashes", corpus: hibp, count: 3, breached: []string{"password", "123456", "qwerty"}, safe: []string{"correct horse battery staple", "Password"}},
+		{desc: "HIBP minimum count", corpus: hibp, config: breachindex.CorpusConfig{MinCount: 10}, count: 2, breached: []string{"password", "123456"}, safe: []string{"qwerty"}},
+		{desc: "Plaintext", corpus: "letmein\nmonkey\n\ndragon\n", config: breachindex.CorpusConfig{Plaintext: true}, count: 3, breached: []string{"letmein", "monkey", "dragon"}, safe: []string{"", "password"}},
+		{desc: "Empty corpus", corpus: "", count: 0, safe: []string{"password"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			index := buildIndex(t, test.corpus, test.config)
+			if index.Len() != test.count {
+				t.Errorf("incorrect number of hashes:\nWanted: %d\nGot: %d", test.count, index.Len())
+			}
+			for _, pw := range test.breached {
+				if breached, err := index.Breached(pw); !breached || err != nil {
+					t.Errorf("breached password %q not found: %v", pw, err)
+				}
+			}
+			for _, pw := range test.safe {
+				if breached, err := index.Breached(pw); breached || err != nil {
+					t.Errorf("password %q incorrectly found: %v", pw, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexLarge(t *testing.T) {
+
+	var corpus strings.Builder
+	for i := 0; i < 5000; i++ {
+		corpus.WriteString(strings.Repeat("x", i%7) + string(rune('a'+i%26)) + strings.Repeat("9", i/26) + "\n")
+	}
+	index := buildIndex(t, corpus.String(), breachindex.CorpusConfig{Plaintext: true})
+
+	for _, line := range strings.Split(strings.TrimSpace(corpus.String()), "\n") {
+		if found, err := index.Contains(sha1.Sum([]byte(line))); !found || err != nil {
+			t.Fatalf("breached password %q not found: %v", line, err)
+		}
+	}
+}
+
+/*
+	TestBuilder checks the external merge sort writes the same index as Build,
+	for sorted and unsorted corpora spread over many runs, with duplicates between runs.
+*/
+func TestBuilder(t *testing.T) {
+
+	var corpus strings.Builder
+	for i := 0; i < 1000; i++ {
+		corpus.WriteString(fmt.Sprintf("password%d\n", i%700))
+	}
+	hashes, err := breachindex.ReadCorpus(strings.NewReader(corpus.String()), breachindex.CorpusConfig{Plaintext: true})
+	if err != nil {
+		t.Fatalf("failed to read corpus: %v", err)
+	}
+	sorted := append([][sha1.Size]byte(nil), hashes...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+
+	var want bytes.Buffer
+	if _, err := breachindex.Build(&want, append([][sha1.Size]byte(nil), hashes...)); err != nil {
+		t.Fatalf("failed to build index: %v", err)
+	}
+
+	tests := []struct {
+		desc    string
+		hashes  [][sha1.Size]byte
+		runSize int
+	}{
+		{desc: "Unsorted, many runs", hashes: hashes, runSize: 64},
+		{desc: "Sorted, many runs", hashes: sorted, runSize: 64},
+		{desc: "Run size of one", hashes: hashes, runSize: 1},
+		{desc: "Single run", hashes: hashes, runSize: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			builder := breachindex.NewBuilder(dir, test.runSize)
+			for _, hash := range test.hashes {
+				if err := builder.Add(hash); err != nil {
+					t.Fatalf("failed to add hash: %v", err)
+				}
+			}
+
+			var got bytes.Buffer
+			count, err := builder.Finish(&got)
+			if err != nil {
+				t.Fatalf("failed to finish index: %v", err)
+			}
+			if count != 700 || !bytes.Equal(got.Bytes(), want.Bytes()) {
+				t.Errorf("incorrect index written, %d hashes", count)
+			}
+
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("temporary files not removed: %v", files)
+			}
+		})
+	}
+}
+
+func TestReadCorpusErrors(t *testing.T) {
+	tests := map[string]string{
+		"Short hash":    "5BAA61E4C9B93F3F0682250B6CF8331B7EE68F:1\n",
+		"Invalid hex":   "ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n",
+		"Invalid count": "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:many\n",
+	}
+	for desc, corpus := range tests {
+		t.Run(desc, func(t *testing.T) {
+			if _, err := breachindex.ReadCorpus(strings.NewReader(corpus), breachindex.CorpusConfig{MinCount: 1}); err == nil {
+				t.Errorf("invalid corpus read without error")
+			}
+		})
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string][]byte{
+		"Empty file":     {},
+		"Wrong header":   []byte("NOTANIDX"),
+		"Truncated hash": append([]byte("BRCHIDX1"), bytes.Repeat([]byte{1}, 19)...),
+	}
+	for desc, content := range tests {
+		t.Run(desc, func(t *testing.T) {
+			path := filepath.Join(dir, desc)
+			if err := os.WriteFile(path, content, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := breachindex.Open(path); !errors.Is(err, breachindex.ErrInvalidIndex) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", breachindex.ErrInvalidIndex, err)
+			}
+		})
+	}
+}
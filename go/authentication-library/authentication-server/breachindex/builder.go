@@ -0,0 +1,280 @@
+package breachindex
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+/*
+	DefaultRunSize is the number of hashes a Builder sorts in memory at once,
+	around 320MB of hashes.
+*/
+const DefaultRunSize = 1 << 24
+
+/*
+	** Builder **
+	Builder writes an index from a corpus of any size, in bounded memory,
+	with an external merge sort.
+	Hashes are added to a run of at most RunSize hashes, which is sorted and
+	written to a temporary file once full.  Finish merges the sorted runs into the index.
+	A corpus that is already sorted, such as the Have I Been Pwned downloads,
+	fills each run in order, so the runs do not need sorting.
+	A Builder is not safe for concurrent use.
+*/
+type Builder struct {
+	tempDir string
+	runSize int
+
+	/*
+		run holds the hashes added since the last spill,
+		and sorted is true while they were added in order
+	*/
+	run    [][hashSize]byte
+	sorted bool
+
+	/*
+		spills are the temporary files holding the full, sorted runs
+	*/
+	spills []*os.File
+}
+
+/*
+	** NewBuilder **
+	NewBuilder returns a Builder that sorts runSize hashes in memory at a time,
+	writing the sorted runs to temporary files within tempDir.
+	A runSize below 1 uses DefaultRunSize, and an empty tempDir uses os.TempDir.
+*/
+func NewBuilder(tempDir string, runSize int) *Builder {
+	if runSize < 1 {
+		runSize = DefaultRunSize
+	}
+	return &Builder{tempDir: tempDir, runSize: runSize, sorted: true}
+}
+
+/*
+	** Add **
+	Add adds the hash to the index, writing the current run to a temporary file once full
+*/
+func (b *Builder) Add(hash [hashSize]byte) error {
+	if n := len(b.run); n > 0 && bytes.Compare(hash[:], b.run[n-1][:]) < 0 {
+		b.sorted = false
+	}
+	b.run = append(b.run, hash)
+
+	if len(b.run) >= b.runSize {
+		return b.spill()
+	}
+	return nil
+}
+
+/*
+	** Finish **
+	Finish merges the added hashes, and writes them to w in the index file format,
+	removing the temporary files.
+	It returns the number of unique hashes written.
+*/
+func (b *Builder) Finish(w io.Writer) (count int, err error) {
+	defer b.Close()
+
+	/*
+		A corpus that fits within a single run is written directly
+	*/
+	if len(b.spills) == 0 {
+		b.sortRun()
+		return writeIndex(w, &sliceReader{hashes: b.run})
+	}
+
+	if len(b.run) > 0 {
+		if err := b.spill(); err != nil {
+			return 0, err
+		}
+	}
+
+	merge := make(mergeHeap, 0, len(b.spills))
+	for _, f := range b.spills {
+		run := &runReader{r: bufio.NewReader(f)}
+		ok, err := run.next()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			merge = append(merge, run)
+		}
+	}
+	heap.Init(&merge)
+	return writeIndex(w, &merge)
+}
+
+/*
+	** Close **
+	Close removes the temporary files, and is called by Finish.
+	It should be deferred to clean up if the corpus cannot be read.
+*/
+func (b *Builder) Close() error {
+	var err error
+	for _, f := range b.spills {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if removeErr := os.Remove(f.Name()); err == nil {
+			err = removeErr
+		}
+	}
+	b.spills = nil
+	b.run = nil
+	return err
+}
+
+/*
+	spill sorts the current run and writes it to a temporary file
+*/
+func (b *Builder) spill() (err error) {
+	f, err := ioutil.TempFile(b.tempDir, "breachindex-run-")
+	if err != nil {
+		return err
+	}
+	b.spills = append(b.spills, f)
+
+	b.sortRun()
+	w := bufio.NewWriter(f)
+	for _, hash := range b.run {
+		if _, err := w.Write(hash[:]); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	b.run = b.run[:0]
+	b.sorted = true
+	return nil
+}
+
+func (b *Builder) sortRun() {
+	if b.sorted {
+		return
+	}
+	sortHashes(b.run)
+	b.sorted = true
+}
+
+func sortHashes(hashes [][hashSize]byte) {
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+}
+
+/*
+	hashReader returns the hashes in ascending order, and false once there are none left
+*/
+type hashReader interface {
+	next() (hash [hashSize]byte, ok bool, err error)
+}
+
+/*
+	writeIndex writes the header and the unique hashes in the index file format
+*/
+func writeIndex(w io.Writer, hashes hashReader) (count int, err error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(header); err != nil {
+		return 0, err
+	}
+
+	var last [hashSize]byte
+	for {
+		hash, ok, err := hashes.next()
+		if err != nil {
+			return count, err
+		}
+		if !ok {
+			break
+		}
+		if count > 0 && hash == last {
+			continue
+		}
+		if _, err := bw.Write(hash[:]); err != nil {
+			return count, err
+		}
+		last = hash
+		count++
+	}
+	return count, bw.Flush()
+}
+
+/*
+	sliceReader reads the hashes of a sorted slice
+*/
+type sliceReader struct {
+	hashes [][hashSize]byte
+}
+
+func (s *sliceReader) next() (hash [hashSize]byte, ok bool, err error) {
+	if len(s.hashes) == 0 {
+		return hash, false, nil
+	}
+	hash, s.hashes = s.hashes[0], s.hashes[1:]
+	return hash, true, nil
+}
+
+/*
+	runReader reads the hashes of a sorted run file,
+	holding the next hash to be merged
+*/
+type runReader struct {
+	r    *bufio.Reader
+	hash [hashSize]byte
+}
+
+func (run *runReader) next() (ok bool, err error) {
+	if _, err := io.ReadFull(run.r, run.hash[:]); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+/*
+	mergeHeap merges the sorted runs, the run holding the lowest hash first
+*/
+type mergeHeap []*runReader
+
+func (m mergeHeap) Len() int            { return len(m) }
+func (m mergeHeap) Less(i, j int) bool  { return bytes.Compare(m[i].hash[:], m[j].hash[:]) < 0 }
+func (m mergeHeap) Swap(i, j int)       { m[i], m[j] = m[j], m[i] }
+func (m *mergeHeap) Push(x interface{}) { *m = append(*m, x.(*runReader)) }
+func (m *mergeHeap) Pop() interface{} {
+	old := *m
+	run := old[len(old)-1]
+	*m = old[:len(old)-1]
+	return run
+}
+
+func (m *mergeHeap) next() (hash [hashSize]byte, ok bool, err error) {
+	if m.Len() == 0 {
+		return hash, false, nil
+	}
+
+	run := (*m)[0]
+	hash = run.hash
+	more, err := run.next()
+	if err != nil {
+		return hash, false, err
+	}
+	if more {
+		heap.Fix(m, 0)
+	} else {
+		heap.Pop(m)
+	}
+	return hash, true, nil
+}
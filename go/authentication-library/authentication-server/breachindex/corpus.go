@@ -0,0 +1,104 @@
+package breachindex
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	** CorpusConfig **
+	CorpusConfig describes the format of a breach corpus
+*/
+type CorpusConfig struct {
+
+	/*
+		Plaintext corpora (i.e. a top-1M password list) have a password on each line,
+		otherwise each line is a hex encoded SHA-1 hash with an optional
+		":COUNT" suffix, as in the Have I Been Pwned downloads
+			7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577
+	*/
+	Plaintext bool
+
+	/*
+		MinCount skips hashes seen fewer than MinCount times, to reduce the
+		size of the index.  Lines without a count are always included.
+	*/
+	MinCount uint64
+}
+
+/*
+	** ReadCorpus **
+	ReadCorpus reads the SHA-1 hashes of the passwords in the corpus into memory,
+	which needs 20 bytes per hash, so is only suitable for small corpora.
+	Large corpora, such as the full Have I Been Pwned download,
+	should be streamed into a Builder with ScanCorpus.
+*/
+func ReadCorpus(r io.Reader, config CorpusConfig) (hashes [][hashSize]byte, err error) {
+	err = ScanCorpus(r, config, func(hash [hashSize]byte) error {
+		hashes = append(hashes, hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+/*
+	** ScanCorpus **
+	ScanCorpus reads the SHA-1 hashes of the passwords in the corpus,
+	calling fn with each hash in the order they appear, i.e. Builder.Add.
+	Reading stops at the first error returned by fn.
+*/
+func ScanCorpus(r io.Reader, config CorpusConfig, fn func(hash [hashSize]byte) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		if config.Plaintext {
+			if text != "" {
+				if err := fn(sha1.Sum([]byte(text))); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		hexHash, count := text, ""
+		if i := strings.IndexByte(text, ':'); i >= 0 {
+			hexHash, count = text[:i], text[i+1:]
+		}
+
+		if count != "" && config.MinCount > 0 {
+			n, err := strconv.ParseUint(count, 10, 64)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid count %q", line, count)
+			}
+			if n < config.MinCount {
+				continue
+			}
+		}
+
+		var hash [hashSize]byte
+		if len(hexHash) != 2*hashSize {
+			return fmt.Errorf("line %d: invalid SHA-1 hash %q", line, hexHash)
+		}
+		if _, err := hex.Decode(hash[:], []byte(hexHash)); err != nil {
+			return fmt.Errorf("line %d: invalid SHA-1 hash %q", line, hexHash)
+		}
+		if err := fn(hash); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -6,15 +6,19 @@ import (
 
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/markstanden/authentication/accesstoken"
+	"github.com/markstanden/authentication/breachindex"
 	"github.com/markstanden/authentication/datastores/postgres"
 	"github.com/markstanden/authentication/datastores/secretstore"
 	"github.com/markstanden/authentication/datastores/usercache"
 	"github.com/markstanden/authentication/datastores/userstore"
 	"github.com/markstanden/authentication/deployment/googlecloud"
+	"github.com/markstanden/authentication/passwordhash"
 	"github.com/markstanden/authentication/routes"
 	"github.com/markstanden/authentication/userservice"
+	"github.com/markstanden/jwt"
 )
 
 func main() {
@@ -77,6 +81,11 @@ func run(args []string, stdout io.Writer) error {
 	userDB := userstore.New(authdb)
 	// create the secretstore instance and connect it to our database
 	ss := secretstore.New(authdb, 3600)
+	// publish each new signing key for twice as long as other services cache the key set
+	ss.Lead = 2 * routes.JWKSMaxAge
+	if err := ss.Migrate(); err != nil {
+		return err
+	}
 
 	// create the userservice
 	us := userservice.NewUserService()
@@ -87,14 +96,59 @@ func run(args []string, stdout io.Writer) error {
 	/* Create a SecretStore to handle our rotating keys */
 	us.SecretDS = ss
 
-	/* create a token service to create authentication tokens for users */
-	us.AccessTS = &accesstoken.AccessToken{
+	/*
+		Hash passwords with argon2id, using the parameters from argonhasher calibrate if set,
+		with the password pepper held in the GCP secret manager,
+		so a copy of the database alone holds neither the pepper nor a way to reset it.
+		The pepper is rotated by adding a new version of the PEPPER secret,
+		and hashes are upgraded to the new version as users log in.
+	*/
+	ph := passwordhash.NewArgon()
+	if err := ph.FromEnv(os.LookupEnv); err != nil {
+		return fmt.Errorf("failed to set the password hashing parameters...\n %v", err)
+	}
+	ph.PepperDS = googlecloud.NewSecretVersions(gcloud, time.Hour)
+	if ph.PepperDS.GetKeyID(ph.PepperKeyName) == "" {
+		fmt.Fprintf(stdout, "authentication/main: %v secret unavailable, passwords cannot be hashed until it is added\n", ph.PepperKeyName)
+	}
+	us.PasswordHasher = ph
+
+	/*
+		Screen new passwords against the breached password index, if one is deployed
+	*/
+	if indexPath, ok := os.LookupEnv("BREACHED_PASSWORD_INDEX"); ok {
+		index, err := breachindex.Open(indexPath)
+		if err != nil {
+			return fmt.Errorf("failed to open the breached password index...\n %v", err)
+		}
+		defer index.Close()
+		fmt.Fprintf(stdout, "authentication/main: screening passwords against %d breached passwords\n", index.Len())
+		us.BreachedPasswords = index
+	}
+
+	/*
+		create a token service to create authentication tokens for users.
+		Tokens are signed with HS512 unless TOKEN_ALGORITHM is set to EdDSA, which lets
+		other services verify them using the public keys published at /.well-known/jwks.json.
+		Changing the algorithm rejects the tokens outstanding at the time, signing users out.
+	*/
+	algorithm := jwt.HS512
+	if algorithmENV, ok := os.LookupEnv("TOKEN_ALGORITHM"); ok {
+		algorithm = algorithmENV
+	}
+	if algorithm != jwt.HS512 && algorithm != jwt.EdDSA {
+		return fmt.Errorf("TOKEN_ALGORITHM must be %v or %v, not %q", jwt.HS512, jwt.EdDSA, algorithm)
+	}
+	at := &accesstoken.AccessToken{
 		Issuer:    "markstanden.dev",
 		Audience:  "markstanden.dev",
 		MinsValid: 60,
 		Secret:    ss,
 		StartTime: 1617020114,
+		Algorithm: algorithm,
+		Leeway:    5 * time.Second,
 	}
+	us.AccessTS = at
 
 	/* Create a handler for our routes, pass in the cache */
 	http.Handle("/", routes.Home(us.UserDS))
@@ -103,6 +157,13 @@ func run(args []string, stdout io.Writer) error {
 	http.Handle("/signin", routes.SignIn(us))
 	http.Handle("/signup", routes.SignUp(us))
 
+	/*
+		HMAC secrets are never published, so there is only a key set to serve for EdDSA
+	*/
+	if algorithm == jwt.EdDSA {
+		http.Handle("/.well-known/jwks.json", routes.JWKS(at))
+	}
+
 	/* start the server. */
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
 		return fmt.Errorf("failed to start HTTP server: /n %v", err)
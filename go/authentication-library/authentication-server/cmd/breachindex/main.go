@@ -0,0 +1,182 @@
+/*
+	breachindex builds the breached password index used to screen new passwords.
+
+	Usage:
+		breachindex build -out breached.idx [-plaintext] [-min-count n] [-tmp dir] [-run-size n] [corpus files...]
+		breachindex check -index breached.idx
+
+	build reads each corpus file (or stdin if none are given), which contain either
+	Have I Been Pwned style SHA-1 hashes ("HASH:COUNT"), or with -plaintext
+	a password on each line, and writes the index to the -out file.
+	The corpus is streamed through an external merge sort, holding -run-size hashes
+	(20 bytes each) in memory at a time, with the sorted runs written to -tmp,
+	which needs as much free space as the index.
+	check reports whether a password is within an index.  The password is read
+	from the terminal without echo, or from the first line of stdin
+	when it is not a terminal, so it never appears in the shell history or process list.
+*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/markstanden/authentication/breachindex"
+	"golang.org/x/term"
+)
+
+var errUsage = errors.New(`usage:
+	breachindex build -out breached.idx [-plaintext] [-min-count n] [-tmp dir] [-run-size n] [corpus files...]
+	breachindex check -index breached.idx`)
+
+func main() {
+	if err := run(os.Args, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	switch args[1] {
+	case "build":
+		return build(args[2:], stdin, stdout)
+	case "check":
+		return check(args[2:], stdin, stdout, stderr)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", args[1], errUsage)
+	}
+}
+
+/*
+	build reads the corpus files and writes the index
+*/
+func build(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	out := flags.String("out", "", "index file to create")
+	plaintext := flags.Bool("plaintext", false, "corpus files contain a plain text password on each line")
+	minCount := flags.Uint64("min-count", 0, "skip hashes seen fewer times than this")
+	tmp := flags.String("tmp", "", "directory for the sorted runs, defaults to the system temporary directory")
+	runSize := flags.Int("run-size", breachindex.DefaultRunSize, "number of hashes to sort in memory at a time")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errUsage
+	}
+
+	config := breachindex.CorpusConfig{Plaintext: *plaintext, MinCount: *minCount}
+
+	builder := breachindex.NewBuilder(*tmp, *runSize)
+	defer builder.Close()
+
+	readCorpus := func(name string, r io.Reader) error {
+		if err := breachindex.ScanCorpus(r, config, builder.Add); err != nil {
+			return fmt.Errorf("%v: %w", name, err)
+		}
+		return nil
+	}
+
+	if flags.NArg() == 0 {
+		if err := readCorpus("stdin", stdin); err != nil {
+			return err
+		}
+	}
+	for _, name := range flags.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		err = readCorpus(name, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	count, err := builder.Finish(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote %d breached password hashes to %v\n", count, *out)
+	return nil
+}
+
+/*
+	check reports whether the password is in the index
+*/
+func check(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	path := flags.String("index", "", "index file to search")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	index, err := breachindex.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	password, err := readPassword(stdin, stderr)
+	if err != nil {
+		return err
+	}
+
+	breached, err := index.Breached(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		fmt.Fprintln(stdout, "breached")
+	} else {
+		fmt.Fprintln(stdout, "not found")
+	}
+	return nil
+}
+
+/*
+	readPassword reads the password from the terminal without echo,
+	or from the first line of stdin if it is not a terminal.
+*/
+func readPassword(stdin io.Reader, stderr io.Writer) (password string, err error) {
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(stderr, "Password: ")
+		pw, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(stderr)
+		if err != nil {
+			return "", err
+		}
+		password = string(pw)
+	} else {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		return "", errors.New("empty password")
+	}
+	return password, nil
+}
@@ -14,12 +14,26 @@ type Secretstore struct {
 	DB postgres.DataStore
 
 	Lifespan int64
+
+	/*
+		Lead is the number of seconds before the current key expires that the next key is created.
+		GetKeyID does not return the next key until the current key expires, but GetKeyIDs does,
+		so its public key is published, and cached by other services, before anything is signed with it.
+		If no key is requested within the lead time, the next key is created, and used, when needed.
+	*/
+	Lead int64
+
+	/*
+		Random generates the new key IDs and secrets
+	*/
+	Random securerandom.Generator
 }
 
 func New(db postgres.DataStore, lifespan int64) (ss Secretstore) {
 	return Secretstore{
 		DB:       db,
 		Lifespan: lifespan,
+		Random:   securerandom.New(),
 	}
 }
 
@@ -27,32 +41,109 @@ func New(db postgres.DataStore, lifespan int64) (ss Secretstore) {
 	GetKeyID returns the latest KeyID provided there is one within the validity window.
 	Valid Key present:
 		- Returns the KeyID with the largest creation date within the validy window
+		- Creates the next key, if the current key expires within Lead seconds
 	Valid Key not found:
 		- Triggers the creation of a new key, and returns the created Key ID
 */
 func (ss Secretstore) GetKeyID(keyName string) (keyID string) {
 	now := time.Now().UTC().Unix()
 	earliestValid := now - ss.Lifespan
-	query := `SELECT keyid FROM keys WHERE keyname = $1 AND created <= $2 GROUP BY keyid HAVING MAX(created) > $3`
+
+	var created int64
+	query := `SELECT keyid, created FROM keys WHERE keyname = $1 AND created <= $2 AND created > $3 ORDER BY created DESC LIMIT 1`
 	row := ss.DB.QueryRow(query, keyName, now, earliestValid)
-	err := row.Scan(&keyID)
+	err := row.Scan(&keyID, &created)
 	switch err {
 	case sql.ErrNoRows:
-		s := authentication.Secret{
-			KeyName: keyName,
-			KeyID:   securerandom.String(16),
-			Value:   securerandom.String(128),
-			Created: now}
-		err := ss.AddSecret(s)
+		s, err := ss.NewSecret(keyName, now)
 		if err != nil {
 			return ""
 		}
+		if err := ss.AddSecret(s); err != nil {
+			return ""
+		}
 		return s.KeyID
+	case nil:
+		if ss.nextDue(created, now) {
+			ss.addNext(keyName, created+ss.Lifespan, now)
+		}
+		return keyID
 	default:
 		return keyID
 	}
 }
 
+/*
+	GetKeyIDs returns the KeyIDs of the next, current and previous keys, newest first.
+	Tokens signed with the previous key may still be valid after a rotation,
+	and the next key will be used once the current key expires,
+	so all three are published to verify tokens.
+*/
+func (ss Secretstore) GetKeyIDs(keyName string) (keyIDs []string) {
+	query := `SELECT keyid FROM keys WHERE keyname = $1 ORDER BY created DESC LIMIT 3`
+	rows, err := ss.DB.Query(query, keyName)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var keyID string
+		if err := rows.Scan(&keyID); err != nil {
+			return nil
+		}
+		keyIDs = append(keyIDs, keyID)
+	}
+	return keyIDs
+}
+
+/*
+	nextDue reports whether the current key, created at the supplied unix time,
+	expires within Lead seconds, so the next key should be created
+*/
+func (ss Secretstore) nextDue(created, now int64) bool {
+	return ss.Lead > 0 && now >= created+ss.Lifespan-ss.Lead
+}
+
+/*
+	addNext creates the next key, valid from the supplied unix time,
+	unless it has already been created by another request or instance.
+	A failure is not returned, the next key is created on a later request.
+*/
+func (ss Secretstore) addNext(keyName string, validFrom, now int64) {
+	var pending int
+	row := ss.DB.QueryRow(`SELECT COUNT(*) FROM keys WHERE keyname = $1 AND created > $2`, keyName, now)
+	if err := row.Scan(&pending); err != nil || pending > 0 {
+		return
+	}
+	s, err := ss.NewSecret(keyName, validFrom)
+	if err != nil {
+		return
+	}
+	ss.AddSecret(s)
+}
+
+/*
+	NewSecret creates a new random secret for the keyName, created at the supplied unix time.
+	An error is returned rather than a secret with an empty KeyID or Value
+	if the random generator fails.
+*/
+func (ss Secretstore) NewSecret(keyName string, created int64) (s authentication.Secret, err error) {
+	keyID, err := ss.Random.String(16)
+	if err != nil {
+		return authentication.Secret{}, fmt.Errorf("failed to create key id: %w", err)
+	}
+	value, err := ss.Random.String(128)
+	if err != nil {
+		return authentication.Secret{}, fmt.Errorf("failed to create secret: %w", err)
+	}
+	return authentication.Secret{
+		KeyName: keyName,
+		KeyID:   keyID,
+		Value:   value,
+		Created: created}, nil
+}
+
 func (ss Secretstore) AddSecret(s authentication.Secret) (err error) {
 	query := "INSERT INTO keys (keyname, keyid, value, created) VALUES ($1, $2, $3, $4)"
 	_, err = ss.DB.Exec(query, s.KeyName, s.KeyID, s.Value, s.Created)
@@ -63,23 +154,32 @@ func (ss Secretstore) AddSecret(s authentication.Secret) (err error) {
 	return nil
 }
 
+/*
+	GetSecret returns a callback to look up the value of a particular version (KeyID)
+	of the named secret.  The callback returns an empty string if the version is not found.
+*/
 func (ss Secretstore) GetSecret(keyName string) func(keyID string) (value string) {
-
-	switch keyName {
-	case "JWT":
-		return func(keyID string) (value string) {
-			row := ss.DB.QueryRow("SELECT value FROM keys WHERE keyid = $1", keyID)
-			err := row.Scan(&value)
-			switch err {
-			case sql.ErrNoRows:
-				return
-			}
-			return value
+	return func(keyID string) (value string) {
+		row := ss.DB.QueryRow("SELECT value FROM keys WHERE keyname = $1 AND keyid = $2", keyName, keyID)
+		if err := row.Scan(&value); err != nil {
+			return ""
 		}
+		return value
 	}
-	return func(keyID string) string {
-		return ""
+}
+
+/*
+	Migrate updates an existing keys table to the current structure.
+	Keys created before the table held more than one key name
+	must have a unique creation time, so the constraint is dropped,
+	allowing keys with different names to be created in the same second.
+*/
+func (ss Secretstore) Migrate() (err error) {
+	_, err = ss.DB.Exec(`ALTER TABLE IF EXISTS keys DROP CONSTRAINT IF EXISTS keys_created_key;`)
+	if err != nil {
+		return fmt.Errorf("authentication/postgres: Failed to migrate keys table:\n%v", err)
 	}
+	return nil
 }
 
 func (ss Secretstore) FullReset() (err error) {
@@ -95,7 +195,7 @@ func (ss Secretstore) FullReset() (err error) {
 	keyname varchar(64) NOT NULL,
     keyid varchar(64) UNIQUE NOT NULL,
     value varchar(255) NOT NULL,
-    created integer UNIQUE NOT NULL);`)
+    created integer NOT NULL);`)
 	if err != nil {
 		return fmt.Errorf("authentication/postgres: Failed to create keys table:\n%v", err)
 	}
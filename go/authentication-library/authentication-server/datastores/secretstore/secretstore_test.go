@@ -1,11 +1,15 @@
 package secretstore_test
 
 import (
+	"bytes"
+	"errors"
 	"testing"
+	"testing/iotest"
 
 	"github.com/markstanden/authentication"
 	"github.com/markstanden/authentication/datastores/postgres"
 	"github.com/markstanden/authentication/datastores/secretstore"
+	"github.com/markstanden/securerandom"
 )
 
 func GetTestSecretService(testDB postgres.DataStore) (testSS authentication.SecretDataStore) {
@@ -28,3 +32,26 @@ func TestSecretService(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSecret(t *testing.T) {
+	ss := secretstore.New(postgres.DataStore{}, 5)
+
+	s, err := ss.NewSecret("JWT", 1617020114)
+	if err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	if s.KeyName != "JWT" || len(s.KeyID) != 16 || len(s.Value) != 128 || s.Created != 1617020114 {
+		t.Errorf("incorrect secret created: %+v", s)
+	}
+
+	failing := []securerandom.Generator{
+		{Source: iotest.ErrReader(errors.New("no entropy"))},
+		{Source: bytes.NewReader(make([]byte, 12))}, // enough for the key id only
+	}
+	for _, g := range failing {
+		ss.Random = g
+		if s, err := ss.NewSecret("JWT", 1617020114); !errors.Is(err, securerandom.ErrEntropy) || s.KeyID != "" || s.Value != "" {
+			t.Errorf("unexpected result with a failing generator: %+v, %v", s, err)
+		}
+	}
+}
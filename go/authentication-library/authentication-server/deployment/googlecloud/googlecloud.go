@@ -5,11 +5,15 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	secretmanagerpb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
 )
 
+// accessTimeout is the longest a request to the secret manager may take
+const accessTimeout = 10 * time.Second
+
 // SecretStore is the base struct of our authentication.SecretStore interface implemetation
 // Basically a wrapper for the google cloud API
 type DeploymentService struct {
@@ -69,12 +73,28 @@ func (ds DeploymentService) GetSecret(SecretName string) func(version string) (s
 // exists. The version can be a version number as a string (e.g. "5") or an
 // alias (e.g. "latest").
 func accessSecretVersion(w io.Writer, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), accessTimeout)
+	defer cancel()
+
+	_, value, err := accessSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(w, value)
+	return nil
+}
+
+// accessSecret returns the payload for the given secret version, and the full
+// name of the version accessed, so the version number of "latest" is known.
+// The context should have a deadline, so an outage doesn't hang the caller.
+func accessSecret(ctx context.Context, name string) (version, value string, err error) {
 	// Create the client.
-	ctx := context.Background()
 	client, err := secretmanager.NewClient(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create secretmanager client: %v", err)
+		return "", "", fmt.Errorf("failed to create secretmanager client: %v", err)
 	}
+	defer client.Close()
 
 	// Build the request.
 	req := &secretmanagerpb.AccessSecretVersionRequest{
@@ -84,9 +104,8 @@ func accessSecretVersion(w io.Writer, name string) error {
 	// Call the API.
 	result, err := client.AccessSecretVersion(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to access secret version: %v", err)
+		return "", "", fmt.Errorf("failed to access secret version: %v", err)
 	}
 
-	fmt.Fprint(w, string(result.Payload.Data))
-	return nil
+	return result.Name, string(result.Payload.Data), nil
 }
@@ -0,0 +1,180 @@
+package googlecloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+)
+
+var (
+	ErrResetNotAllowed = errors.New("secrets in the GCP secret manager cannot be reset by the service")
+)
+
+/*
+	SecretVersions is an authentication.SecretDataStore backed by the GCP secret manager,
+	for secrets that must be kept away from the database, such as the password pepper.
+	Each version of a secret within the secret manager is a KeyID, so the secret is rotated
+	by adding a new version, and the older versions remain available until they are disabled.
+	Versions cannot change once created, so their values are cached,
+	and the latest version number is looked up again after Refresh.
+	Failed lookups are also cached for Refresh, so an outage of the secret manager
+	doesn't add a request to every password hash, the last known version is used instead.
+*/
+type SecretVersions struct {
+	ds DeploymentService
+
+	/*
+		Refresh is how long the latest version of a secret is cached,
+		before checking the secret manager for a new version.
+	*/
+	Refresh time.Duration
+
+	/*
+		Timeout is the longest a request to the secret manager may take
+	*/
+	Timeout time.Duration
+
+	/*
+		access returns the full name of the version accessed,
+		so the version number of "latest" is known.
+		It is a field so the tests can replace the secret manager.
+	*/
+	access func(ctx context.Context, name string) (version, value string, err error)
+
+	mu     sync.Mutex
+	values map[string]string
+	latest map[string]latestVersion
+
+	/*
+		failed holds the time of the last failed lookup of each version not in values
+	*/
+	failed map[string]time.Time
+}
+
+/*
+	latestVersion is the cached latest version of a secret
+*/
+type latestVersion struct {
+	keyID   string
+	checked time.Time
+}
+
+/*
+	NewSecretVersions returns a SecretVersions using the secret manager of the project
+*/
+func NewSecretVersions(ds *DeploymentService, refresh time.Duration) *SecretVersions {
+	return &SecretVersions{
+		ds:      *ds,
+		Refresh: refresh,
+		Timeout: accessTimeout,
+		access:  accessSecret,
+		values:  make(map[string]string),
+		latest:  make(map[string]latestVersion),
+		failed:  make(map[string]time.Time),
+	}
+}
+
+/*
+	GetKeyID returns the latest version number of the named secret.
+	If the secret manager cannot be reached, the last known version is returned,
+	or an empty string if the secret has never been found, until the next Refresh.
+	Only one caller looks up an expired version number, the others use the cached one.
+*/
+func (sv *SecretVersions) GetKeyID(keyName string) (keyID string) {
+	sv.mu.Lock()
+	cached, ok := sv.latest[keyName]
+	if ok && time.Since(cached.checked) < sv.Refresh {
+		sv.mu.Unlock()
+		return cached.keyID
+	}
+	sv.latest[keyName] = latestVersion{keyID: cached.keyID, checked: time.Now()}
+	sv.mu.Unlock()
+
+	name, value, err := sv.fetch(sv.requestString(keyName, "latest"))
+	if err != nil {
+		return cached.keyID
+	}
+	keyID = path.Base(name)
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	sv.values[sv.requestString(keyName, keyID)] = value
+	sv.latest[keyName] = latestVersion{keyID: keyID, checked: time.Now()}
+	return keyID
+}
+
+/*
+	GetKeyIDs returns the latest version number only,
+	older versions are still found by GetSecret, but are not listed.
+*/
+func (sv *SecretVersions) GetKeyIDs(keyName string) (keyIDs []string) {
+	if keyID := sv.GetKeyID(keyName); keyID != "" {
+		return []string{keyID}
+	}
+	return nil
+}
+
+/*
+	GetSecret returns a callback to look up the value of a particular version of the named secret.
+	The callback returns an empty string if the version is not found.
+*/
+func (sv *SecretVersions) GetSecret(keyName string) func(keyID string) (secret string) {
+	return func(keyID string) (secret string) {
+		if keyID == "" || keyID == "latest" {
+			return ""
+		}
+		requestString := sv.requestString(keyName, keyID)
+
+		sv.mu.Lock()
+		value, ok := sv.values[requestString]
+		failed, recent := sv.failed[requestString]
+		recent = recent && time.Since(failed) < sv.Refresh
+		sv.mu.Unlock()
+		if ok {
+			return value
+		}
+		if recent {
+			return ""
+		}
+
+		_, value, err := sv.fetch(requestString)
+
+		sv.mu.Lock()
+		defer sv.mu.Unlock()
+		if err != nil {
+			sv.failed[requestString] = time.Now()
+			return ""
+		}
+		delete(sv.failed, requestString)
+		sv.values[requestString] = value
+		return value
+	}
+}
+
+/*
+	fetch accesses the secret version, giving up after Timeout
+*/
+func (sv *SecretVersions) fetch(name string) (version, value string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sv.Timeout)
+	defer cancel()
+	return sv.access(ctx, name)
+}
+
+/*
+	FullReset refuses to reset the secrets, as deleting the password pepper
+	would leave every stored password hash unverifiable.
+	Secrets are managed within the GCP console.
+*/
+func (sv *SecretVersions) FullReset() (err error) {
+	return ErrResetNotAllowed
+}
+
+/*
+	requestString is the path to a version of a secret within the secret manager
+*/
+func (sv *SecretVersions) requestString(keyName, version string) string {
+	return fmt.Sprintf("projects/%v/secrets/%v/versions/%v", sv.ds.ProjectID, keyName, version)
+}
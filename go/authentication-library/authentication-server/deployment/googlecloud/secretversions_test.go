@@ -0,0 +1,150 @@
+package googlecloud
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+	fakeSecretManager holds the versions of the secrets, oldest first
+*/
+type fakeSecretManager struct {
+	secrets  map[string][]string
+	down     bool
+	accessed int
+}
+
+func (sm *fakeSecretManager) access(ctx context.Context, name string) (version, value string, err error) {
+	sm.accessed++
+	if _, ok := ctx.Deadline(); !ok {
+		return "", "", errors.New("no deadline")
+	}
+	if sm.down {
+		return "", "", errors.New("unavailable")
+	}
+
+	// projects/<project>/secrets/<name>/versions/<version>
+	parts := strings.Split(name, "/")
+	versions := sm.secrets[parts[3]]
+	if len(versions) == 0 {
+		return "", "", errors.New("not found")
+	}
+
+	n := len(versions)
+	if parts[5] != "latest" {
+		v, err := strconv.Atoi(parts[5])
+		if err != nil || v < 1 || v > len(versions) {
+			return "", "", errors.New("not found")
+		}
+		n = v
+	}
+	return path.Join(path.Dir(name), strconv.Itoa(n)), versions[n-1], nil
+}
+
+func TestSecretVersions(t *testing.T) {
+	sm := &fakeSecretManager{secrets: map[string][]string{"PEPPER": {"first", "second"}}}
+	sv := NewSecretVersions(&DeploymentService{ProjectID: "1"}, time.Hour)
+	sv.access = sm.access
+
+	if got := sv.GetKeyID("PEPPER"); got != "2" {
+		t.Fatalf("incorrect latest version:\nWanted: 2\nGot: %v", got)
+	}
+	if got := sv.GetKeyID("MISSING"); got != "" {
+		t.Errorf("missing secret returned version %q", got)
+	}
+
+	tests := []struct {
+		desc  string
+		keyID string
+		want  string
+	}{
+		{desc: "Latest", keyID: "2", want: "second"},
+		{desc: "Previous", keyID: "1", want: "first"},
+		{desc: "Unknown", keyID: "3", want: ""},
+		{desc: "Alias", keyID: "latest", want: ""},
+		{desc: "Empty", keyID: "", want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := sv.GetSecret("PEPPER")(test.keyID); got != test.want {
+				t.Errorf("incorrect secret:\nWanted: %q\nGot: %q", test.want, got)
+			}
+		})
+	}
+
+	/*
+		Versions are cached, so the secret manager is not needed once they have been read
+	*/
+	sm.down = true
+	sm.accessed = 0
+	if sv.GetKeyID("PEPPER") != "2" || sv.GetSecret("PEPPER")("1") != "first" || sm.accessed != 0 {
+		t.Errorf("cached versions not used, %d requests", sm.accessed)
+	}
+
+	/*
+		A new version is found after the refresh,
+		and the last known version used while the secret manager is down
+	*/
+	sv.Refresh = 0
+	if got := sv.GetKeyID("PEPPER"); got != "2" {
+		t.Errorf("last known version not used while unavailable:\nWanted: 2\nGot: %v", got)
+	}
+	sm.down = false
+	sm.secrets["PEPPER"] = append(sm.secrets["PEPPER"], "third")
+	if got := sv.GetKeyID("PEPPER"); got != "3" {
+		t.Errorf("rotated version not found:\nWanted: 3\nGot: %v", got)
+	}
+
+	if err := sv.FullReset(); !errors.Is(err, ErrResetNotAllowed) {
+		t.Errorf("secrets reset:\nWanted: %v\nGot: %v", ErrResetNotAllowed, err)
+	}
+}
+
+/*
+	While the secret manager is down, failed lookups are cached until the next refresh,
+	so each password hash doesn't wait on another request
+*/
+func TestSecretVersionsOutage(t *testing.T) {
+	sm := &fakeSecretManager{secrets: map[string][]string{"PEPPER": {"first", "second"}}}
+	sv := NewSecretVersions(&DeploymentService{ProjectID: "1"}, time.Hour)
+	sv.access = sm.access
+
+	if got := sv.GetKeyID("PEPPER"); got != "2" {
+		t.Fatalf("incorrect latest version:\nWanted: 2\nGot: %v", got)
+	}
+
+	/*
+		The refresh after the outage begins fails once, then the last known version is used
+	*/
+	sm.down = true
+	sm.accessed = 0
+	sv.latest["PEPPER"] = latestVersion{keyID: "2", checked: time.Now().Add(-2 * time.Hour)}
+	for i := 0; i < 3; i++ {
+		if got := sv.GetKeyID("PEPPER"); got != "2" {
+			t.Errorf("last known version not used while unavailable:\nWanted: 2\nGot: %v", got)
+		}
+		if got := sv.GetSecret("PEPPER")("1"); got != "" {
+			t.Errorf("uncached version found while unavailable: %q", got)
+		}
+		if got := sv.GetKeyID("MISSING"); got != "" {
+			t.Errorf("missing secret returned version %q", got)
+		}
+	}
+	if sm.accessed != 3 {
+		t.Errorf("failed lookups not cached:\nWanted: 3 requests\nGot: %v", sm.accessed)
+	}
+
+	/*
+		Once the failures are older than Refresh, the secret manager is tried again
+	*/
+	sm.down = false
+	sv.Refresh = 0
+	if got := sv.GetSecret("PEPPER")("1"); got != "first" {
+		t.Errorf("version not found after the outage:\nWanted: first\nGot: %q", got)
+	}
+}
@@ -13,7 +13,16 @@ require (
 	golang.org/x/net v0.0.0-20210415231046-e915ea6b2b7d // indirect
 	golang.org/x/oauth2 v0.0.0-20210413134643-5e61552d6c78 // indirect
 	golang.org/x/sys v0.0.0-20210419170143-37df388d1f33 // indirect
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
 	google.golang.org/api v0.44.0 // indirect
 	google.golang.org/genproto v0.0.0-20210416161957-9910b6c460de
 	google.golang.org/grpc v1.37.0 // indirect
 )
+
+// the library modules are built from their sibling directories,
+// so the Dockerfiles build from the library root
+replace github.com/markstanden/argonhasher => ../argonhasher
+
+replace github.com/markstanden/securerandom => ../securerandom-generator
+
+replace github.com/markstanden/jwt => ../jwt
@@ -0,0 +1,233 @@
+package passwordhash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/markstanden/argonhasher"
+	"github.com/markstanden/authentication"
+)
+
+var (
+	ErrPepperUnavailable = errors.New("failed to obtain current pepper version")
+)
+
+/*
+	** Argon **
+	Argon is the argon2id implementation of authentication.PasswordHash,
+	using the argonhasher package.
+	Stored hashes created with different parameters or pepper versions
+	are reported by NeedsRehash, so they can be upgraded at login.
+	Legacy hashes registered with argonhasher.Register can be compared,
+	and always need rehashing.
+*/
+type Argon struct {
+
+	/*
+		Config is the argonhasher configuration used to hash new passwords.
+	*/
+	Config argonhasher.KDFconfig
+
+	/*
+		Hasher limits the number of concurrent password hashes,
+		as each one allocates the full hashing memory.
+		If nil, hashes are not limited.
+	*/
+	Hasher *argonhasher.Hasher
+
+	/*
+		MaxWait is the longest a request waits for a free hashing slot
+		before giving up with authentication.ErrServiceBusy
+	*/
+	MaxWait time.Duration
+
+	/*
+		Pepper storage, the server side secret mixed into every password hash.
+		It is kept away from the user datastore so leaked user records
+		alone cannot be used to brute force the passwords.
+		If nil, passwords are hashed without a pepper.
+	*/
+	PepperDS authentication.SecretDataStore
+
+	/*
+		PepperKeyName is the name of the pepper secret within the PepperDS
+	*/
+	PepperKeyName string
+}
+
+/*
+	** NewArgon **
+	NewArgon returns an Argon password hasher with system defaults
+*/
+func NewArgon() *Argon {
+	return &Argon{
+		Config:        argonhasher.RecommendedConfig(),
+		Hasher:        argonhasher.NewHasher(runtime.NumCPU(), 4*runtime.NumCPU()),
+		MaxWait:       2 * time.Second,
+		PepperKeyName: "PEPPER",
+	}
+}
+
+/*
+	** FromEnv **
+	FromEnv sets the hashing parameters from the ARGON2_TIME, ARGON2_MEMORY, ARGON2_THREADS,
+	ARGON2_SALT_LENGTH and ARGON2_KEY_LENGTH variables printed by the argonhasher calibrate command,
+	so the parameters calibrated for the host can be set in the deployment config.
+	lookup is called for each variable, i.e. os.LookupEnv, and unset variables are left unchanged.
+	An error is returned, and the config left unchanged, if a variable is not a number
+	or the parameters are out of bounds.
+*/
+func (a *Argon) FromEnv(lookup func(key string) (value string, ok bool)) (err error) {
+	config := a.Config
+
+	params := []struct {
+		name string
+		bits int
+		set  func(n uint64)
+	}{
+		{name: "ARGON2_TIME", bits: 32, set: func(n uint64) { config.Time = uint32(n) }},
+		{name: "ARGON2_MEMORY", bits: 32, set: func(n uint64) { config.Memory = uint32(n) }},
+		{name: "ARGON2_THREADS", bits: 8, set: func(n uint64) { config.Threads = uint8(n) }},
+		{name: "ARGON2_SALT_LENGTH", bits: 32, set: func(n uint64) { config.SaltLength = uint(n) }},
+		{name: "ARGON2_KEY_LENGTH", bits: 32, set: func(n uint64) { config.KeyLen = uint32(n) }},
+	}
+	for _, param := range params {
+		value, ok := lookup(param.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, param.bits)
+		if err != nil {
+			return fmt.Errorf("invalid %v %q: %w", param.name, value, err)
+		}
+		param.set(n)
+	}
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	a.Config = config
+	return nil
+}
+
+/*
+	** Encode **
+	Encode hashes the password using the current config and pepper version,
+	as EncodeContext without a request context.
+*/
+func (a *Argon) Encode(plainTextPassword string) (hashedPassword string, err error) {
+	return a.EncodeContext(context.Background(), plainTextPassword)
+}
+
+/*
+	** EncodeContext **
+	EncodeContext hashes the password using the current config and pepper version,
+	giving up waiting for a hashing slot once the context is done.
+	An error is returned if the current pepper version is unavailable,
+	rather than silently creating hashes without a pepper.
+*/
+func (a *Argon) EncodeContext(ctx context.Context, plainTextPassword string) (hashedPassword string, err error) {
+	config, err := a.config()
+	if err != nil {
+		return "", err
+	}
+
+	if a.Hasher == nil {
+		return config.Encode(plainTextPassword)
+	}
+
+	ctx, cancel := a.context(ctx)
+	defer cancel()
+	hashedPassword, err = a.Hasher.Encode(ctx, config, plainTextPassword)
+	return hashedPassword, busy(err)
+}
+
+/*
+	** Compare **
+	Compare checks the password against the hashed password,
+	as CompareContext without a request context.
+*/
+func (a *Argon) Compare(plainTextPassword, hashedPassword string) (match bool, err error) {
+	return a.CompareContext(context.Background(), plainTextPassword, hashedPassword)
+}
+
+/*
+	** CompareContext **
+	CompareContext checks the password against the hashed password,
+	giving up waiting for a hashing slot once the context is done.
+	The config can still confirm hashes made with older peppers
+	if the current pepper version is unavailable, so the error is ignored.
+*/
+func (a *Argon) CompareContext(ctx context.Context, plainTextPassword, hashedPassword string) (match bool, err error) {
+	config, _ := a.config()
+
+	if a.Hasher == nil {
+		return config.Confirm(plainTextPassword, hashedPassword), nil
+	}
+
+	ctx, cancel := a.context(ctx)
+	defer cancel()
+	match, err = a.Hasher.Confirm(ctx, config, plainTextPassword, hashedPassword)
+	return match, busy(err)
+}
+
+/*
+	** NeedsRehash **
+	NeedsRehash returns true if the hashed password was not created using
+	the current config and pepper version.
+	Hashes can't be upgraded without the current pepper, so if it is unavailable
+	NeedsRehash returns false and leaves the hash to be upgraded later.
+*/
+func (a *Argon) NeedsRehash(hashedPassword string) bool {
+	config, err := a.config()
+	if err != nil {
+		return false
+	}
+	return argonhasher.NeedsRehash(hashedPassword, config.Policy())
+}
+
+/*
+	** config **
+	config returns the configuration used to hash passwords,
+	with the pepper lookup and current pepper version from the PepperDS.
+*/
+func (a *Argon) config() (config argonhasher.KDFconfig, err error) {
+	config = a.Config
+	if a.PepperDS == nil {
+		return config, nil
+	}
+
+	config.Pepper = a.PepperDS.GetSecret(a.PepperKeyName)
+	config.PepperID = a.PepperDS.GetKeyID(a.PepperKeyName)
+	if config.PepperID == "" {
+		return config, ErrPepperUnavailable
+	}
+	return config, nil
+}
+
+/*
+	** context **
+	context returns the request's context, limited to MaxWait for a hashing slot
+*/
+func (a *Argon) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.MaxWait <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, a.MaxWait)
+}
+
+/*
+	** busy **
+	busy converts the Hasher's load shedding errors to authentication.ErrServiceBusy,
+	so the routes can ask the client to retry later.
+*/
+func busy(err error) error {
+	if errors.Is(err, argonhasher.ErrBusy) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", authentication.ErrServiceBusy, err)
+	}
+	return err
+}
@@ -0,0 +1,322 @@
+package passwordhash_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/markstanden/argonhasher"
+	"github.com/markstanden/authentication"
+	"github.com/markstanden/authentication/passwordhash"
+)
+
+/*
+	memSecrets is an in-memory authentication.SecretDataStore,
+	where the current KeyID can be changed to simulate key rotation.
+*/
+type memSecrets struct {
+	current string
+	secrets map[string]string
+}
+
+func (ms *memSecrets) FullReset() error {
+	ms.secrets = make(map[string]string)
+	return nil
+}
+
+func (ms *memSecrets) GetSecret(keyName string) func(keyID string) string {
+	return func(keyID string) string {
+		return ms.secrets[keyID]
+	}
+}
+
+func (ms *memSecrets) GetKeyID(keyName string) string {
+	return ms.current
+}
+
+func (ms *memSecrets) GetKeyIDs(keyName string) []string {
+	return []string{ms.current}
+}
+
+/*
+	testArgon returns an Argon hasher with low cost parameters, to keep the tests fast
+*/
+func testArgon() *passwordhash.Argon {
+	a := passwordhash.NewArgon()
+	a.Config = argonhasher.KDFconfig{SaltLength: 16, Time: 1, Memory: 1024, Threads: 1, KeyLen: 16}
+	return a
+}
+
+const password = "livetotest"
+
+/*
+	*** TestArgon ***
+	TestArgon checks that hashes created by the adapter can be compared,
+	and that changing the parameters causes them to be rehashed.
+*/
+func TestArgon(t *testing.T) {
+
+	a := testArgon()
+
+	hash, err := a.Encode(password)
+	if err != nil {
+		t.Fatalf("failed to encode password:\n%v", err)
+	}
+
+	if match, err := a.Compare(password, hash); !match || err != nil {
+		t.Errorf("failed to compare password:\n%v", err)
+	}
+	if match, err := a.Compare("incorrect", hash); match || err != nil {
+		t.Errorf("incorrect password matched:\n%v", err)
+	}
+	if a.NeedsRehash(hash) {
+		t.Error("hash with current parameters needs rehash")
+	}
+
+	a.Config.Time = 2
+	if !a.NeedsRehash(hash) {
+		t.Error("hash with outdated parameters does not need rehash")
+	}
+	if match, err := a.Compare(password, hash); !match || err != nil {
+		t.Errorf("failed to compare password with outdated hash:\n%v", err)
+	}
+
+	if _, err := a.Encode(""); !errors.Is(err, argonhasher.ErrEmptyPassword) {
+		t.Errorf("unexpected error for an empty password:\n%v", err)
+	}
+}
+
+/*
+	*** TestArgonFromEnv ***
+	TestArgonFromEnv checks the parameters printed by argonhasher calibrate are used,
+	and that invalid parameters leave the config unchanged.
+*/
+func TestArgonFromEnv(t *testing.T) {
+	calibrated := map[string]string{
+		"ARGON2_TIME":        "3",
+		"ARGON2_MEMORY":      "65536",
+		"ARGON2_THREADS":     "2",
+		"ARGON2_SALT_LENGTH": "16",
+		"ARGON2_KEY_LENGTH":  "32",
+	}
+
+	tests := []struct {
+		desc string
+		env  map[string]string
+		want argonhasher.KDFconfig
+		err  bool
+	}{
+		{desc: "Unset", env: nil, want: argonhasher.RecommendedConfig()},
+		{desc: "Calibrated", env: calibrated, want: argonhasher.KDFconfig{SaltLength: 16, Time: 3, Memory: 65536, Threads: 2, KeyLen: 32}},
+		{desc: "Time only", env: map[string]string{"ARGON2_TIME": "4"}, want: argonhasher.KDFconfig{SaltLength: 16, Time: 4, Memory: 19 * 1024, Threads: 1, KeyLen: 32}},
+		{desc: "Not a number", env: map[string]string{"ARGON2_MEMORY": "64MiB"}, err: true},
+		{desc: "Threads overflow", env: map[string]string{"ARGON2_THREADS": "256"}, err: true},
+		{desc: "Out of bounds", env: map[string]string{"ARGON2_TIME": "0"}, err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			a := passwordhash.NewArgon()
+			err := a.FromEnv(func(key string) (string, bool) {
+				value, ok := test.env[key]
+				return value, ok
+			})
+			if (err != nil) != test.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err {
+				test.want = argonhasher.RecommendedConfig()
+			}
+			if a.Config.Policy() != test.want.Policy() {
+				t.Errorf("incorrect config:\nWanted: %+v\nGot: %+v", test.want.Policy(), a.Config.Policy())
+			}
+		})
+	}
+}
+
+/*
+	*** TestArgonLegacyHash ***
+	TestArgonLegacyHash checks that a password migrated from a legacy system
+	with a Django PBKDF2 hash can be compared, and needs rehashing.
+*/
+func TestArgonLegacyHash(t *testing.T) {
+
+	a := testArgon()
+	const legacy = "pbkdf2_sha256$1000$seasalt123$KuEnssc6S4MzVSS8Tu48m1RDSrTAn7j3CfgvvjkvfWA="
+
+	if match, err := a.Compare("correct horse", legacy); !match || err != nil {
+		t.Errorf("failed to compare password with legacy hash:\n%v", err)
+	}
+	if match, _ := a.Compare("incorrect", legacy); match {
+		t.Error("incorrect password matched legacy hash")
+	}
+	if !a.NeedsRehash(legacy) {
+		t.Error("legacy hash does not need rehash")
+	}
+}
+
+/*
+	*** TestArgonPepperRotation ***
+	TestArgonPepperRotation creates a peppered hash,
+	rotates the pepper, and checks that the password can still be compared
+	and the hash needs upgrading to the new pepper version.
+*/
+func TestArgonPepperRotation(t *testing.T) {
+
+	peppers := &memSecrets{
+		current: "v1",
+		secrets: map[string]string{"v1": "first pepper", "v2": "second pepper"},
+	}
+	a := testArgon()
+	a.PepperDS = peppers
+
+	hash, err := a.Encode(password)
+	if err != nil {
+		t.Fatalf("failed to encode password:\n%v", err)
+	}
+	if parsed, _, _ := argonhasher.ParseHash(hash); parsed.PepperID != "v1" {
+		t.Fatalf("hash not created with current pepper version: %v", hash)
+	}
+	if argonhasher.Confirm(password, hash) {
+		t.Fatal("peppered hash confirmed without the pepper")
+	}
+
+	t.Run("Pepper Unavailable", func(t *testing.T) {
+		peppers.current = ""
+		defer func() { peppers.current = "v1" }()
+
+		if _, err := a.Encode(password); !errors.Is(err, passwordhash.ErrPepperUnavailable) {
+			t.Errorf("unexpected error encoding without a pepper:\n%v", err)
+		}
+		if match, err := a.Compare(password, hash); !match || err != nil {
+			t.Errorf("failed to compare without current pepper version:\n%v", err)
+		}
+		if a.NeedsRehash(hash) {
+			t.Error("hash needs rehash without a pepper to upgrade to")
+		}
+	})
+
+	t.Run("Rotated Pepper", func(t *testing.T) {
+		peppers.current = "v2"
+		if match, err := a.Compare(password, hash); !match || err != nil {
+			t.Fatalf("failed to compare after pepper rotation:\n%v", err)
+		}
+		if !a.NeedsRehash(hash) {
+			t.Error("hash with old pepper version does not need rehash")
+		}
+	})
+
+	t.Run("Retired Pepper", func(t *testing.T) {
+		delete(peppers.secrets, "v1")
+		if match, _ := a.Compare(password, hash); match {
+			t.Error("compared password with a retired pepper")
+		}
+	})
+}
+
+/*
+	*** TestArgonBusy ***
+	TestArgonBusy fills the adapter's hashing slots,
+	and checks that further hashes are shed with ErrServiceBusy.
+*/
+func TestArgonBusy(t *testing.T) {
+
+	a := testArgon()
+	a.Hasher = argonhasher.NewHasher(1, 0)
+
+	hash, err := a.Encode(password)
+	if err != nil {
+		t.Fatalf("failed to encode password:\n%v", err)
+	}
+
+	/*
+		Hold the only hashing slot with a pepper lookup that blocks
+	*/
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	blocking := a.Config
+	blocking.PepperID = "v1"
+	blocking.Pepper = func(pepperID string) string {
+		close(started)
+		<-release
+		return "pepper"
+	}
+	go func() {
+		a.Hasher.Encode(context.Background(), blocking, password)
+		close(done)
+	}()
+	<-started
+
+	if _, err := a.Compare(password, hash); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("unexpected error from Compare when busy:\nWanted: %v\nGot: %v", authentication.ErrServiceBusy, err)
+	}
+	if _, err := a.Encode(password); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("unexpected error from Encode when busy:\nWanted: %v\nGot: %v", authentication.ErrServiceBusy, err)
+	}
+
+	close(release)
+	<-done
+
+	if match, err := a.Compare(password, hash); !match || err != nil {
+		t.Errorf("failed to compare once hashing slot freed:\n%v", err)
+	}
+}
+
+/*
+	*** TestArgonCancelled ***
+	TestArgonCancelled checks that a request whose client has gone gives up waiting
+	for a hashing slot, rather than being hashed once a slot is free.
+*/
+func TestArgonCancelled(t *testing.T) {
+
+	a := testArgon()
+	a.Hasher = argonhasher.NewHasher(1, 1)
+
+	hash, err := a.Encode(password)
+	if err != nil {
+		t.Fatalf("failed to encode password:\n%v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.EncodeContext(cancelled, password); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error from EncodeContext when cancelled:\nWanted: %v\nGot: %v", context.Canceled, err)
+	}
+
+	/*
+		Hold the only hashing slot with a pepper lookup that blocks
+	*/
+	started, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	blocking := a.Config
+	blocking.PepperID = "v1"
+	blocking.Pepper = func(pepperID string) string {
+		close(started)
+		<-release
+		return "pepper"
+	}
+	go func() {
+		a.Hasher.Encode(context.Background(), blocking, password)
+		close(done)
+	}()
+	<-started
+
+	/*
+		Cancel the request once it is queued
+	*/
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for a.Hasher.Stats().Queued == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+	if _, err := a.CompareContext(ctx, password, hash); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error from CompareContext when cancelled:\nWanted: %v\nGot: %v", context.Canceled, err)
+	}
+
+	close(release)
+	<-done
+
+	if admitted := a.Hasher.Stats().Admitted; admitted != 2 {
+		t.Errorf("cancelled requests admitted:\nWanted: 2\nGot: %v", admitted)
+	}
+}
@@ -0,0 +1,93 @@
+package passwordhash
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrEmptyPassword = errors.New("empty password")
+)
+
+/*
+	** Fake **
+	Fake is a fast, deterministic authentication.PasswordHash for tests,
+	where real argon2 hashing would make the tests slow.
+	It must never be used to store real passwords.
+	Hashes have the format $fake$VERSION$SHA256HEX, and NeedsRehash reports
+	hashes created with a different Version, so rehashing can be tested
+	by changing the Version.
+	If Err is set, it is returned by Encode and Compare, to simulate a failing hasher.
+*/
+type Fake struct {
+	Version string
+	Err     error
+}
+
+/*
+	** Encode **
+	Encode returns the fake hash of the password
+*/
+func (f Fake) Encode(plainTextPassword string) (hashedPassword string, err error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if plainTextPassword == "" {
+		return "", ErrEmptyPassword
+	}
+	return "$fake$" + f.Version + "$" + fakeDigest(plainTextPassword), nil
+}
+
+/*
+	** Compare **
+	Compare checks the password against a fake hash of any version
+*/
+func (f Fake) Compare(plainTextPassword, hashedPassword string) (match bool, err error) {
+	if f.Err != nil {
+		return false, f.Err
+	}
+	sections := strings.Split(hashedPassword, "$")
+	if len(sections) != 4 || sections[1] != "fake" || plainTextPassword == "" {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(sections[3]), []byte(fakeDigest(plainTextPassword))) == 1, nil
+}
+
+/*
+	** EncodeContext **
+	EncodeContext is Encode, returning the context's error if it is done
+*/
+func (f Fake) EncodeContext(ctx context.Context, plainTextPassword string) (hashedPassword string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return f.Encode(plainTextPassword)
+}
+
+/*
+	** CompareContext **
+	CompareContext is Compare, returning the context's error if it is done
+*/
+func (f Fake) CompareContext(ctx context.Context, plainTextPassword, hashedPassword string) (match bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return f.Compare(plainTextPassword, hashedPassword)
+}
+
+/*
+	** NeedsRehash **
+	NeedsRehash returns true unless the hash is a fake hash of the current Version
+*/
+func (f Fake) NeedsRehash(hashedPassword string) bool {
+	return !strings.HasPrefix(hashedPassword, "$fake$"+f.Version+"$")
+}
+
+func fakeDigest(plainTextPassword string) string {
+	digest := sha256.Sum256([]byte(plainTextPassword))
+	return hex.EncodeToString(digest[:])
+}
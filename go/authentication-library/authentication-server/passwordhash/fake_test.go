@@ -0,0 +1,50 @@
+package passwordhash_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/markstanden/authentication"
+	"github.com/markstanden/authentication/passwordhash"
+)
+
+func TestFake(t *testing.T) {
+
+	var _ authentication.PasswordHash = passwordhash.Fake{}
+	var _ authentication.PasswordHash = passwordhash.NewArgon()
+
+	f := passwordhash.Fake{Version: "v1"}
+
+	hash, err := f.Encode(password)
+	if err != nil {
+		t.Fatalf("failed to encode password:\n%v", err)
+	}
+	if again, _ := f.Encode(password); again != hash {
+		t.Errorf("fake hash is not deterministic:\n%v\n%v", hash, again)
+	}
+	if match, _ := f.Compare(password, hash); !match {
+		t.Error("failed to compare password")
+	}
+	if match, _ := f.Compare("incorrect", hash); match {
+		t.Error("incorrect password matched")
+	}
+	if f.NeedsRehash(hash) {
+		t.Error("hash with current version needs rehash")
+	}
+
+	f.Version = "v2"
+	if !f.NeedsRehash(hash) {
+		t.Error("hash with old version does not need rehash")
+	}
+	if match, _ := f.Compare(password, hash); !match {
+		t.Error("failed to compare password with old version")
+	}
+
+	f.Err = authentication.ErrServiceBusy
+	if _, err := f.Encode(password); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("Err not returned from Encode: %v", err)
+	}
+	if _, err := f.Compare(password, hash); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("Err not returned from Compare: %v", err)
+	}
+}
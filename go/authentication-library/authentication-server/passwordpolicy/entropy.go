@@ -0,0 +1,513 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+/*
+	The patterns recognised by Estimate, in the order of the guidance offered for them.
+	A password segment that doesn't match a pattern is guessed character by character, by brute force.
+*/
+const (
+	PatternUserInput  = "user_input"
+	PatternDictionary = "dictionary"
+	PatternRepeat     = "repeat"
+	PatternSequence   = "sequence"
+	PatternKeyboard   = "keyboard"
+	PatternYear       = "year"
+	PatternBruteForce = "bruteforce"
+)
+
+/*
+	The ranked wordlists, most common first, so the line number is the word's rank.
+	common.txt is a short list of recently common passwords, and the others
+	are zxcvbn's frequency lists, copied from the Go port github.com/ccojocar/zxcvbn-go,
+	which took them from https://github.com/dropbox/zxcvbn (see wordlists/LICENSE):
+	common passwords, English words from TV and film scripts, and US census names.
+*/
+var (
+	//go:embed wordlists/common.txt
+	commonPasswords string
+	//go:embed wordlists/passwords.txt
+	passwords string
+	//go:embed wordlists/english.txt
+	englishWords string
+	//go:embed wordlists/female_names.txt
+	femaleNames string
+	//go:embed wordlists/male_names.txt
+	maleNames string
+	//go:embed wordlists/surnames.txt
+	surnames string
+)
+
+/*
+	dictionary maps each word to its best rank within the wordlists.
+	Each list is ranked separately, as zxcvbn does, so a common name
+	is as guessable as a common password.
+*/
+var dictionary = mergeDictionaries(commonPasswords, passwords, englishWords, femaleNames, maleNames, surnames)
+
+/*
+	maxWordLength is the length in runes of the longest word in the dictionary,
+	so longer segments of the password need not be looked up.
+*/
+var maxWordLength = longestWord(dictionary)
+
+/*
+	keyboardRows are the rows of a UK/US qwerty keyboard,
+	runs of adjacent keys along a row being easy to type, and to guess.
+*/
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]",
+	"asdfghjkl;'#",
+	"zxcvbnm,./",
+}
+
+/*
+	leetSubstitutions maps the common l33t character substitutions to the letter they replace.
+	'1' is commonly used for both i and l, so both are tried.
+*/
+var leetSubstitutions = []map[rune]rune{
+	{'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't'},
+	{'4': 'a', '@': 'a', '3': 'e', '1': 'l', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't'},
+}
+
+/*
+	** Match **
+	Match is a segment of the password recognised as a guessable pattern,
+	and the entropy (in bits) of guessing that segment.
+*/
+type Match struct {
+	Pattern string
+	Token   string
+	Entropy float64
+
+	/*
+		start and end are the rune indexes of the segment, password[start:end]
+	*/
+	start, end int
+}
+
+/*
+	** Estimation **
+	Estimation is the result of estimating the entropy of a password
+*/
+type Estimation struct {
+
+	/*
+		Entropy is the estimated number of bits needed to guess the password,
+		assuming the attacker knows the patterns people use.
+	*/
+	Entropy float64
+
+	/*
+		Matches is the most guessable sequence of patterns making up the password
+	*/
+	Matches []Match
+}
+
+/*
+	** Estimate **
+	Estimate estimates the entropy of a password in the manner of zxcvbn.
+	Rather than counting character classes, it finds the recognisable patterns
+	within the password (common passwords, repeats, sequences, keyboard runs and years),
+	and the userInputs (such as the user's name and email address), and returns the
+	entropy of the most guessable way of putting those patterns together.
+*/
+func Estimate(password string, userInputs ...string) Estimation {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Estimation{}
+	}
+
+	/*
+		Group the candidate matches by the index they end at
+	*/
+	ending := make([][]Match, len(runes)+1)
+	for _, m := range findMatches(runes, userDictionary(userInputs)) {
+		ending[m.end] = append(ending[m.end], m)
+	}
+
+	/*
+		minimum[i] is the lowest entropy of the first i runes,
+		either brute forcing rune i-1 or completing a match ending at i.
+	*/
+	bruteForce := math.Log2(float64(cardinality(runes)))
+	minimum := make([]float64, len(runes)+1)
+	choice := make([]Match, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		minimum[i] = minimum[i-1] + bruteForce
+		choice[i] = Match{Pattern: PatternBruteForce, Token: string(runes[i-1]), Entropy: bruteForce, start: i - 1, end: i}
+		for _, m := range ending[i] {
+			if minimum[m.start]+m.Entropy < minimum[i] {
+				minimum[i] = minimum[m.start] + m.Entropy
+				choice[i] = m
+			}
+		}
+	}
+
+	/*
+		Walk back through the choices, joining neighbouring brute force runes
+	*/
+	var matches []Match
+	for i := len(runes); i > 0; i = choice[i].start {
+		m := choice[i]
+		if n := len(matches); n > 0 && m.Pattern == PatternBruteForce && matches[n-1].Pattern == PatternBruteForce {
+			matches[n-1].Token = m.Token + matches[n-1].Token
+			matches[n-1].Entropy += m.Entropy
+			matches[n-1].start = m.start
+			continue
+		}
+		matches = append(matches, m)
+	}
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+
+	return Estimation{Entropy: minimum[len(runes)], Matches: matches}
+}
+
+/*
+	findMatches returns every recognisable pattern within the password
+*/
+func findMatches(runes []rune, userDict map[string]int) (matches []Match) {
+	matches = append(matches, dictionaryMatches(runes, userDict, longestWord(userDict), PatternUserInput)...)
+	matches = append(matches, dictionaryMatches(runes, dictionary, maxWordLength, PatternDictionary)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, keyboardMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+/*
+	dictionaryMatches finds the words of the dictionary within the password,
+	ignoring case, and including reversed and l33t spellings of the words.
+	The entropy is that of guessing the word's rank, plus the extra bits for
+	its capitalisation and spelling.
+	Segments longer than the longest word in the dictionary are not looked up.
+*/
+func dictionaryMatches(runes []rune, dict map[string]int, longest int, pattern string) (matches []Match) {
+	lower := []rune(strings.ToLower(string(runes)))
+	for i := range lower {
+		for j := i + 3; j <= len(lower) && j-i <= longest; j++ {
+			word := string(lower[i:j])
+			extra := uppercaseEntropy(runes[i:j])
+
+			if rank, ok := dict[word]; ok {
+				matches = append(matches, Match{Pattern: pattern, Token: string(runes[i:j]), Entropy: math.Log2(float64(rank)) + extra, start: i, end: j})
+			}
+			if rank, ok := dict[reverse(word)]; ok {
+				matches = append(matches, Match{Pattern: pattern, Token: string(runes[i:j]), Entropy: math.Log2(float64(rank)) + extra + 1, start: i, end: j})
+			}
+			for _, subs := range leetSubstitutions {
+				unleet, substituted := substitute(lower[i:j], subs)
+				if substituted == 0 {
+					continue
+				}
+				if rank, ok := dict[unleet]; ok {
+					matches = append(matches, Match{Pattern: pattern, Token: string(runes[i:j]), Entropy: math.Log2(float64(rank)) + extra + float64(substituted), start: i, end: j})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+/*
+	repeatMatches finds repeated characters (aaa) and repeated blocks (abcabc).
+	The entropy is that of guessing the repeated block, plus the number of repeats.
+	Only the shortest repeating block starting at each index is matched,
+	which keeps long, repetitive passwords quick to estimate.
+*/
+func repeatMatches(runes []rune) (matches []Match) {
+	blockEntropy := make(map[string]float64)
+	for i := range runes {
+		for size := 1; i+2*size <= len(runes); size++ {
+			block := string(runes[i : i+size])
+			count := 1
+			for i+(count+1)*size <= len(runes) && string(runes[i+count*size:i+(count+1)*size]) == block {
+				count++
+			}
+			if count < 2 || (size == 1 && count < 3) {
+				continue
+			}
+			if _, ok := blockEntropy[block]; !ok {
+				blockEntropy[block] = Estimate(block).Entropy
+			}
+			end := i + count*size
+			entropy := blockEntropy[block] + math.Log2(float64(count))
+			matches = append(matches, Match{Pattern: PatternRepeat, Token: string(runes[i:end]), Entropy: entropy, start: i, end: end})
+			break
+		}
+	}
+	return matches
+}
+
+/*
+	sequenceMatches finds runs of at least three letters or digits that
+	step up or down by one, such as abc, 6543 or XYZ.
+	Starting a sequence at the beginning or end of the alphabet is even more
+	likely, so those starts have a lower entropy.
+*/
+func sequenceMatches(runes []rune) (matches []Match) {
+	for i := 0; i < len(runes)-2; {
+		delta := runes[i+1] - runes[i]
+		if (delta != 1 && delta != -1) || !sameClass(runes[i], runes[i+1]) {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+		if j-i+1 < 3 {
+			i = j
+			continue
+		}
+
+		var entropy float64
+		switch first := runes[i]; {
+		case strings.ContainsRune("aAzZ019", first):
+			entropy = 2
+		case unicode.IsDigit(first):
+			entropy = math.Log2(10)
+		default:
+			entropy = math.Log2(26) + 1
+		}
+		entropy += math.Log2(float64(j - i + 1))
+		if delta < 0 {
+			entropy++
+		}
+		matches = append(matches, Match{Pattern: PatternSequence, Token: string(runes[i : j+1]), Entropy: entropy, start: i, end: j + 1})
+		i = j + 1
+	}
+	return matches
+}
+
+/*
+	keyboardMatches finds runs of at least three adjacent keys along
+	a keyboard row, in either direction, such as qwerty or lkjh.
+*/
+func keyboardMatches(runes []rune) (matches []Match) {
+	lower := []rune(strings.ToLower(string(runes)))
+	for i := 0; i < len(lower)-2; i++ {
+		for _, row := range keyboardRows {
+			for _, step := range []int{1, -1} {
+				j := i
+				for j+1 < len(lower) && adjacent(row, lower[j], lower[j+1], step) {
+					j++
+				}
+				if j-i+1 < 3 {
+					continue
+				}
+				entropy := math.Log2(float64(len(strings.Join(keyboardRows, "")))) + math.Log2(float64(j-i+1)) + uppercaseEntropy(runes[i:j+1])
+				if step < 0 {
+					entropy++
+				}
+				matches = append(matches, Match{Pattern: PatternKeyboard, Token: string(runes[i : j+1]), Entropy: entropy, start: i, end: j + 1})
+			}
+		}
+	}
+	return matches
+}
+
+/*
+	yearMatches finds recent years, which are often birth years or anniversaries
+*/
+func yearMatches(runes []rune) (matches []Match) {
+	const first, last = 1900, 2049
+	for i := 0; i+4 <= len(runes); i++ {
+		year := 0
+		for _, r := range runes[i : i+4] {
+			if r < '0' || r > '9' {
+				year = -1
+				break
+			}
+			year = year*10 + int(r-'0')
+		}
+		if year >= first && year <= last {
+			matches = append(matches, Match{Pattern: PatternYear, Token: string(runes[i : i+4]), Entropy: math.Log2(last - first + 1), start: i, end: i + 4})
+		}
+	}
+	return matches
+}
+
+/*
+	userDictionary ranks the words of the user's inputs,
+	such as the full name and email, and their parts.
+*/
+func userDictionary(userInputs []string) map[string]int {
+	var words []string
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		words = append(words, input)
+		words = append(words, contextParts(input)...)
+	}
+	return rankedDictionary(words)
+}
+
+/*
+	rankedDictionary maps each word to its position in the list, starting at 1
+*/
+func rankedDictionary(words []string) map[string]int {
+	dict := make(map[string]int, len(words))
+	for _, word := range words {
+		if _, ok := dict[word]; !ok && word != "" {
+			dict[word] = len(dict) + 1
+		}
+	}
+	return dict
+}
+
+/*
+	mergeDictionaries ranks the words of each newline separated list,
+	and keeps the best rank of words appearing in more than one list
+*/
+func mergeDictionaries(lists ...string) map[string]int {
+	merged := make(map[string]int)
+	for _, list := range lists {
+		for word, rank := range rankedDictionary(strings.Fields(list)) {
+			if best, ok := merged[word]; !ok || rank < best {
+				merged[word] = rank
+			}
+		}
+	}
+	return merged
+}
+
+/*
+	longestWord returns the length in runes of the longest word in the dictionary
+*/
+func longestWord(dict map[string]int) (longest int) {
+	for word := range dict {
+		if n := len([]rune(word)); n > longest {
+			longest = n
+		}
+	}
+	return longest
+}
+
+/*
+	cardinality returns the number of possible characters
+	for each character of a brute force guess of the password
+*/
+func cardinality(runes []rune) (size int) {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r < unicode.MaxASCII:
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+	return size
+}
+
+/*
+	uppercaseEntropy returns the extra bits needed to guess
+	the capitalisation of a word.  All lower, all upper and capitalised
+	words are common, so cost at most a bit.
+*/
+func uppercaseEntropy(runes []rune) float64 {
+	var upper, lower int
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+		if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0, upper == 1 && unicode.IsUpper(runes[0]):
+		return 1
+	}
+
+	/*
+		The number of ways of capitalising at most the smaller of the upper and lower count
+	*/
+	var variations float64
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return math.Log2(variations)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+/*
+	substitute replaces the l33t substitutions within the word,
+	returning the number of substitutions made
+*/
+func substitute(word []rune, subs map[rune]rune) (unleet string, substituted int) {
+	replaced := make([]rune, len(word))
+	for i, r := range word {
+		if letter, ok := subs[r]; ok {
+			r = letter
+			substituted++
+		}
+		replaced[i] = r
+	}
+	return string(replaced), substituted
+}
+
+func reverse(word string) string {
+	runes := []rune(word)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+/*
+	sameClass returns true if both runes are digits, lowercase or uppercase letters
+*/
+func sameClass(a, b rune) bool {
+	switch {
+	case a >= '0' && a <= '9':
+		return b >= '0' && b <= '9'
+	case a >= 'a' && a <= 'z':
+		return b >= 'a' && b <= 'z'
+	case a >= 'A' && a <= 'Z':
+		return b >= 'A' && b <= 'Z'
+	}
+	return false
+}
+
+/*
+	adjacent returns true if b is the key after a along the row, in the direction of step
+*/
+func adjacent(row string, a, b rune, step int) bool {
+	i := strings.IndexRune(row, a)
+	j := strings.IndexRune(row, b)
+	return i >= 0 && j >= 0 && j-i == step
+}
@@ -0,0 +1,202 @@
+/*
+	passwordpolicy checks new passwords against a NIST 800-63B style policy.
+	Rather than composition rules (a digit, a symbol...), which push people towards
+	predictable passwords like Password1!, it sets a minimum length,
+	rejects passwords that contain the user's own details, and rejects passwords
+	that are easily guessed using the patterns people commonly use.
+*/
+package passwordpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrPolicyViolation = errors.New("password does not meet the password policy")
+)
+
+/*
+	Feedback codes, identifying the rule a password failed
+*/
+const (
+	CodeTooShort         = "too_short"
+	CodeTooLong          = "too_long"
+	CodeContainsUserInfo = "contains_user_info"
+	CodeTooGuessable     = "too_guessable"
+)
+
+/*
+	minContextLength is the shortest part of a user's details checked for
+	within a password, so initials don't reject most passwords
+*/
+const minContextLength = 3
+
+/*
+	** Feedback **
+	Feedback is a message explaining why a password was rejected,
+	or how to choose a better one, suitable for showing to the user.
+*/
+type Feedback struct {
+	Code    string
+	Message string
+}
+
+/*
+	** Violation **
+	Violation is the error returned when a password fails the policy,
+	holding the feedback for each failed rule.
+	errors.Is(err, ErrPolicyViolation) is true for a Violation.
+*/
+type Violation struct {
+	Feedback []Feedback
+
+	/*
+		Entropy is the estimated entropy of the rejected password, in bits
+	*/
+	Entropy float64
+}
+
+func (v *Violation) Error() string {
+	messages := make([]string, len(v.Feedback))
+	for i, f := range v.Feedback {
+		messages[i] = f.Message
+	}
+	return fmt.Sprintf("%v: %v", ErrPolicyViolation, strings.Join(messages, " "))
+}
+
+func (v *Violation) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+/*
+	** Policy **
+	Policy is the set of rules a new password must meet
+*/
+type Policy struct {
+
+	/*
+		MinLength and MaxLength are measured in characters (unicode code points) rather than bytes.
+		NIST recommends at least 8 characters, and allowing at least 64.
+	*/
+	MinLength int
+	MaxLength int
+
+	/*
+		MinEntropy is the lowest estimated entropy, in bits, of an acceptable password.
+		See Estimate.
+	*/
+	MinEntropy float64
+}
+
+/*
+	** New **
+	New returns a Policy with system defaults
+*/
+func New() Policy {
+	return Policy{
+		MinLength:  8,
+		MaxLength:  255,
+		MinEntropy: 30,
+	}
+}
+
+/*
+	** Check **
+	Check returns a *Violation if the password does not meet the policy, and nil if it does.
+	userInputs are the user's own details, such as their name and email address,
+	which must not be used within the password.
+*/
+func (p Policy) Check(password string, userInputs ...string) error {
+	var feedback []Feedback
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		feedback = append(feedback, Feedback{Code: CodeTooShort, Message: fmt.Sprintf("Use at least %d characters.", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		feedback = append(feedback, Feedback{Code: CodeTooLong, Message: fmt.Sprintf("Use at most %d characters.", p.MaxLength)})
+	}
+
+	if containsUserInfo(password, userInputs) {
+		feedback = append(feedback, Feedback{Code: CodeContainsUserInfo, Message: "Avoid using your name or email address in your password."})
+	}
+
+	estimate := Estimate(password, userInputs...)
+	if estimate.Entropy < p.MinEntropy {
+		feedback = append(feedback, guessableFeedback(estimate)...)
+	}
+
+	if len(feedback) == 0 {
+		return nil
+	}
+	return &Violation{Feedback: feedback, Entropy: estimate.Entropy}
+}
+
+/*
+	containsUserInfo returns true if the password contains
+	any part of the user's details
+*/
+func containsUserInfo(password string, userInputs []string) bool {
+	password = strings.ToLower(password)
+	for _, input := range userInputs {
+		for _, part := range contextParts(strings.ToLower(input)) {
+			if strings.Contains(password, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+	contextParts splits a user's detail into the words worth checking for.
+	For an email address the top level domain is dropped, as ".com" is not personal.
+		"testy@mctestface.com" returns [ "testy", "mctestface" ]
+*/
+func contextParts(input string) (parts []string) {
+	if at := strings.LastIndex(input, "@"); at >= 0 {
+		if dot := strings.LastIndex(input, "."); dot > at {
+			input = input[:dot]
+		}
+	}
+
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= minContextLength {
+			parts = append(parts, word)
+		}
+	}
+	return parts
+}
+
+/*
+	guessableFeedback explains the patterns that make the password easy to guess,
+	most guessable first, followed by a suggestion for a better password.
+*/
+func guessableFeedback(estimate Estimation) (feedback []Feedback) {
+	messages := map[string]string{
+		PatternDictionary: "Common passwords, words and names are easy to guess.",
+		PatternRepeat:     "Repeats like \"aaa\" or \"abcabc\" are easy to guess.",
+		PatternSequence:   "Sequences like \"abc\" or \"6543\" are easy to guess.",
+		PatternKeyboard:   "Straight rows of keys like \"qwerty\" are easy to guess.",
+		PatternYear:       "Years, especially recent ones, are easy to guess.",
+	}
+
+	seen := make(map[string]bool)
+	for _, pattern := range []string{PatternDictionary, PatternRepeat, PatternSequence, PatternKeyboard, PatternYear} {
+		for _, m := range estimate.Matches {
+			if m.Pattern == pattern && !seen[pattern] {
+				seen[pattern] = true
+				feedback = append(feedback, Feedback{Code: CodeTooGuessable, Message: messages[pattern]})
+			}
+		}
+	}
+
+	return append(feedback, Feedback{Code: CodeTooGuessable, Message: "Add another word or two. Uncommon words are better."})
+}
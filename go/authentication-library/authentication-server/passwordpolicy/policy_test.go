@@ -0,0 +1,121 @@
+package passwordpolicy_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/authentication/passwordpolicy"
+)
+
+func TestCheck(t *testing.T) {
+
+	name, email := "Testy McTestface", "testy@mctestface.com"
+
+	tests := []struct {
+		desc     string
+		password string
+		codes    []string
+	}{
+		{desc: "Long uncommon password", password: "correct horse battery staple"},
+		{desc: "Unicode password", password: "ünïcödé pässwörd nötëd"},
+		{desc: "Containing the top level domain", password: "comfortable otter lemon"},
+		{desc: "Too short", password: "Zq8!x", codes: []string{passwordpolicy.CodeTooShort}},
+		{desc: "Too long", password: strings.Repeat("horse battery ", 20), codes: []string{passwordpolicy.CodeTooLong}},
+		{desc: "Common password", password: "password", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Common password with substitutions", password: "P@ssw0rd1", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Reversed common password", password: "drowssap123", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Name with suffix", password: "Charlotte1!", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Name with digits", password: "Jasmine123", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Surname with year", password: "Wilson2019!", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Word with digit", password: "Buttercup1", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Word with digits", password: "midnight77", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Capitalised word with digits", password: "Pineapple12", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Sequence", password: "abcdefgh12345", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Keyboard row", password: "qwertasdfgzxcvb", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Repeats", password: "aaaaaaaaaaaa", codes: []string{passwordpolicy.CodeTooGuessable}},
+		{desc: "Contains name", password: "mctestface2021", codes: []string{passwordpolicy.CodeContainsUserInfo, passwordpolicy.CodeTooGuessable}},
+		{desc: "Contains email", password: "Testy is a strange choice", codes: []string{passwordpolicy.CodeContainsUserInfo}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := passwordpolicy.New().Check(test.password, name, email)
+			if len(test.codes) == 0 {
+				if err != nil {
+					t.Fatalf("valid password rejected: %v", err)
+				}
+				return
+			}
+
+			var violation *passwordpolicy.Violation
+			if !errors.As(err, &violation) || !errors.Is(err, passwordpolicy.ErrPolicyViolation) {
+				t.Fatalf("invalid password accepted, or incorrect error: %v", err)
+			}
+			for _, code := range test.codes {
+				if !hasCode(violation.Feedback, code) {
+					t.Errorf("missing feedback %q: %+v", code, violation.Feedback)
+				}
+			}
+			for _, f := range violation.Feedback {
+				if f.Message == "" {
+					t.Errorf("empty feedback message for %q", f.Code)
+				}
+			}
+		})
+	}
+}
+
+func hasCode(feedback []passwordpolicy.Feedback, code string) bool {
+	for _, f := range feedback {
+		if f.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEstimate(t *testing.T) {
+
+	tests := []struct {
+		desc     string
+		password string
+		pattern  string
+		max      float64
+	}{
+		{desc: "Common password", password: "password", pattern: passwordpolicy.PatternDictionary, max: 2},
+		{desc: "Capitalised common password", password: "Dragon", pattern: passwordpolicy.PatternDictionary, max: 6},
+		{desc: "English word", password: "pineapple", pattern: passwordpolicy.PatternDictionary, max: 14},
+		{desc: "Female name", password: "charlotte", pattern: passwordpolicy.PatternDictionary, max: 8},
+		{desc: "Male name", password: "Harold", pattern: passwordpolicy.PatternDictionary, max: 9},
+		{desc: "Surname", password: "wilson", pattern: passwordpolicy.PatternDictionary, max: 5},
+		{desc: "l33t word", password: "bu77ercup", pattern: passwordpolicy.PatternDictionary, max: 17},
+		{desc: "User input", password: "mctestface", pattern: passwordpolicy.PatternUserInput, max: 3},
+		{desc: "Sequence", password: "abcdefg", pattern: passwordpolicy.PatternSequence, max: 6},
+		{desc: "Descending digits", password: "98765", pattern: passwordpolicy.PatternSequence, max: 6},
+		{desc: "Keyboard row", password: "lkjhgf", pattern: passwordpolicy.PatternKeyboard, max: 10},
+		{desc: "Repeated block", password: "xyzxyzxyz", pattern: passwordpolicy.PatternRepeat, max: 10},
+		{desc: "Year", password: "1987", pattern: passwordpolicy.PatternYear, max: 8},
+		{desc: "Random characters", password: "kT9#qZ", pattern: passwordpolicy.PatternBruteForce, max: 40},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			e := passwordpolicy.Estimate(test.password, "Testy McTestface")
+			if e.Entropy > test.max {
+				t.Errorf("entropy too high:\nWanted: <= %.1f\nGot: %.1f", test.max, e.Entropy)
+			}
+			if len(e.Matches) != 1 || e.Matches[0].Pattern != test.pattern || e.Matches[0].Token != test.password {
+				t.Errorf("incorrect match:\nWanted: %v %q\nGot: %+v", test.pattern, test.password, e.Matches)
+			}
+		})
+	}
+
+	if e := passwordpolicy.Estimate(""); e.Entropy != 0 || len(e.Matches) != 0 {
+		t.Errorf("empty password has entropy: %+v", e)
+	}
+
+	if weak, strong := passwordpolicy.Estimate("monkey123"), passwordpolicy.Estimate("mqnxey1z3"); weak.Entropy >= strong.Entropy {
+		t.Errorf("patterned password estimated stronger than random: %.1f >= %.1f", weak.Entropy, strong.Entropy)
+	}
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/markstanden/authentication"
+)
+
+/*
+	JWKSMaxAge is the time, in seconds, other services may cache our public keys.
+	The next signing key must be published for longer than this before it is used,
+	so the secret store's Lead must be longer, or services may reject new tokens
+	until their cached key set expires.
+*/
+const JWKSMaxAge = 300
+
+// JWKS publishes the public keys used to verify access tokens, at /.well-known/jwks.json
+func JWKS(ks authentication.KeySetService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		keySet, err := ks.JWKS()
+		if err != nil {
+			log.Println("authentication/routes: Failed to create JWKS:\n\t", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(JWKSMaxAge))
+		if err := json.NewEncoder(w).Encode(keySet); err != nil {
+			log.Println("authentication/routes: Failed to write JWKS:\n\t", err)
+		}
+	})
+}
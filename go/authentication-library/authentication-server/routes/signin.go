@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -26,9 +27,15 @@ func SignIn(us authentication.UserService) http.Handler {
 				return
 			}
 
-			u, err := us.Login(r.PostForm.Get("name"), r.PostForm.Get("password"))
+			u, err := us.LoginContext(r.Context(), r.PostForm.Get("name"), r.PostForm.Get("password"))
+			if errors.Is(err, authentication.ErrServiceBusy) {
+				serviceBusy(w)
+				fmt.Fprintln(w, getHTML("Too many sign in attempts - Please try again shortly", ""))
+				return
+			}
 			if err != nil {
 				fmt.Fprintln(w, getHTML("Invalid Username/Password - Have another go...", ""))
+				return
 			}
 
 			fmt.Fprintf(w, `
@@ -54,3 +61,18 @@ func getHTML(title, email string) (html string) {
 			<input value="Submit Info" type="submit" />
 		</form>`, title, email)
 }
+
+/*
+	** serviceBusy **
+	serviceBusy responds with a 503, asking the client to retry
+	after retryAfter seconds, when the server is too busy to hash passwords.
+*/
+func serviceBusy(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfter)
+	w.WriteHeader(http.StatusServiceUnavailable)
+}
+
+/*
+	retryAfter is the Retry-After header value, in seconds, sent with a 503
+*/
+const retryAfter = "1"
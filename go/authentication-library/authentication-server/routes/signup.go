@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
+	"strings"
 
 	"github.com/markstanden/authentication"
+	"github.com/markstanden/authentication/passwordpolicy"
 )
 
 /*
@@ -43,7 +47,31 @@ func SignUp(us authentication.UserService) http.Handler {
 			/*
 				Create the user and add to the datastore
 			*/
-			u, err := us.NewUser(r.PostForm.Get("name"), r.PostForm.Get("email"), r.PostForm.Get("password"))
+			u, err := us.NewUserContext(r.Context(), r.PostForm.Get("name"), r.PostForm.Get("email"), r.PostForm.Get("password"))
+
+			/*
+				Show the user why their password was rejected,
+				and how to choose a better one
+			*/
+			var violation *passwordpolicy.Violation
+			if errors.As(err, &violation) {
+				messages := make([]string, len(violation.Feedback))
+				for i, f := range violation.Feedback {
+					messages[i] = f.Message
+				}
+				fmt.Fprintln(w, getHTMLFORM("Please choose a stronger password", messages...))
+				return
+			}
+			if errors.Is(err, authentication.ErrBreachedPassword) {
+				fmt.Fprintln(w, getHTMLFORM("Please choose a different password",
+					"This password has appeared in a data breach, so is unsafe to use on any site."))
+				return
+			}
+			if errors.Is(err, authentication.ErrServiceBusy) {
+				serviceBusy(w)
+				fmt.Fprintln(w, getHTMLFORM("Too many sign up requests, please try again shortly"))
+				return
+			}
 			if err != nil {
 				fmt.Fprintln(w, getHTMLFORM("Failed to create user, please try again"+err.Error()))
 				return
@@ -71,8 +99,14 @@ func SignUp(us authentication.UserService) http.Handler {
 	Internal, temporary function to create and display the user input form
 	The subtitle parameter is being used as a status display line, so the form
 	can be reissued in event of an error in invalid input.
+	The optional passwordFeedback messages are listed next to the password input.
 */
-func getHTMLFORM(subtitle string) (html string) {
+func getHTMLFORM(subtitle string, passwordFeedback ...string) (html string) {
+	var feedback strings.Builder
+	for _, message := range passwordFeedback {
+		fmt.Fprintf(&feedback, "<li>%v</li>", template.HTMLEscapeString(message))
+	}
+
 	html = fmt.Sprintf(`
 		<div style="padding-left:10rem">
 			<h1> Sign Up </h1>
@@ -83,12 +117,13 @@ func getHTMLFORM(subtitle string) (html string) {
 				<label for="email">Email:</label>
 				<input id="email" name="email" type="email" maxlength="255"/><br>
 				<label for="password">Password</label>
-				<input id="password" name="password" type="password" maxlength="255"/><br>
+				<input id="password" name="password" type="password" maxlength="255"/>
+				<ul class="password-feedback">%v</ul>
 				<label for="confirmpassword">Confirm Password</label>
 				<input id="confirmpassword" name="confirmpassword" type="password" /><br>
 				<input value="Submit Info" type="submit" />
 			</form>
 		</div>
-	`, subtitle)
+	`, subtitle, feedback.String())
 	return
 }
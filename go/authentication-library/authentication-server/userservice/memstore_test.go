@@ -0,0 +1,70 @@
+package userservice_test
+
+import (
+	"errors"
+
+	"github.com/markstanden/authentication"
+)
+
+/*
+	memStore is an in-memory authentication.UserDataStore,
+	allowing the userservice logic to be tested without a database.
+*/
+type memStore struct {
+	users   map[int]authentication.User
+	nextID  int
+	updates int
+}
+
+func newMemStore() *memStore {
+	return &memStore{users: make(map[int]authentication.User)}
+}
+
+func (ms *memStore) FullReset() error {
+	ms.users = make(map[int]authentication.User)
+	return nil
+}
+
+func (ms *memStore) Add(u *authentication.User) error {
+	if _, err := ms.Find("email", u.Email); err == nil {
+		return errors.New("email address already in use")
+	}
+	ms.nextID++
+	u.UniqueID = ms.nextID
+	ms.users[u.UniqueID] = *u
+	return nil
+}
+
+func (ms *memStore) Find(key, value string) (*authentication.User, error) {
+	for _, u := range ms.users {
+		if (key == "email" && u.Email == value) || (key == "tokenuserid" && u.TokenUserID == value) {
+			found := u
+			return &found, nil
+		}
+	}
+	return nil, authentication.ErrUserNotFound
+}
+
+func (ms *memStore) Update(u *authentication.User, updated authentication.User) error {
+	if _, ok := ms.users[updated.UniqueID]; !ok {
+		return authentication.ErrUserNotFound
+	}
+	ms.updates++
+	ms.users[updated.UniqueID] = updated
+	return nil
+}
+
+func (ms *memStore) UpdateRefreshToken(u *authentication.User, newRefreshToken string) error {
+	stored, ok := ms.users[u.UniqueID]
+	if !ok {
+		return authentication.ErrUserNotFound
+	}
+	stored.CurrentRefreshToken = newRefreshToken
+	ms.users[u.UniqueID] = stored
+	return nil
+}
+
+func (ms *memStore) Delete(u *authentication.User) error {
+	delete(ms.users, u.UniqueID)
+	return nil
+}
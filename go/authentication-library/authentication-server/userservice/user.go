@@ -1,13 +1,15 @@
 package userservice
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
 
-	"github.com/markstanden/argonhasher"
 	"github.com/markstanden/authentication"
+	"github.com/markstanden/authentication/passwordhash"
+	"github.com/markstanden/authentication/passwordpolicy"
 	"github.com/markstanden/securerandom"
 )
 
@@ -31,6 +33,17 @@ type UserService struct {
 	*/
 	PasswordHasher authentication.PasswordHash
 
+	/*
+		Breached password screening for new passwords.
+		If nil, new passwords are not screened.
+	*/
+	BreachedPasswords authentication.BreachedPasswords
+
+	/*
+		Random generates the user's token ID and refresh tokens
+	*/
+	Random securerandom.Generator
+
 	/*
 		Session management
 	*/
@@ -46,8 +59,13 @@ type UserService struct {
 type USConfig struct {
 	MinInputLength   int
 	MaxInputLength   int
-	TokenIDSize      uint
 	RefreshTokenSize uint
+
+	/*
+		PasswordPolicy is checked by NewUser and UpdatePassword,
+		existing passwords are not checked at login.
+	*/
+	PasswordPolicy passwordpolicy.Policy
 }
 
 /*
@@ -59,13 +77,17 @@ func NewUserService() (us UserService) {
 	//us.SecretDS
 	//us.AccessTS
 	//us.RefreshTS
-	//us.PasswordHasher = argonhasher
 
 	us.Config.MinInputLength = 3
 	us.Config.MaxInputLength = 255
 
 	us.Config.RefreshTokenSize = uint(100)
-	us.Config.TokenIDSize = uint(100)
+
+	us.Config.PasswordPolicy = passwordpolicy.New()
+
+	us.Random = securerandom.New()
+
+	us.PasswordHasher = passwordhash.NewArgon()
 	return us
 }
 
@@ -76,6 +98,15 @@ func NewUserService() (us UserService) {
 	and returns the new user if created ok
 */
 func (us UserService) NewUser(name, email, password string) (u *authentication.User, err error) {
+	return us.NewUserContext(context.Background(), name, email, password)
+}
+
+/*
+	** NewUserContext **
+	NewUserContext is NewUser, giving up waiting to hash the password
+	once the request's context is done.
+*/
+func (us UserService) NewUserContext(ctx context.Context, name, email, password string) (u *authentication.User, err error) {
 
 	if emptyString(name) ||
 		tooLong(name, us.Config.MaxInputLength) ||
@@ -88,18 +119,42 @@ func (us UserService) NewUser(name, email, password string) (u *authentication.U
 		return nil, ErrInvalidInput
 	}
 
-	// hash the password, default complexity
-	passwordHash := argonhasher.Encode(password, 0)
-	if passwordHash == "" {
-		return nil, fmt.Errorf("SERVER ERROR - FAILED TO CREATE HASH")
+	/*
+		Returns a *passwordpolicy.Violation with feedback for the user
+	*/
+	if err := us.Config.PasswordPolicy.Check(password, name, email); err != nil {
+		return nil, err
+	}
+	if err := us.checkBreached(password); err != nil {
+		return nil, err
+	}
+
+	// hash the password, using the configured complexity
+	passwordHash, err := us.PasswordHasher.EncodeContext(ctx, password)
+	if err != nil {
+		return nil, fmt.Errorf("SERVER ERROR - FAILED TO CREATE HASH: %w", err)
+	}
+
+	/*
+		The token user ID is a random UUID, compact in the JWT, and unlike a sequence,
+		not enumerable.  It is public within every token's subject, so unlike
+		a time ordered UUIDv7 it must not reveal when the account was created.
+	*/
+	tokenUserID, err := us.Random.UUIDv4()
+	if err != nil {
+		return nil, fmt.Errorf("SERVER ERROR - FAILED TO CREATE TOKEN USER ID: %w", err)
+	}
+	refreshToken, err := us.NewRefreshToken()
+	if err != nil {
+		return nil, err
 	}
 
 	u = new(authentication.User)
 	u.Name = name
 	u.Email = email
 	u.HashedPassword = passwordHash
-	u.TokenUserID = securerandom.String(us.Config.TokenIDSize)
-	u.CurrentRefreshToken = us.NewRefreshToken()
+	u.TokenUserID = tokenUserID.String()
+	u.CurrentRefreshToken = refreshToken
 
 	if err = us.UserDS.Add(u); err != nil {
 
@@ -113,17 +168,24 @@ func (us UserService) NewUser(name, email, password string) (u *authentication.U
 	** NewRefreshToken **
 	NewRefreshToken generates a new refresh token and returns the result as a string
 */
-func (us UserService) NewRefreshToken() (refreshToken string) {
-	refreshToken = securerandom.String(us.Config.RefreshTokenSize)
-	return refreshToken
+func (us UserService) NewRefreshToken() (refreshToken string, err error) {
+	refreshToken, err = us.Random.String(us.Config.RefreshTokenSize)
+	if err != nil {
+		return "", fmt.Errorf("SERVER ERROR - FAILED TO CREATE REFRESH TOKEN: %w", err)
+	}
+	return refreshToken, nil
 }
 
 /*
 	** UpdateRefreshToken **
 	Helper function that gets a new refresh token, and updates the local and stored version.
 */
-func (us UserService) UpdateRefreshToken(user *authentication.User) {
-	us.UpdateUser(user, UpdateRefreshToken(us.NewRefreshToken()))
+func (us UserService) UpdateRefreshToken(user *authentication.User) error {
+	refreshToken, err := us.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	return us.UpdateUser(user, UpdateRefreshToken(refreshToken))
 }
 
 /*
@@ -181,6 +243,15 @@ func (us UserService) AuthenticateAccess(jwt string) (user *authentication.User,
 	if the password validation fails a nil pointer and an error returned.
 */
 func (us UserService) Login(email, password string) (user *authentication.User, err error) {
+	return us.LoginContext(context.Background(), email, password)
+}
+
+/*
+	** LoginContext **
+	LoginContext is Login, giving up waiting to check the password
+	once the request's context is done.
+*/
+func (us UserService) LoginContext(ctx context.Context, email, password string) (user *authentication.User, err error) {
 
 	/*
 		check inputs to potentially save unnecessary hashing or DB lookups
@@ -206,15 +277,68 @@ func (us UserService) Login(email, password string) (user *authentication.User,
 		return nil, err
 	}
 
-	/*
-		need to use an interface here
-	*/
-	valid := argonhasher.Confirm(password, user.HashedPassword)
+	valid, err := us.PasswordHasher.CompareContext(ctx, password, user.HashedPassword)
+	if err != nil {
+		return nil, err
+	}
 	if !valid {
 		return nil, authentication.ErrIncorrectPassword
-	} else {
-		return user, nil
 	}
+
+	/*
+		The password is correct, so this is our chance to upgrade
+		a hash created using outdated parameters or pepper.
+	*/
+	if us.PasswordHasher.NeedsRehash(user.HashedPassword) {
+		us.rehashPassword(ctx, user, password)
+	}
+
+	return user, nil
+}
+
+/*
+	** rehashPassword **
+	rehashPassword re-encodes the confirmed plaintext password using the current
+	hashing parameters, and persists the new hash to the user datastore.
+	Failing to upgrade the hash should not fail the login, so errors are logged,
+	and the existing hash left in place to be upgraded at the next login.
+*/
+func (us UserService) rehashPassword(ctx context.Context, user *authentication.User, password string) {
+	newHashedPW, err := us.PasswordHasher.EncodeContext(ctx, password)
+	if err != nil {
+		log.Printf("userservice/Login: failed to rehash password:\n%v", err)
+		return
+	}
+
+	updatedUser := *user
+	updatedUser.HashedPassword = newHashedPW
+	if err := us.UserDS.Update(user, updatedUser); err != nil {
+		log.Printf("userservice/Login: failed to store rehashed password:\n%v", err)
+		return
+	}
+	user.HashedPassword = newHashedPW
+}
+
+/*
+	** checkBreached **
+	checkBreached returns authentication.ErrBreachedPassword if the password
+	has appeared in a data breach.
+	If the screening fails the password is rejected, rather than risk
+	accepting a breached password.
+*/
+func (us UserService) checkBreached(password string) error {
+	if us.BreachedPasswords == nil {
+		return nil
+	}
+
+	breached, err := us.BreachedPasswords.Breached(password)
+	if err != nil {
+		return fmt.Errorf("SERVER ERROR - FAILED TO SCREEN PASSWORD: %w", err)
+	}
+	if breached {
+		return authentication.ErrBreachedPassword
+	}
+	return nil
 }
 
 /*
@@ -256,10 +380,19 @@ func UpdateEmail(email string) UpdateFunc {
 */
 func UpdatePassword(plaintext string) UpdateFunc {
 	return UpdateFunc(func(us UserService, updates *authentication.User) error {
-		newHashedPW := argonhasher.Encode(plaintext, 0)
-		if newHashedPW == "" {
+		if emptyString(plaintext) {
 			return ErrInvalidInput
 		}
+		if err := us.Config.PasswordPolicy.Check(plaintext, updates.Name, updates.Email); err != nil {
+			return err
+		}
+		if err := us.checkBreached(plaintext); err != nil {
+			return err
+		}
+		newHashedPW, err := us.PasswordHasher.Encode(plaintext)
+		if err != nil {
+			return err
+		}
 		updates.HashedPassword = newHashedPW
 		return nil
 	})
@@ -1,14 +1,21 @@
 package userservice_test
 
 import (
+	"context"
+	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/markstanden/authentication"
 	"github.com/markstanden/authentication/datastores/postgres"
 	"github.com/markstanden/authentication/datastores/userstore"
+	"github.com/markstanden/authentication/passwordhash"
+	"github.com/markstanden/authentication/passwordpolicy"
 	"github.com/markstanden/authentication/userservice"
+	"github.com/markstanden/securerandom"
 )
 
 /*
@@ -47,7 +54,7 @@ var tests = []test{
 		user: newUser{
 			name:     "Testy 'first' McTestface",
 			email:    "userservice@mctestface.com",
-			password: "livetotest",
+			password: "livetotestdaily",
 		},
 		isValid: true,
 	},
@@ -56,7 +63,7 @@ var tests = []test{
 		user: newUser{
 			name:     "",
 			email:    "emptyname@mctestface.com",
-			password: "livetotest",
+			password: "livetotestdaily",
 		},
 		isValid: false,
 	},
@@ -66,7 +73,7 @@ var tests = []test{
 		user: newUser{
 			name:     "Testy McTestface",
 			email:    "",
-			password: "livetotest",
+			password: "livetotestdaily",
 		},
 		isValid: false,
 	},
@@ -96,7 +103,7 @@ var tests = []test{
 		user: newUser{
 			name:     "Testy McTestface",
 			email:    "testymctestface.com",
-			password: "livetotest",
+			password: "livetotestdaily",
 		},
 		isValid: false,
 	},
@@ -106,7 +113,7 @@ var tests = []test{
 		user: newUser{
 			name:     "Testy McTestface",
 			email:    "testy@mctestfacecom",
-			password: "livetotest",
+			password: "livetotestdaily",
 		},
 		isValid: false,
 	},
@@ -143,6 +150,7 @@ func TestNewUserAndLogin(t *testing.T) {
 	*/
 	us := userservice.NewUserService()
 	us.UserDS = userStore
+	us.PasswordHasher = passwordhash.Fake{}
 
 	var usersCreated []authentication.User
 
@@ -222,8 +230,8 @@ func TestNewUserAndLogin(t *testing.T) {
 						t.Error("password is stored in plain text!")
 					}
 
-					if len(newUser.TokenUserID) != int(us.Config.TokenIDSize) {
-						t.Error("TokenUserID is incorrect size")
+					if !securerandom.ValidUUID(newUser.TokenUserID) {
+						t.Error("TokenUserID is not a UUID")
 					}
 				}
 			})
@@ -243,6 +251,241 @@ func TestNewUserAndLogin(t *testing.T) {
 	}
 }
 
+/*
+	*** TestLoginRehash ***
+	TestLoginRehash creates a user, changes the password hasher's
+	version, and checks that the stored hash is upgraded when
+	the user next logs in.
+*/
+func TestLoginRehash(t *testing.T) {
+
+	store := newMemStore()
+	hasher := &passwordhash.Fake{Version: "v1"}
+	us := userservice.NewUserService()
+	us.UserDS = store
+	us.PasswordHasher = hasher
+
+	const email, password = "rehash@mctestface.com", "livetotestdaily"
+
+	created, err := us.NewUser("Testy McTestface", email, password)
+	if err != nil {
+		t.Fatalf("failed to create user:\n%v", err)
+	}
+
+	t.Run("Current Version", func(t *testing.T) {
+		if _, err := us.Login(email, password); err != nil {
+			t.Fatalf("failed to login:\n%v", err)
+		}
+		if store.updates != 0 {
+			t.Fatal("hash with current version was rehashed")
+		}
+	})
+
+	t.Run("New Version", func(t *testing.T) {
+		hasher.Version = "v2"
+		user, err := us.Login(email, password)
+		if err != nil {
+			t.Fatalf("failed to login:\n%v", err)
+		}
+		if store.updates != 1 {
+			t.Fatal("outdated hash was not rehashed")
+		}
+		stored, _ := store.Find("email", email)
+		if stored.HashedPassword == created.HashedPassword || stored.HashedPassword != user.HashedPassword {
+			t.Error("rehashed password not stored")
+		}
+	})
+
+	t.Run("Login With Upgraded Hash", func(t *testing.T) {
+		if _, err := us.Login(email, password); err != nil {
+			t.Fatalf("failed to login with upgraded hash:\n%v", err)
+		}
+		if store.updates != 1 {
+			t.Fatal("upgraded hash was rehashed again")
+		}
+	})
+
+	t.Run("Incorrect Password", func(t *testing.T) {
+		hasher.Version = "v3"
+		if _, err := us.Login(email, "incorrect"); err != authentication.ErrIncorrectPassword {
+			t.Fatalf("unexpected error for an incorrect password:\n%v", err)
+		}
+		if store.updates != 1 {
+			t.Fatal("hash upgraded without a correct password")
+		}
+	})
+}
+
+/*
+	*** TestPasswordHasherErrors ***
+	TestPasswordHasherErrors checks that errors from the password hasher,
+	such as the hasher being too busy, are returned to the caller.
+*/
+func TestPasswordHasherErrors(t *testing.T) {
+
+	store := newMemStore()
+	hasher := &passwordhash.Fake{}
+	us := userservice.NewUserService()
+	us.UserDS = store
+	us.PasswordHasher = hasher
+
+	const email, password = "busy@mctestface.com", "livetotestdaily"
+
+	user, err := us.NewUser("Testy McTestface", email, password)
+	if err != nil {
+		t.Fatalf("failed to create user:\n%v", err)
+	}
+
+	/*
+		A request whose client has gone is not hashed
+	*/
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := us.LoginContext(cancelled, email, password); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error from LoginContext:\nWanted: %v\nGot: %v", context.Canceled, err)
+	}
+	if _, err := us.NewUserContext(cancelled, "Testy McTestface", "gone@mctestface.com", password); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error from NewUserContext:\nWanted: %v\nGot: %v", context.Canceled, err)
+	}
+
+	hasher.Err = authentication.ErrServiceBusy
+
+	if _, err := us.Login(email, password); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("unexpected error from Login:\nWanted: %v\nGot: %v", authentication.ErrServiceBusy, err)
+	}
+	if _, err := us.NewUser("Testy McTestface", "busy2@mctestface.com", password); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("unexpected error from NewUser:\nWanted: %v\nGot: %v", authentication.ErrServiceBusy, err)
+	}
+	if err := us.UpdateUser(user, userservice.UpdatePassword("livetotesttwice")); !errors.Is(err, authentication.ErrServiceBusy) {
+		t.Errorf("unexpected error from UpdatePassword:\nWanted: %v\nGot: %v", authentication.ErrServiceBusy, err)
+	}
+	if store.updates != 0 {
+		t.Error("user updated after hashing failed")
+	}
+}
+
+/*
+	*** TestPasswordPolicy ***
+	TestPasswordPolicy checks that new and updated passwords
+	are rejected with feedback if they fail the password policy.
+*/
+func TestPasswordPolicy(t *testing.T) {
+
+	store := newMemStore()
+	us := userservice.NewUserService()
+	us.UserDS = store
+	us.PasswordHasher = passwordhash.Fake{}
+
+	const name, email = "Testy McTestface", "policy@mctestface.com"
+
+	for _, password := range []string{"abc", "password1", "mctestface99"} {
+		_, err := us.NewUser(name, email, password)
+		var violation *passwordpolicy.Violation
+		if !errors.As(err, &violation) || len(violation.Feedback) == 0 {
+			t.Errorf("password %q accepted, or returned without feedback: %v", password, err)
+		}
+	}
+
+	user, err := us.NewUser(name, email, "livetotestdaily")
+	if err != nil {
+		t.Fatalf("failed to create user:\n%v", err)
+	}
+
+	if err := us.UpdateUser(user, userservice.UpdatePassword("qwerty123")); !errors.Is(err, passwordpolicy.ErrPolicyViolation) {
+		t.Errorf("unexpected error updating to a weak password:\nWanted: %v\nGot: %v", passwordpolicy.ErrPolicyViolation, err)
+	}
+	if err := us.UpdateUser(user, userservice.UpdateName("Livetotest Twice"), userservice.UpdatePassword("livetotesttwice")); !errors.Is(err, passwordpolicy.ErrPolicyViolation) {
+		t.Errorf("password containing the updated name accepted:\n%v", err)
+	}
+	if store.updates != 0 {
+		t.Error("user updated with a password failing the policy")
+	}
+	if err := us.UpdateUser(user, userservice.UpdatePassword("livetotesttwice")); err != nil {
+		t.Errorf("failed to update password:\n%v", err)
+	}
+}
+
+/*
+	breachedList is an authentication.BreachedPasswords
+	containing the listed passwords
+*/
+type breachedList []string
+
+func (bl breachedList) Breached(password string) (bool, error) {
+	for _, breached := range bl {
+		if password == breached {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+	*** TestBreachedPasswords ***
+	TestBreachedPasswords checks that new and updated passwords
+	are rejected if they have appeared in a data breach.
+*/
+func TestBreachedPasswords(t *testing.T) {
+
+	store := newMemStore()
+	us := userservice.NewUserService()
+	us.UserDS = store
+	us.PasswordHasher = passwordhash.Fake{}
+	us.BreachedPasswords = breachedList{"livetotestdaily"}
+
+	const name, email = "Testy McTestface", "breached@mctestface.com"
+
+	if _, err := us.NewUser(name, email, "livetotestdaily"); err != authentication.ErrBreachedPassword {
+		t.Errorf("unexpected error for a breached password:\nWanted: %v\nGot: %v", authentication.ErrBreachedPassword, err)
+	}
+
+	user, err := us.NewUser(name, email, "livetotesttwice")
+	if err != nil {
+		t.Fatalf("failed to create user:\n%v", err)
+	}
+	if err := us.UpdateUser(user, userservice.UpdatePassword("livetotestdaily")); err != authentication.ErrBreachedPassword {
+		t.Errorf("unexpected error updating to a breached password:\nWanted: %v\nGot: %v", authentication.ErrBreachedPassword, err)
+	}
+	if store.updates != 0 {
+		t.Error("user updated with a breached password")
+	}
+}
+
+/*
+	*** TestRandomFailure ***
+	TestRandomFailure checks that users are not created or updated
+	with empty identifiers when the random generator fails.
+*/
+func TestRandomFailure(t *testing.T) {
+
+	store := newMemStore()
+	us := userservice.NewUserService()
+	us.UserDS = store
+	us.PasswordHasher = passwordhash.Fake{}
+
+	user, err := us.NewUser("Testy McTestface", "random@mctestface.com", "livetotestdaily")
+	if err != nil {
+		t.Fatalf("failed to create user:\n%v", err)
+	}
+
+	failing := []securerandom.Generator{
+		{Source: iotest.ErrReader(errors.New("no entropy"))},
+		{Source: bytes.NewReader(make([]byte, 81))}, // enough for the TokenUserID only
+	}
+	for _, g := range failing {
+		us.Random = g
+		if _, err := us.NewUser("Testy McTestface", "random2@mctestface.com", "livetotestdaily"); !errors.Is(err, securerandom.ErrEntropy) {
+			t.Errorf("unexpected error creating a user:\nWanted: %v\nGot: %v", securerandom.ErrEntropy, err)
+		}
+	}
+	if err := us.UpdateRefreshToken(user); !errors.Is(err, securerandom.ErrEntropy) {
+		t.Errorf("unexpected error updating the refresh token:\nWanted: %v\nGot: %v", securerandom.ErrEntropy, err)
+	}
+	if len(store.users) != 1 || store.updates != 0 {
+		t.Error("user stored without random identifiers")
+	}
+}
+
 /*
 **********************************
 *
@@ -284,7 +527,7 @@ func BenchmarkNewUser(b *testing.B) {
 
 	user := new(authentication.User)
 	for n := 0; n < b.N; n++ {
-		user, err = us.NewUser("name", fmt.Sprintf("%v", n)+"@address.com", "password")
+		user, err = us.NewUser("name", fmt.Sprintf("%v", n)+"@address.com", "livetotestdaily")
 		if err != nil {
 			b.Fatalf("failed to create user:\n%s", err.Error())
 		}
@@ -344,7 +587,7 @@ func BenchmarkUpdateUser(b *testing.B) {
 	us.UserDS = userStore
 
 	var user *authentication.User
-	user, err = us.NewUser("name", "email@address.com", "password")
+	user, err = us.NewUser("name", "email@address.com", "livetotestdaily")
 	if err != nil {
 		b.Fatal("failed to create user")
 	}
@@ -356,7 +599,7 @@ func BenchmarkUpdateUser(b *testing.B) {
 		if err := us.UpdateUser(user,
 			userservice.UpdateName(prefix+"New Name"),
 			userservice.UpdateEmail(prefix+"Email@address.com"),
-			userservice.UpdatePassword(prefix+"newpassword"),
+			userservice.UpdatePassword(prefix+"livetotestdaily"),
 		); err != nil {
 			b.Fatalf("Failed to update user: \n%v", err)
 		}
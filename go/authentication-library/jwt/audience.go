@@ -0,0 +1,57 @@
+package jwt
+
+import (
+	"encoding/json"
+)
+
+/*
+	Audience is the "aud" claim, the recipients the token is intended for.
+	RFC 7519 allows a single string or an array of strings,
+	so both forms are accepted when decoding.  A single audience is
+	marshalled as a string, and an empty audience as an empty string.
+*/
+type Audience []string
+
+/*
+	Contains returns true if the audience includes the recipient
+*/
+func (a Audience) Contains(recipient string) bool {
+	for _, aud := range a {
+		if aud == recipient {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	switch len(a) {
+	case 0:
+		return json.Marshal("")
+	case 1:
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		if single == "" {
+			*a = nil
+			return nil
+		}
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	if len(multiple) == 0 {
+		multiple = nil
+	}
+	*a = multiple
+	return nil
+}
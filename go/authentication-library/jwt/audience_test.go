@@ -0,0 +1,69 @@
+package jwt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAudienceJSON(t *testing.T) {
+	tests := []struct {
+		desc     string
+		json     string
+		audience Audience
+		marshal  string
+	}{
+		{desc: "Single string", json: `"api"`, audience: Audience{"api"}, marshal: `"api"`},
+		{desc: "Array", json: `["api","web"]`, audience: Audience{"api", "web"}, marshal: `["api","web"]`},
+		{desc: "Single element array", json: `["api"]`, audience: Audience{"api"}, marshal: `"api"`},
+		{desc: "Empty string", json: `""`, audience: nil, marshal: `""`},
+		{desc: "Empty array", json: `[]`, audience: nil, marshal: `""`},
+		{desc: "Null", json: `null`, audience: nil, marshal: `""`},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var got Audience
+			if err := json.Unmarshal([]byte(test.json), &got); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(got, test.audience) {
+				t.Errorf("incorrect audience:\nWanted: %#v\nGot: %#v", test.audience, got)
+			}
+
+			bs, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("failed to marshal: %v", err)
+			}
+			if string(bs) != test.marshal {
+				t.Errorf("incorrect JSON:\nWanted: %v\nGot: %s", test.marshal, bs)
+			}
+		})
+	}
+
+	for _, invalid := range []string{`1`, `{"aud":"api"}`, `["api",1]`} {
+		var got Audience
+		if err := json.Unmarshal([]byte(invalid), &got); err == nil {
+			t.Errorf("unmarshalled invalid audience %v", invalid)
+		}
+	}
+}
+
+/*
+	A token issued by another identity provider, with an array audience
+*/
+func TestDecodeAudienceArray(t *testing.T) {
+	token := NewToken("https://idp.example.com", "", "sub", "jti", "kid", 3600)
+	token.Audience = Audience{"https://api.example.com", "https://web.example.com"}
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	got := Token{Config: Config{Lifespan: 3600}}
+	if err := Decode(jwt, jwtioSecret(), &got, WithAudience("https://web.example.com")); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	if !reflect.DeepEqual(got.Audience, token.Audience) {
+		t.Errorf("incorrect audience:\nWanted: %v\nGot: %v", token.Audience, got.Audience)
+	}
+}
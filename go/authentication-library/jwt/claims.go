@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+/*
+	registeredClaims are the claim names held by the Payload,
+	which custom claims must not use
+*/
+var registeredClaims = map[string]bool{
+	"iss": true,
+	"aud": true,
+	"sub": true,
+	"jti": true,
+	"kid": true,
+	"iat": true,
+	"nbf": true,
+	"exp": true,
+}
+
+/*
+	MarshalPayload returns the JSON payload of the token, the registered claims
+	followed by any custom claims, for use in other token formats such as PASETO
+*/
+func (t *Token) MarshalPayload() (payload []byte, err error) {
+	return t.payloadJSON()
+}
+
+/*
+	payloadJSON marshals the registered claims of the Payload,
+	followed by the token's custom Claims and Extra claims.
+	The registered claims are marshalled first, and in the same order,
+	so tokens without custom claims are unchanged.
+*/
+func (t *Token) payloadJSON() (payload []byte, err error) {
+	payload, err = json.Marshal(t.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if t.Claims == nil && len(t.Extra) == 0 {
+		return payload, nil
+	}
+
+	seen := make(map[string]bool)
+	var custom [][]byte
+
+	if t.Claims != nil {
+		claims, err := json.Marshal(t.Claims)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+		}
+		members, err := claimNames(claims)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range members {
+			if registeredClaims[name] {
+				return nil, fmt.Errorf("%w: %q", ErrReservedClaim, name)
+			}
+			seen[name] = true
+		}
+		if len(members) > 0 {
+			custom = append(custom, claims[1:len(claims)-1])
+		}
+	}
+
+	if len(t.Extra) > 0 {
+		for name := range t.Extra {
+			if registeredClaims[name] || seen[name] {
+				return nil, fmt.Errorf("%w: %q", ErrReservedClaim, name)
+			}
+		}
+		extra, err := json.Marshal(t.Extra)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+		}
+		custom = append(custom, extra[1:len(extra)-1])
+	}
+
+	/*
+		Splice the custom claims into the registered claims object
+	*/
+	if len(custom) == 0 {
+		return payload, nil
+	}
+	joined := append(payload[:len(payload)-1], ',')
+	joined = append(joined, bytes.Join(custom, []byte(","))...)
+	return append(joined, '}'), nil
+}
+
+/*
+	claimNames returns the member names of a marshalled claims object,
+	returning ErrInvalidClaims if the claims are not a JSON object
+*/
+func claimNames(claims []byte) (names []string, err error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(claims, &members); err != nil || members == nil {
+		return nil, fmt.Errorf("%w: custom claims must be a JSON object", ErrInvalidClaims)
+	}
+	for name := range members {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+/*
+	unmarshalClaims fills the token's custom Claims, if set, from the
+	validated payload, and collects every claim that is not a registered claim into Extra
+*/
+func (t *Token) unmarshalClaims(payload []byte) error {
+	if t.Claims != nil {
+		if err := json.Unmarshal(payload, t.Claims); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+		}
+	}
+
+	var all map[string]interface{}
+	if err := json.Unmarshal(payload, &all); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClaims, err)
+	}
+	for name := range registeredClaims {
+		delete(all, name)
+	}
+	if len(all) == 0 {
+		all = nil
+	}
+	t.Extra = all
+	return nil
+}
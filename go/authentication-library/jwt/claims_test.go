@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+type testClaims struct {
+	Roles         []string `json:"roles"`
+	TenantID      string   `json:"tenant_id"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+func TestCustomClaims(t *testing.T) {
+	want := testClaims{Roles: []string{"admin", "billing"}, TenantID: "acme", EmailVerified: true}
+
+	token := NewToken("iss", "aud", "sub", "jti", "kid", 3600)
+	token.Claims = &want
+	token.Extra = map[string]interface{}{"scope": "read write"}
+
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	// the registered claims are unchanged, and come first
+	payload, _ := b64.ToBytes(strings.Split(jwt, ".")[1])
+	if !strings.HasPrefix(string(payload), `{"iss":"iss","aud":"aud","sub":"sub"`) {
+		t.Errorf("registered claims modified: %s", payload)
+	}
+
+	var claims testClaims
+	got := Token{Config: Config{Lifespan: 3600}, Claims: &claims}
+	if err := Decode(jwt, jwtioSecret(), &got); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("incorrect custom claims:\nWanted: %+v\nGot: %+v", want, claims)
+	}
+	if got.Claims != &claims {
+		t.Errorf("Claims replaced by Decode")
+	}
+	if !reflect.DeepEqual(got.Payload, token.Payload) {
+		t.Errorf("incorrect registered claims:\nWanted: %+v\nGot: %+v", token.Payload, got.Payload)
+	}
+
+	wantExtra := map[string]interface{}{
+		"roles":          []interface{}{"admin", "billing"},
+		"tenant_id":      "acme",
+		"email_verified": true,
+		"scope":          "read write",
+	}
+	if !reflect.DeepEqual(got.Extra, wantExtra) {
+		t.Errorf("incorrect extra claims:\nWanted: %v\nGot: %v", wantExtra, got.Extra)
+	}
+}
+
+func TestCustomClaimsNotFilledForInvalidTokens(t *testing.T) {
+	token := NewToken("iss", "aud", "sub", "jti", "kid", 3600)
+	token.Claims = &testClaims{Roles: []string{"admin"}}
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	var claims testClaims
+	got := Token{Config: Config{Lifespan: 3600}, Claims: &claims}
+	wrongSecret := func(string) string { return "wrongsecret" }
+	if err := Decode(jwt, wrongSecret, &got); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", ErrInvalidToken, err)
+	}
+	if claims.Roles != nil || got.Extra != nil {
+		t.Errorf("claims filled from an invalid token: %+v %v", claims, got.Extra)
+	}
+}
+
+func TestCustomClaimsErrors(t *testing.T) {
+	tests := []struct {
+		desc   string
+		claims interface{}
+		extra  map[string]interface{}
+		err    error
+	}{
+		{desc: "Registered claim in struct", claims: &struct {
+			Subject string `json:"sub"`
+		}{"override"}, err: ErrReservedClaim},
+		{desc: "Registered claim in extra", extra: map[string]interface{}{"exp": 0}, err: ErrReservedClaim},
+		{desc: "Extra repeats struct claim", claims: &testClaims{}, extra: map[string]interface{}{"roles": "admin"}, err: ErrReservedClaim},
+		{desc: "Claims not an object", claims: []string{"admin"}, err: ErrInvalidClaims},
+		{desc: "Claims not JSON", claims: func() {}, err: ErrInvalidClaims},
+		{desc: "Empty claims", claims: &struct{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			token := NewToken("iss", "aud", "sub", "jti", "kid", 3600)
+			token.Claims = test.claims
+			token.Extra = test.extra
+			if _, err := token.Create(jwtioSecret()); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
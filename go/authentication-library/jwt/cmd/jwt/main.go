@@ -0,0 +1,444 @@
+/*
+	jwt is a command line tool for debugging JWTs, without sending them to a third party website.
+
+	Usage:
+		jwt inspect [-json] [TOKEN]
+		jwt verify (-secret-file FILE | -secret-env NAME) [-alg HS512] [-lifespan 1h] [-valid-from unix] [-iss ISSUER] [-aud AUDIENCE] [-leeway d] [-payload-kid] [TOKEN]
+		jwt sign (-secret-file FILE | -secret-env NAME) [-alg HS512] [-iss ISSUER] [-aud AUDIENCE] [-sub SUBJECT] [-jti ID] [-kid KEYID] [-exp 1h] [-claim name=value]...
+
+	inspect prints the header and claims of the token, with the times in a readable form,
+	WITHOUT checking the signature, so the contents may have been forged.
+	verify checks the token as jwt.Decode would, and explains which check failed,
+	exiting with status 1 if the token is rejected.  The lifespan defaults to the
+	authentication service's 1h, as it is checked against the token's own exp and iat.
+	sign creates a token signed with the secret, i.e. to test a service.
+
+	Tokens are read from the first line of stdin when not given as an argument,
+	so they don't appear in the shell history.
+	Secrets are read from a file, or the environment variable named by -secret-env,
+	and are used whatever the token's key ID.  Only the HMAC algorithms are supported.
+*/
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/markstanden/jwt"
+	jwttime "github.com/markstanden/jwt/time"
+)
+
+var (
+	errUsage = errors.New(`usage:
+	jwt inspect [-json] [TOKEN]
+	jwt verify (-secret-file FILE | -secret-env NAME) [-alg HS512] [-lifespan 1h] [-valid-from unix] [-iss ISSUER] [-aud AUDIENCE] [-leeway d] [-payload-kid] [TOKEN]
+	jwt sign (-secret-file FILE | -secret-env NAME) [-alg HS512] [-iss ISSUER] [-aud AUDIENCE] [-sub SUBJECT] [-jti ID] [-kid KEYID] [-exp 1h] [-claim name=value]...`)
+	errRejected = errors.New("token rejected")
+)
+
+/*
+	timeClaims are the claims printed as times, in the order they occur
+*/
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+func main() {
+	if err := run(os.Args, os.Stdin, os.Stdout, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, now time.Time) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	switch args[1] {
+	case "inspect":
+		return inspect(args[2:], stdin, stdout, now)
+	case "verify":
+		return verify(args[2:], stdin, stdout, now)
+	case "sign":
+		return sign(args[2:], stdout)
+	default:
+		return fmt.Errorf("unknown command %q\n%w", args[1], errUsage)
+	}
+}
+
+/*
+	inspect prints the unverified header and payload of the token
+*/
+func inspect(args []string, stdin io.Reader, stdout io.Writer, now time.Time) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "output JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	token, err := readToken(flags, stdin)
+	if err != nil {
+		return err
+	}
+
+	/* print as much of the token as could be decoded, then the error */
+	t, inspectErr := jwt.Inspect(token)
+	if t.Claims == nil {
+		return inspectErr
+	}
+
+	if *asJSON {
+		if err := writeJSON(stdout, struct {
+			Header   json.RawMessage   `json:"header"`
+			Payload  json.RawMessage   `json:"payload"`
+			Times    map[string]string `json:"times,omitempty"`
+			Verified bool              `json:"verified"`
+		}{t.RawHeader, t.RawPayload, times(t.Claims), false}); err != nil {
+			return err
+		}
+		return inspectErr
+	}
+
+	fmt.Fprintln(stdout, "NOT VERIFIED: the signature and claims have not been checked")
+	fmt.Fprintln(stdout, "header:")
+	writeIndented(stdout, t.RawHeader)
+	fmt.Fprintln(stdout, "payload:")
+	writeIndented(stdout, t.RawPayload)
+	for _, name := range timeClaims {
+		if value, ok := t.Claims[name]; ok {
+			fmt.Fprintf(stdout, "%v: %v\n", name, describeTime(value, now))
+		}
+	}
+	fmt.Fprintf(stdout, "signature: %d bytes\n", len(t.Signature))
+	return inspectErr
+}
+
+/*
+	verify decodes the token with the secret, and explains why it was rejected
+*/
+func verify(args []string, stdin io.Reader, stdout io.Writer, now time.Time) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	secretFile := flags.String("secret-file", "", "file holding the secret")
+	secretEnv := flags.String("secret-env", "", "environment variable holding the secret")
+	alg := flags.String("alg", jwt.HS512, "accepted algorithm, HS256, HS384 or HS512")
+	lifespan := flags.Duration("lifespan", time.Hour, "lifespan of the service's tokens, the authentication service's is 1h")
+	validFrom := flags.Int64("valid-from", 0, "unix time the service started issuing tokens, defaults to 1st Jan 2021")
+	issuer := flags.String("iss", "", "accepted issuer")
+	audience := flags.String("aud", "", "the service's audience")
+	leeway := flags.Duration("leeway", 0, "allowed clock skew")
+	payloadKeyID := flags.Bool("payload-kid", false, "accept a key ID in the payload, from older tokens")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *lifespan < time.Second {
+		return errors.New("-lifespan must be at least 1s")
+	}
+	secret, err := readSecret(*secretFile, *secretEnv)
+	if err != nil {
+		return err
+	}
+	token, err := readToken(flags, stdin)
+	if err != nil {
+		return err
+	}
+
+	options := []jwt.DecodeOption{
+		jwt.WithAlgorithms(*alg),
+		jwt.WithClock(jwttime.Fixed(now.Unix())),
+		jwt.WithLeeway(*leeway),
+	}
+	if *issuer != "" {
+		options = append(options, jwt.WithIssuer(*issuer))
+	}
+	if *audience != "" {
+		options = append(options, jwt.WithAudience(*audience))
+	}
+	if *payloadKeyID {
+		options = append(options, jwt.AllowPayloadKeyID())
+	}
+
+	unverified, _ := jwt.Inspect(token)
+	config := jwt.Config{
+		ValidFrom: *validFrom,
+		Lifespan:  int64(*lifespan / time.Second),
+	}
+
+	t := jwt.Token{Config: config}
+	err = jwt.Decode(token, func(keyID string) string { return secret }, &t, options...)
+	if err == nil {
+		fmt.Fprintln(stdout, "valid")
+		fmt.Fprintf(stdout, "sub: %v\n", t.UserID)
+		fmt.Fprintf(stdout, "exp: %v\n", describeTime(json.Number(fmt.Sprint(t.ExpirationTime)), now))
+		return nil
+	}
+
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+	if ve.Claim == "" {
+		fmt.Fprintf(stdout, "rejected: %v\n", ve.Reason)
+	} else {
+		fmt.Fprintf(stdout, "rejected: %v (%v)\n", ve.Reason, ve.Claim)
+	}
+	fmt.Fprintln(stdout, explain(ve, unverified, config, *alg, *issuer, *audience, now))
+	return fmt.Errorf("%w: %v", errRejected, err)
+}
+
+/*
+	explain describes the failed check in terms of the token's values
+*/
+func explain(ve *jwt.ValidationError, t jwt.UnverifiedToken, config jwt.Config, alg, issuer, audience string, now time.Time) string {
+	value := t.Claims[ve.Claim]
+
+	switch ve.Reason {
+	case jwt.ReasonMalformed:
+		return "the token is not three base64 URL encoded sections, with a JSON header and payload of the expected types"
+
+	case jwt.ReasonBadHeader:
+		switch ve.Claim {
+		case "alg":
+			return fmt.Sprintf("alg %q is not the accepted algorithm %q (-alg)", t.Header.Algorithm, alg)
+		case "typ":
+			return fmt.Sprintf("typ %q is not JWT or at+jwt", t.Header.TokenType)
+		case "cty":
+			return "a signed token must not have a cty of JWT, nested tokens are signed then encrypted (JWE)"
+		case "crit":
+			return fmt.Sprintf("crit %q lists extensions that are not supported", t.Header.Critical)
+		}
+
+	case jwt.ReasonBadSignature:
+		return "the signature does not match, either the secret is wrong or the token has been altered"
+
+	case jwt.ReasonUnknownKeyID:
+		return fmt.Sprintf("no secret was found for kid %q, or the secret is empty", t.Header.KeyID)
+
+	case jwt.ReasonExpired:
+		if errors.Is(ve, jwt.ErrTokenTooOld) {
+			return fmt.Sprintf("iat %v is older than the maximum age", describeTime(value, now))
+		}
+		if ve.Claim == "iat" {
+			return fmt.Sprintf("iat %v is more than the lifespan %v ago", describeTime(value, now), seconds(config.Lifespan))
+		}
+		return fmt.Sprintf("exp %v has passed", describeTime(value, now))
+
+	case jwt.ReasonNotYetValid:
+		return fmt.Sprintf("%v %v is in the future, allow for clock skew with -leeway", ve.Claim, describeTime(value, now))
+
+	case jwt.ReasonIssuedBeforeValidFrom:
+		validFrom := config.ValidFrom
+		if validFrom == 0 {
+			validFrom = 1609459200
+		}
+		return fmt.Sprintf("%v %v is before the service started issuing tokens at %v (-valid-from)",
+			ve.Claim, describeTime(value, now), time.Unix(validFrom, 0).UTC().Format(time.RFC3339))
+
+	case jwt.ReasonInvalidClaim:
+		switch ve.Claim {
+		case "iss":
+			return fmt.Sprintf("iss %q is not the accepted issuer %q (-iss)", t.Payload.Issuer, issuer)
+		case "aud":
+			return fmt.Sprintf("aud %q does not include the audience %q (-aud)", []string(t.Payload.Audience), audience)
+		}
+		return fmt.Sprintf("%v %v is more than the lifespan %v after iat, so can't have been issued by the service (-lifespan)",
+			ve.Claim, describeTime(value, now), seconds(config.Lifespan))
+
+	case jwt.ReasonMissingClaim:
+		return fmt.Sprintf("the required claim %q is missing", ve.Claim)
+	}
+	return ve.Error()
+}
+
+/*
+	sign creates a token from the flags, signed with the secret
+*/
+func sign(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sign", flag.ContinueOnError)
+	secretFile := flags.String("secret-file", "", "file holding the secret")
+	secretEnv := flags.String("secret-env", "", "environment variable holding the secret")
+	alg := flags.String("alg", jwt.HS512, "algorithm, HS256, HS384 or HS512")
+	issuer := flags.String("iss", "", "issuer")
+	audience := flags.String("aud", "", "audience")
+	subject := flags.String("sub", "", "subject, the user ID")
+	jwtID := flags.String("jti", "", "token ID")
+	keyID := flags.String("kid", "", "key ID, the version of the secret")
+	expires := flags.Duration("exp", time.Hour, "time until the token expires")
+	claims := make(claimsFlag)
+	flags.Var(claims, "claim", "custom claim as name=value, the value is used as JSON if valid, repeatable")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+	secret, err := readSecret(*secretFile, *secretEnv)
+	if err != nil {
+		return err
+	}
+
+	signer, err := jwt.NewHMAC(*alg, secret)
+	if err != nil {
+		return err
+	}
+
+	t := jwt.NewToken(*issuer, *audience, *subject, *jwtID, *keyID, int64(*expires/time.Second))
+	if len(claims) > 0 {
+		t.Extra = claims
+	}
+	token, err := t.Sign(signer)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, token)
+	return nil
+}
+
+/*
+	claimsFlag collects the custom claims from repeated -claim flags
+*/
+type claimsFlag map[string]interface{}
+
+func (c claimsFlag) String() string {
+	return ""
+}
+
+func (c claimsFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i < 1 {
+		return fmt.Errorf("claim %q is not name=value", s)
+	}
+	name, value := s[:i], s[i+1:]
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	c[name] = v
+	return nil
+}
+
+/*
+	readToken returns the token argument, or the first line of stdin if there isn't one
+*/
+func readToken(flags *flag.FlagSet, stdin io.Reader) (token string, err error) {
+	switch flags.NArg() {
+	case 1:
+		return flags.Arg(0), nil
+	case 0:
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if token = strings.TrimSpace(line); token == "" {
+			return "", errUsage
+		}
+		return token, nil
+	}
+	return "", errUsage
+}
+
+/*
+	readSecret returns the secret from the file, or the environment variable
+*/
+func readSecret(file, env string) (secret string, err error) {
+	switch {
+	case file != "" && env != "":
+		return "", errors.New("use only one of -secret-file and -secret-env")
+	case file != "":
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		secret = strings.TrimRight(string(contents), "\r\n")
+	case env != "":
+		secret = os.Getenv(env)
+	default:
+		return "", errors.New("a secret is required, use -secret-file or -secret-env")
+	}
+
+	if secret == "" {
+		return "", errors.New("the secret is empty")
+	}
+	return secret, nil
+}
+
+/*
+	times returns the time claims as RFC 3339 strings
+*/
+func times(claims map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	for _, name := range timeClaims {
+		if value, ok := claims[name]; ok {
+			if t, ok := unixTime(value); ok {
+				out[name] = t.Format(time.RFC3339)
+			}
+		}
+	}
+	return out
+}
+
+/*
+	describeTime returns the unix time claim as an RFC 3339 time, relative to now
+*/
+func describeTime(value interface{}, now time.Time) string {
+	t, ok := unixTime(value)
+	if !ok {
+		return fmt.Sprintf("%v (not a unix time)", value)
+	}
+
+	d := t.Sub(now).Round(time.Second)
+	switch {
+	case d > 0:
+		return fmt.Sprintf("%v (%v, in %v)", value, t.Format(time.RFC3339), d)
+	case d < 0:
+		return fmt.Sprintf("%v (%v, %v ago)", value, t.Format(time.RFC3339), -d)
+	}
+	return fmt.Sprintf("%v (%v, now)", value, t.Format(time.RFC3339))
+}
+
+/*
+	unixTime returns the claim value as a time, if it is a whole number
+*/
+func unixTime(value interface{}) (t time.Time, ok bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	unix, err := n.Int64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(unix, 0).UTC(), true
+}
+
+/*
+	seconds returns a number of seconds as a duration
+*/
+func seconds(s int64) time.Duration {
+	return time.Duration(s) * time.Second
+}
+
+/*
+	writeIndented writes the JSON indented, or as it is if it isn't valid JSON
+*/
+func writeIndented(w io.Writer, raw []byte) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		out.Reset()
+		out.Write(raw)
+	}
+	fmt.Fprintln(w, out.String())
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markstanden/jwt"
+	"github.com/markstanden/jwt/b64"
+)
+
+const (
+	testSecret = "cmd-jwt-test-secret"
+	testNow    = 1700000000
+	testHeader = `{"alg":"HS512","typ":"JWT"}`
+)
+
+/*
+	signed returns a token with the header and payload as given, signed with the secret
+*/
+func signed(t *testing.T, alg, header, payload, secret string) string {
+	t.Helper()
+	signer, err := jwt.NewHMAC(alg, secret)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	input := b64.FromBytes([]byte(header)) + "." + b64.FromBytes([]byte(payload))
+	signature, err := signer.Sign(input)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return input + "." + b64.FromBytes(signature)
+}
+
+/*
+	claims returns a payload as issued by the authentication service, with the supplied times
+*/
+func claims(iat, nbf, exp int64) string {
+	return fmt.Sprintf(`{"iss":"markstanden.dev","aud":"markstanden.dev","sub":"tokenuserid","iat":%d,"nbf":%d,"exp":%d}`, iat, nbf, exp)
+}
+
+/*
+	secretFile writes the secret to a file, as it would be mounted in a container
+*/
+func secretFile(t *testing.T) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(file, []byte(testSecret+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	return file
+}
+
+func TestVerify(t *testing.T) {
+	file := secretFile(t)
+	now := time.Unix(testNow, 0)
+	valid := signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow-60, testNow+3540), testSecret)
+
+	tests := []struct {
+		desc    string
+		args    []string
+		token   string
+		want    string
+		explain string
+	}{
+		{desc: "Valid", token: valid, want: "valid", explain: "sub: tokenuserid"},
+		{desc: "Valid Issuer and Audience", args: []string{"-iss", "markstanden.dev", "-aud", "markstanden.dev"}, token: valid, want: "valid"},
+		{desc: "Malformed",
+			token: "abc.def", want: "rejected: malformed", explain: "three base64 URL encoded sections"},
+		{desc: "Algorithm",
+			token: signed(t, jwt.HS256, `{"alg":"HS256","typ":"JWT"}`, claims(testNow-60, testNow-60, testNow+3540), testSecret),
+			want:  "rejected: bad_header (alg)", explain: `alg "HS256" is not the accepted algorithm "HS512"`},
+		{desc: "Accepted Algorithm", args: []string{"-alg", jwt.HS256},
+			token: signed(t, jwt.HS256, `{"alg":"HS256","typ":"JWT"}`, claims(testNow-60, testNow-60, testNow+3540), testSecret),
+			want:  "valid"},
+		{desc: "Type",
+			token: signed(t, jwt.HS512, `{"alg":"HS512","typ":"xml"}`, claims(testNow-60, testNow-60, testNow+3540), testSecret),
+			want:  "rejected: bad_header (typ)", explain: `typ "xml" is not JWT`},
+		{desc: "Content Type",
+			token: signed(t, jwt.HS512, `{"alg":"HS512","typ":"JWT","cty":"JWT"}`, claims(testNow-60, testNow-60, testNow+3540), testSecret),
+			want:  "rejected: bad_header (cty)", explain: "must not have a cty of JWT"},
+		{desc: "Critical",
+			token: signed(t, jwt.HS512, `{"alg":"HS512","typ":"JWT","crit":["exp"]}`, claims(testNow-60, testNow-60, testNow+3540), testSecret),
+			want:  "rejected: bad_header (crit)", explain: "extensions that are not supported"},
+		{desc: "Signature",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow-60, testNow+3540), "another secret"),
+			want:  "rejected: bad_signature", explain: "the secret is wrong"},
+		{desc: "Expired",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow-7200, testNow-7200, testNow-3600), testSecret),
+			want:  "rejected: expired (exp)", explain: "has passed"},
+		{desc: "Issued in the future",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow+600, testNow+600, testNow+4200), testSecret),
+			want:  "rejected: not_yet_valid (iat)", explain: "is in the future"},
+		{desc: "Not before",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow, testNow+600, testNow+3600), testSecret),
+			want:  "rejected: not_yet_valid (nbf)", explain: "-leeway"},
+		{desc: "Leeway", args: []string{"-leeway", "10m"},
+			token: signed(t, jwt.HS512, testHeader, claims(testNow, testNow+600, testNow+3600), testSecret),
+			want:  "valid"},
+		{desc: "Before 2021",
+			token: signed(t, jwt.HS512, testHeader, claims(1600000000, 1600000000, 1600003600), testSecret),
+			want:  "rejected: issued_before_valid_from (iat)", explain: "2021-01-01T00:00:00Z (-valid-from)"},
+		{desc: "Before Valid From", args: []string{"-valid-from", fmt.Sprint(testNow)},
+			token: valid, want: "rejected: issued_before_valid_from (iat)", explain: "2023-11-14T22:13:20Z (-valid-from)"},
+		{desc: "Issuer", args: []string{"-iss", "other.dev"},
+			token: valid, want: "rejected: invalid_claim (iss)", explain: `iss "markstanden.dev" is not the accepted issuer "other.dev"`},
+		{desc: "Audience", args: []string{"-aud", "other.dev"},
+			token: valid, want: "rejected: invalid_claim (aud)", explain: `does not include the audience "other.dev"`},
+		{desc: "Expiry beyond the lifespan",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow-60, testNow+7140), testSecret),
+			want:  "rejected: invalid_claim (exp)", explain: "more than the lifespan 1h0m0s after iat"},
+		{desc: "Expiry within a longer lifespan", args: []string{"-lifespan", "2h"},
+			token: signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow-60, testNow+7140), testSecret),
+			want:  "valid"},
+		{desc: "Not before beyond the lifespan",
+			token: signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow+7140, testNow+3540), testSecret),
+			want:  "rejected: invalid_claim (nbf)", explain: "can't have been issued by the service"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			args := append([]string{"jwt", "verify", "-secret-file", file}, test.args...)
+			err := run(args, strings.NewReader(test.token+"\n"), &out, now)
+
+			lines := strings.SplitN(out.String(), "\n", 2)
+			if lines[0] != test.want {
+				t.Errorf("incorrect result:\nWanted: %v\nGot: %v", test.want, out.String())
+			}
+			if !strings.Contains(out.String(), test.explain) {
+				t.Errorf("incorrect explanation:\nWanted: %v\nGot: %v", test.explain, out.String())
+			}
+			if rejected := strings.HasPrefix(test.want, "rejected"); rejected != errors.Is(err, errRejected) || (!rejected && err != nil) {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+/*
+	Incorrect usage is reported without checking the token
+*/
+func TestVerifyUsage(t *testing.T) {
+	file := secretFile(t)
+	token := signed(t, jwt.HS512, testHeader, claims(testNow-60, testNow-60, testNow+3540), testSecret)
+
+	tests := []struct {
+		desc string
+		args []string
+	}{
+		{desc: "No Secret", args: []string{"jwt", "verify", token}},
+		{desc: "Two Secrets", args: []string{"jwt", "verify", "-secret-file", file, "-secret-env", "JWT_SECRET", token}},
+		{desc: "Zero Lifespan", args: []string{"jwt", "verify", "-secret-file", file, "-lifespan", "0", token}},
+		{desc: "No Token", args: []string{"jwt", "verify", "-secret-file", file}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(test.args, strings.NewReader(""), &out, time.Unix(testNow, 0))
+			if err == nil || errors.Is(err, errRejected) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if out.Len() != 0 {
+				t.Errorf("unexpected output: %v", out.String())
+			}
+		})
+	}
+}
+
+/*
+	explain describes the checks verify cannot reach, as it accepts any key ID
+	and has no options for the maximum age or required claims
+*/
+func TestExplain(t *testing.T) {
+	now := time.Unix(testNow, 0)
+	unverified, err := jwt.Inspect(signed(t, jwt.HS512, `{"alg":"HS512","typ":"JWT","kid":"v2"}`, claims(testNow-7200, testNow-7200, testNow+600), testSecret))
+	if err != nil {
+		t.Fatalf("failed to inspect token: %v", err)
+	}
+	config := jwt.Config{Lifespan: 3600}
+
+	tests := []struct {
+		desc string
+		ve   *jwt.ValidationError
+		want string
+	}{
+		{desc: "Unknown Key ID", ve: &jwt.ValidationError{Reason: jwt.ReasonUnknownKeyID, Claim: "kid", Err: jwt.ErrFailedSecret},
+			want: `no secret was found for kid "v2"`},
+		{desc: "Older than the lifespan", ve: &jwt.ValidationError{Reason: jwt.ReasonExpired, Claim: "iat", Err: jwt.ErrExpiredToken},
+			want: "iat 1699992800 (2023-11-14T20:13:20Z, 2h0m0s ago) is more than the lifespan 1h0m0s ago"},
+		{desc: "Older than the maximum age", ve: &jwt.ValidationError{Reason: jwt.ReasonExpired, Claim: "iat", Err: jwt.ErrTokenTooOld},
+			want: "is older than the maximum age"},
+		{desc: "Missing Claim", ve: &jwt.ValidationError{Reason: jwt.ReasonMissingClaim, Claim: "jti", Err: jwt.ErrMissingClaim},
+			want: `the required claim "jti" is missing`},
+		{desc: "Decryption Failed", ve: &jwt.ValidationError{Reason: jwt.ReasonDecryptionFailed, Claim: "kid", Err: jwt.ErrInvalidToken},
+			want: string(jwt.ReasonDecryptionFailed)},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := explain(test.ve, unverified, config, jwt.HS512, "", "", now)
+			if !strings.Contains(got, test.want) {
+				t.Errorf("incorrect explanation:\nWanted: %v\nGot: %v", test.want, got)
+			}
+		})
+	}
+}
@@ -4,32 +4,62 @@ import (
 	"encoding/json"
 
 	"github.com/markstanden/jwt/b64"
-	"github.com/markstanden/jwt/hash"
 )
 
 /*
-	Create creates a JWT token from a token object
+	Create creates a JWT token from a token object,
+	signed using HS512 with the secret returned by the callback for the token's KeyID,
+	or the LegacyKeyID if the header KeyID is not set
 */
 func (t *Token) Create(getRemoteSecret func(keyID string) string) (jwt string, err error) {
 
+	secret := getRemoteSecret(t.signingKeyID())
+	if secret == "" {
+		return "", ErrFailedSecret
+	}
+
+	signer, err := NewHMAC(HS512, secret)
+	if err != nil {
+		return "", err
+	}
+	return t.Sign(signer)
+}
+
+/*
+	signingKeyID returns the key ID used to find the secret,
+	the header KeyID, or the LegacyKeyID if the header KeyID is not set
+*/
+func (t *Token) signingKeyID() string {
+	if t.KeyID == "" {
+		return t.LegacyKeyID
+	}
+	return t.KeyID
+}
+
+/*
+	Sign creates a JWT token from a token object, signed by the Signer.
+	The header's "alg" is set to the Signer's algorithm.
+*/
+func (t *Token) Sign(signer Signer) (jwt string, err error) {
+
+	t.Header.Algorithm = signer.Algorithm()
+
 	jsonHeader, err := json.Marshal(t.Header)
 	if err != nil {
 		return "", err
 	}
-	jsonPayload, err := json.Marshal(t.Payload)
+	jsonPayload, err := t.payloadJSON()
 	if err != nil {
 		return "", err
 	}
 
 	jwtBody := b64.FromBytes(jsonHeader) + "." + b64.FromBytes(jsonPayload)
 
-	secret := getRemoteSecret(t.KeyID)
-	if secret == "" {
-		return "", ErrFailedSecret
+	sigBS, err := signer.Sign(jwtBody)
+	if err != nil {
+		return "", err
 	}
 
-	sigBS := hash.HS512(jwtBody, secret)
-
 	jwt = jwtBody + "." + b64.FromBytes(sigBS)
 
 	return jwt, nil
@@ -1,30 +1,36 @@
 package jwt
 
 import (
-	"crypto/hmac"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 
 	"github.com/markstanden/jwt/b64"
-	"github.com/markstanden/jwt/hash"
 	"github.com/markstanden/jwt/time"
 )
 
 /*
 	Decode takes an untrusted JWT and checks it for validity:
 		- Checks structure, and for invalid characters
-		- Checks Header
+		- Checks Header, rejecting any "alg" outside of the allowlist (HS512 unless set by WithAlgorithms)
 		- Checks Payload, checking timestamps are valid
-		- Checks signature, by calling the secret callback with the key version encoded within the JWT
+		- Checks signature, by calling the secret callback with the key version encoded within the JWT,
+		  or the KeyLookup set by WithKeyLookup with the key version and algorithm
+		- Checks the issuer, audience, required claims and maximum age, if set by the DecodeOptions
 	it returns:
 		- nil error if the token is valid and has not expired
 		- ErrInvalidToken if the token fails any of the validity checks
 		- ErrExpiredToken if the token is valid, but has expired
 		- ErrFailedSecret if the callback failed to return a secret, or the secret was an empty string
+		- ErrInvalidIssuer, ErrInvalidAudience, ErrMissingClaim or ErrTokenTooOld if the token fails a DecodeOption check
+	The errors are returned within a *ValidationError, giving the Reason and the offending claim,
+	so should be checked with errors.Is or errors.As.
 */
-func Decode(untrustedJWT string, passwordLookup func(key string) (secret string), token *Token) (err error) {
+func Decode(untrustedJWT string, passwordLookup func(key string) (secret string), token *Token, options ...DecodeOption) (err error) {
+
+	opts := newDecodeOptions(passwordLookup, options)
 
 	/*
 		ValidFrom is the official time that the server started issuing tokens.
@@ -32,16 +38,14 @@ func Decode(untrustedJWT string, passwordLookup func(key string) (secret string)
 		we cannot have issued them before this date.
 		If the value hasn't been set in the supplied struct we will default it to the start of 2021.
 	*/
-	if token.Config.ValidFrom == 0 {
-		/* 01 Jan 2021 00:00 UTC */
-		token.Config.ValidFrom = 1609459200
-		token.Log = append(token.Log, "ValidFrom field not set, setting to default")
-	}
+	token.Config.setDefaults()
 
-	token.Log = append(token.Log, "ValidFrom field set as: "+fmt.Sprintf("%d", token.Config.ValidFrom))
-	token.Log = append(token.Log, "Lifespan field set as: "+fmt.Sprintf("%d", token.Config.Lifespan))
+	ut := Token{Config: token.Config}
 
-	ut := Token{}
+	/*
+		The custom claims are only filled once the token has been validated
+	*/
+	claims := token.Claims
 
 	/*
 		Check the JWT as a whole
@@ -51,8 +55,7 @@ func Decode(untrustedJWT string, passwordLookup func(key string) (secret string)
 	jwtSection := strings.Split(untrustedJWT, ".")
 
 	if !checkJwtValid(jwtSection) {
-		token.Log = append(token.Log, "Failed checkJwtValid")
-		return ErrInvalidToken
+		return invalid(ReasonMalformed, "")
 	}
 
 	/*
@@ -68,42 +71,67 @@ func Decode(untrustedJWT string, passwordLookup func(key string) (secret string)
 		Check header section
 	*/
 
-	if err := unmarshalJWT(header, &ut); err != nil {
-		token.Log = append(token.Log, "Failed to unmarshalJWT header")
-		return ErrInvalidToken
+	if err := unmarshalJWT(header, &ut.Header); err != nil {
+		return invalid(ReasonMalformed, "")
 	}
 
-	if !checkHeaderValid(ut.Header) {
-		token.Log = append(token.Log, "Failed checkHeaderValid")
-		return ErrInvalidToken
+	if err := checkHeaderValid(ut.Header, opts); err != nil {
+		return err
 	}
 
 	/*
 		Check payload section
 	*/
 
-	if err := unmarshalJWT(payload, &ut); err != nil {
-		token.Log = append(token.Log, "Failed to unmarshalJWT payload")
-		return ErrInvalidToken
+	if err := unmarshalJWT(payload, &ut.Payload); err != nil {
+		return invalid(ReasonMalformed, "")
 	}
 
+	now := time.Unix(opts.clock)
 	tokenInvalid, tokenExpired := checkTimeValidity(
 		ut.IssuedAtTime,
 		ut.NotBeforeTime,
 		ut.ExpirationTime,
 		token.ValidFrom,
-		token.Lifespan)
+		token.Lifespan,
+		now,
+		opts.leeway)
 
-	if tokenInvalid {
-		token.Log = append(token.Log, "Failed checkTimeValidity")
-		return ErrInvalidToken
+	if tokenInvalid != nil {
+		return tokenInvalid
 	}
 
 	/*
 		Check Signature
 	*/
-	if err := signatureValid(header, payload, signature, passwordLookup(ut.KeyID)); err != nil {
-		token.Log = append(token.Log, "Failed Signature Validation")
+	keyID := opts.keyID(ut)
+	verifier, err := opts.lookup(keyID, ut.Algorithm)
+	if err != nil {
+		if !errors.Is(err, ErrFailedSecret) {
+			err = fmt.Errorf("%w: %v", ErrFailedSecret, err)
+		}
+		return &ValidationError{Reason: ReasonUnknownKeyID, Claim: "kid", Err: err}
+	}
+	if err := signatureValid(header, payload, signature, ut.Algorithm, verifier); err != nil {
+		return err
+	}
+
+	/*
+		Decode the custom claims, now the signature has been validated
+	*/
+	ut.Claims = claims
+	payloadBytes, err := b64.ToBytes(payload)
+	if err != nil {
+		return invalid(ReasonMalformed, "")
+	}
+	if err := ut.unmarshalClaims(payloadBytes); err != nil {
+		return &ValidationError{Reason: ReasonMalformed, Err: fmt.Errorf("%w: %v", ErrInvalidToken, err)}
+	}
+
+	/*
+		Check the issuer, audience, required claims and maximum age if requested
+	*/
+	if err := opts.checkClaims(&ut, payloadBytes, now); err != nil {
 		return err
 	}
 
@@ -117,36 +145,94 @@ func Decode(untrustedJWT string, passwordLookup func(key string) (secret string)
 	/*
 		Token is valid but expired, so return data with expiry error
 	*/
-	if tokenExpired {
-		token.Log = append(token.Log, "Token Has Expired")
-		return ErrExpiredToken
+	if tokenExpired != nil {
+		return tokenExpired
+	}
+
+	return nil
+}
+
+/*
+	DecodePayload checks the claims of a JSON payload that has already been authenticated,
+	such as the payload of a PASETO, using the same rules as Decode:
+		- Checks timestamps are valid, using the token's Config and the clock and leeway options
+		- Checks the issuer, audience, required claims and maximum age, if set by the DecodeOptions
+	The token is filled if the claims are valid, and the errors returned are as Decode.
+	Options for the JWT header and signature have no effect.
+*/
+func DecodePayload(payload []byte, token *Token, options ...DecodeOption) (err error) {
+
+	opts := newDecodeOptions(nil, options)
+	token.Config.setDefaults()
+
+	ut := Token{Config: token.Config, Claims: token.Claims}
+
+	if err := json.Unmarshal(payload, &ut.Payload); err != nil {
+		return invalid(ReasonMalformed, "")
+	}
+
+	now := time.Unix(opts.clock)
+	tokenInvalid, tokenExpired := checkTimeValidity(
+		ut.IssuedAtTime,
+		ut.NotBeforeTime,
+		ut.ExpirationTime,
+		token.ValidFrom,
+		token.Lifespan,
+		now,
+		opts.leeway)
+
+	if tokenInvalid != nil {
+		return tokenInvalid
+	}
+
+	if err := ut.unmarshalClaims(payload); err != nil {
+		return &ValidationError{Reason: ReasonMalformed, Err: fmt.Errorf("%w: %v", ErrInvalidToken, err)}
+	}
+
+	if err := opts.checkClaims(&ut, payload, now); err != nil {
+		return err
 	}
 
+	*token = ut
+
+	if tokenExpired != nil {
+		return tokenExpired
+	}
 	return nil
 }
 
 /*
 	checkHeaderValid performs tests on the contents of the JWT header
-	returns true only if all tests pass
+	returns a *ValidationError naming the rejected header parameter if any test fails
 */
-func checkHeaderValid(h Header) bool {
+func checkHeaderValid(h Header, opts decodeOptions) error {
 
 	// jwt vulnerability where the signature can be
 	// bypassed by setting the alg to none.
 	// if this is attempted log it
 	if h.Algorithm == "none" {
 		log.Println(`"alg": "none" present in header`)
-		return false
+		return invalid(ReasonBadHeader, "alg")
 	}
 
-	if h.Algorithm != "HS512" {
-		return false
+	if !opts.allowed(h.Algorithm) {
+		return invalid(ReasonBadHeader, "alg")
 	}
 
-	if h.TokenType != "JWT" {
-		return false
+	if !opts.typeAllowed(h.TokenType) {
+		return invalid(ReasonBadHeader, "typ")
 	}
-	return true
+
+	// a signed token must not claim to contain another, nested tokens are decoded with DecodeEncrypted
+	if strings.EqualFold(h.ContentType, "JWT") {
+		return invalid(ReasonBadHeader, "cty")
+	}
+
+	// no extensions are understood, so any critical extension must be rejected
+	if h.Critical != nil {
+		return invalid(ReasonBadHeader, "crit")
+	}
+	return nil
 }
 
 /*
@@ -203,18 +289,17 @@ func checkRunes(toCheck, validRunes string) (valid bool) {
 
 /*
 	unmarshalJWT decodes a section of the JWT and
-	unmarshals the JSON data into the provided *Token
+	unmarshals the JSON data into the provided *Header or *Payload.
+	The sections are unmarshalled separately, so a payload can't overwrite the header.
 */
-func unmarshalJWT(jwtSection string, t *Token) error {
+func unmarshalJWT(jwtSection string, section interface{}) error {
 
 	bytes, err := b64.ToBytes(jwtSection)
 	if err != nil {
-		t.Log = append(t.Log, "Failed convert Base64 string to bytes")
 		return ErrInvalidToken
 	}
 
-	if err := json.Unmarshal(bytes, t); err != nil {
-		t.Log = append(t.Log, "Failed to json.Unmarshal JWT section")
+	if err := json.Unmarshal(bytes, section); err != nil {
 		return ErrInvalidToken
 	}
 	return nil
@@ -223,12 +308,14 @@ func unmarshalJWT(jwtSection string, t *Token) error {
 /*
 	checkTimeValidity checks that the Issued at time, Not before time and Expiry
 	are set to values that could have been set by our server.
-	and that they are within the expiry window
+	and that they are within the expiry window at the time now.
+	leeway (seconds) allows for clock skew between servers.
+	tokenInvalid is the first check failed that invalidates the token,
+	tokenExpired is set if the token could be valid, but has expired.
 */
-func checkTimeValidity(iat, nbf, exp, firstIssuedToken, lifespan int64) (tokenInvalid, tokenExpired bool) {
+func checkTimeValidity(iat, nbf, exp, firstIssuedToken, lifespan, now, leeway int64) (tokenInvalid, tokenExpired *ValidationError) {
 
-	now := time.GetUnix()
-	min := now - lifespan
+	min := now - lifespan - leeway
 	max := iat + lifespan
 
 	/*
@@ -240,88 +327,83 @@ func checkTimeValidity(iat, nbf, exp, firstIssuedToken, lifespan int64) (tokenIn
 		before the project started the token should be marked valid but expired
 	*/
 
-	if time.WithinRange(iat, firstIssuedToken, now) {
-		if iat < min {
-			/*
-				token could have been made by our server,
-				but was too long ago to not have expired
-			*/
-			tokenExpired = true
-		}
-	} else {
+	switch {
+	case iat < firstIssuedToken:
+		/* token was made before the project began, so must be invalid */
+		return invalid(ReasonIssuedBeforeValidFrom, "iat"), nil
+	case iat > now+leeway:
+		/* token was made in the future, so must be invalid */
+		return invalid(ReasonNotYetValid, "iat"), nil
+	case iat < min:
 		/*
-			token was made before the project began,
-			or in the future, so must be invalid
+			token could have been made by our server,
+			but was too long ago to not have expired
 		*/
-		tokenInvalid = true
+		tokenExpired = expired("iat")
 	}
 
 	/*
 		The not before time restricts access until a point in time has been reached,
 		so if that time has not been reached yet, the token is invalid.
 	*/
-	if time.WithinRange(nbf, firstIssuedToken, max) {
-		/* It could have been issued by our server */
-		if nbf > now {
-			/* Token is not yet valid */
-			tokenInvalid = true
-		}
-	} else {
-		/*
-			token was made before the project began,
-			or in the future, so must be invalid
-		*/
-		tokenInvalid = true
+	switch {
+	case nbf < firstIssuedToken:
+		/* token was made before the project began, so must be invalid */
+		return invalid(ReasonIssuedBeforeValidFrom, "nbf"), nil
+	case nbf > max:
+		/* our server would not have issued a token starting this far in the future */
+		return invalid(ReasonInvalidClaim, "nbf"), nil
+	case nbf > now+leeway:
+		/* Token is not yet valid */
+		return invalid(ReasonNotYetValid, "nbf"), nil
 	}
 
 	/*
 		First check that the token could have been made by the server,
 		and that the expiry date is not too far in the future
 	*/
-	if time.WithinRange(exp, firstIssuedToken, max) {
-		/* It could have been issued by our server */
-		if exp < now {
-			/* Token has expired */
-			tokenExpired = true
-		}
-	} else {
-		/*
-			token was made before the project began,
-			or too far in the future, so must be invalid
-		*/
-		tokenInvalid = true
+	switch {
+	case exp < firstIssuedToken:
+		/* token was made before the project began, so must be invalid */
+		return invalid(ReasonIssuedBeforeValidFrom, "exp"), nil
+	case exp > max:
+		/* our server would not have issued a token expiring this far in the future */
+		return invalid(ReasonInvalidClaim, "exp"), nil
+	case exp < now-leeway:
+		/* Token has expired */
+		tokenExpired = expired("exp")
 	}
-	return tokenInvalid, tokenExpired
+	return nil, tokenExpired
 }
 
-func signatureValid(header, payload, signature, secret string) (err error) {
+/*
+	signatureValid checks the signature of the token using the verifier
+	returned by the key lookup.  The verifier must be for the algorithm in
+	the header, so a key can't be used with an algorithm it wasn't issued for.
+*/
+func signatureValid(header, payload, signature, algorithm string, verifier Verifier) (err error) {
 
 	/*
-		If the secret is empty we have failed to obtain the secret from the secret store,
-		or the secret is intentionally empty.
+		If the verifier is nil we have failed to obtain the key.
 	*/
-	if secret == "" {
-		return ErrFailedSecret
+	if verifier == nil {
+		return &ValidationError{Reason: ReasonUnknownKeyID, Claim: "kid", Err: ErrFailedSecret}
 	}
 
-	bodyBytes := header + "." + payload
+	if verifier.Algorithm() != algorithm {
+		return invalid(ReasonBadSignature, "alg")
+	}
 
 	signatureBytes, err := b64.ToBytes(signature)
 	if err != nil {
-		return ErrInvalidToken
+		return invalid(ReasonMalformed, "")
 	}
 
-	/*
-		Re-Hash the body section, using our secret,
-		and compare to the signature supplied
-		by the JWT using a time safe comparison.
-	*/
-	hashedBody := hash.HS512(bodyBytes, secret)
-	if hmac.Equal(hashedBody, signatureBytes) {
-		/* signature is valid */
-		return nil
+	if err := verifier.Verify(header+"."+payload, signatureBytes); err != nil {
+		/* signature is invalid */
+		return invalid(ReasonBadSignature, "")
 	}
 
-	/* signature is invalid */
-	return ErrInvalidToken
+	/* signature is valid */
+	return nil
 }
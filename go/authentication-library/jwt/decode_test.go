@@ -1,8 +1,12 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/markstanden/jwt/time"
 )
 
 // test with a valid SHA512 JWT created from jwt.io website
@@ -18,14 +22,45 @@ func TestDecodeJWTIO(t *testing.T) {
 
 	secret := jwtioSecret()
 
-	// Decode the test data
-	err := Decode(jwtioToken, secret, &got)
+	// Decode the test data, at a time between the token's "iat" and "exp"
+	err := Decode(jwtioToken, secret, &got, WithClock(time.Fixed(1625000000)))
 	if err != nil {
-		fmt.Printf("failed to decode JWT \n%t", err)
+		t.Fatalf("failed to decode JWT \n%v", err)
 	}
-	if got.Payload != jwtioStruct {
+	if !reflect.DeepEqual(got.Payload, jwtioStruct) {
 		t.Errorf("Want: \n%v\nGot: \n%v\n", jwtioStruct, got.Payload)
 	}
 
 	fmt.Println("Decoded jwt.io test token OK.")
 }
+
+// DecodePayload applies the same claim checks as Decode, to a payload that has already been authenticated
+func TestDecodePayload(t *testing.T) {
+	const issued = 1700000000
+	payload := []byte(`{"iss":"iss","aud":["aud","other"],"sub":"sub","jti":"jti","iat":1700000000,"nbf":1700000000,"exp":1700003600,"scope":"read"}`)
+
+	tests := []struct {
+		desc    string
+		now     int64
+		options []DecodeOption
+		err     error
+	}{
+		{desc: "Valid", now: issued},
+		{desc: "Expired", now: issued + 3601, err: ErrExpiredToken},
+		{desc: "Not yet valid", now: issued - 1, err: ErrInvalidToken},
+		{desc: "Wrong issuer", now: issued, options: []DecodeOption{WithIssuer("other")}, err: ErrInvalidIssuer},
+		{desc: "Header options ignored", now: issued, options: []DecodeOption{WithAlgorithms(EdDSA), WithTypes("at+jwt")}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := Token{Config: Config{Lifespan: 3600}}
+			err := DecodePayload(payload, &got, append(test.options, WithClock(time.Fixed(test.now)))...)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if test.err == nil && (got.UserID != "sub" || got.Extra["scope"] != "read" || !got.Audience.Contains("other")) {
+				t.Errorf("incorrect token: %+v, %v", got.Payload, got.Extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+)
+
+/*
+	ECDSA signs and verifies tokens with ES256 (P-256) or ES384 (P-384).
+	Signatures are the fixed width R || S form required by RFC 7518,
+	rather than the ASN.1 form used by the crypto/ecdsa package.
+*/
+type ECDSA struct {
+	alg        string
+	privateKey *ecdsa.PrivateKey
+	publicKey  *ecdsa.PublicKey
+}
+
+/*
+	NewECDSASigner returns an ECDSA Signer, which can also verify its own tokens.
+	Returns ErrUnsupportedAlgorithm if alg is not ES256 or ES384,
+	and ErrInvalidKey if the key is missing or on the wrong curve for the algorithm.
+*/
+func NewECDSASigner(alg string, key *ecdsa.PrivateKey) (e ECDSA, err error) {
+	if key == nil {
+		return ECDSA{}, fmt.Errorf("%w: missing ECDSA private key", ErrInvalidKey)
+	}
+	e, err = NewECDSAVerifier(alg, &key.PublicKey)
+	if err != nil {
+		return ECDSA{}, err
+	}
+	e.privateKey = key
+	return e, nil
+}
+
+/*
+	NewECDSAVerifier returns an ECDSA Verifier for the public key.
+	Returns ErrUnsupportedAlgorithm if alg is not ES256 or ES384,
+	and ErrInvalidKey if the key is missing or on the wrong curve for the algorithm.
+*/
+func NewECDSAVerifier(alg string, key *ecdsa.PublicKey) (e ECDSA, err error) {
+	curve, err := ecdsaCurve(alg)
+	if err != nil {
+		return ECDSA{}, err
+	}
+	if key == nil || key.Curve == nil {
+		return ECDSA{}, fmt.Errorf("%w: missing ECDSA public key", ErrInvalidKey)
+	}
+	if key.Curve.Params().Name != curve.Params().Name {
+		return ECDSA{}, fmt.Errorf("%w: %v requires a %v key, got %v", ErrInvalidKey, alg, curve.Params().Name, key.Curve.Params().Name)
+	}
+	return ECDSA{alg: alg, publicKey: key}, nil
+}
+
+/*
+	ecdsaCurve returns the curve required by the algorithm
+*/
+func ecdsaCurve(alg string) (curve elliptic.Curve, err error) {
+	switch alg {
+	case ES256:
+		return elliptic.P256(), nil
+	case ES384:
+		return elliptic.P384(), nil
+	}
+	return nil, fmt.Errorf("%w: %q is not an ECDSA algorithm", ErrUnsupportedAlgorithm, alg)
+}
+
+func (e ECDSA) Algorithm() string {
+	return e.alg
+}
+
+func (e ECDSA) Sign(signingInput string) (signature []byte, err error) {
+	if e.privateKey == nil {
+		return nil, fmt.Errorf("%w: ECDSA verifier has no private key", ErrInvalidKey)
+	}
+	r, s, err := ecdsa.Sign(rand.Reader, e.privateKey, e.digest(signingInput))
+	if err != nil {
+		return nil, err
+	}
+
+	size := e.size()
+	signature = make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return signature, nil
+}
+
+func (e ECDSA) Verify(signingInput string, signature []byte) error {
+	size := e.size()
+	if len(signature) != 2*size {
+		return ErrInvalidSignature
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(e.publicKey, e.digest(signingInput), r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+/*
+	size returns the width in bytes of each of R and S for the curve
+*/
+func (e ECDSA) size() int {
+	return (e.publicKey.Curve.Params().BitSize + 7) / 8
+}
+
+/*
+	digest hashes the signing input with the hash paired with the curve
+*/
+func (e ECDSA) digest(signingInput string) []byte {
+	if e.alg == ES384 {
+		d := sha512.Sum384([]byte(signingInput))
+		return d[:]
+	}
+	d := sha256.Sum256([]byte(signingInput))
+	return d[:]
+}
@@ -0,0 +1,56 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+/*
+	Ed25519 signs and verifies tokens with the EdDSA algorithm (RFC 8037).
+	Signatures are small and deterministic, and keys are quick to generate,
+	so Ed25519 is the preferred asymmetric algorithm.
+*/
+type Ed25519 struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+/*
+	NewEd25519Signer returns an EdDSA Signer, which can also verify its own tokens.
+	Returns ErrInvalidKey if the key is the wrong size.
+*/
+func NewEd25519Signer(key ed25519.PrivateKey) (e Ed25519, err error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return Ed25519{}, fmt.Errorf("%w: Ed25519 private key is %d bytes, wanted %d", ErrInvalidKey, len(key), ed25519.PrivateKeySize)
+	}
+	return Ed25519{privateKey: key, publicKey: key.Public().(ed25519.PublicKey)}, nil
+}
+
+/*
+	NewEd25519Verifier returns an EdDSA Verifier for the public key.
+	Returns ErrInvalidKey if the key is the wrong size.
+*/
+func NewEd25519Verifier(key ed25519.PublicKey) (e Ed25519, err error) {
+	if len(key) != ed25519.PublicKeySize {
+		return Ed25519{}, fmt.Errorf("%w: Ed25519 public key is %d bytes, wanted %d", ErrInvalidKey, len(key), ed25519.PublicKeySize)
+	}
+	return Ed25519{publicKey: key}, nil
+}
+
+func (e Ed25519) Algorithm() string {
+	return EdDSA
+}
+
+func (e Ed25519) Sign(signingInput string) (signature []byte, err error) {
+	if e.privateKey == nil {
+		return nil, fmt.Errorf("%w: Ed25519 verifier has no private key", ErrInvalidKey)
+	}
+	return ed25519.Sign(e.privateKey, []byte(signingInput)), nil
+}
+
+func (e Ed25519) Verify(signingInput string, signature []byte) error {
+	if !ed25519.Verify(e.publicKey, []byte(signingInput), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -7,4 +7,16 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 	ErrExpiredToken = errors.New("token has expired")
 	ErrFailedSecret = errors.New("failed to retrieve secret")
+
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrInvalidKey           = errors.New("invalid key for signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid signature")
+
+	ErrInvalidIssuer   = errors.New("token has an unexpected issuer")
+	ErrInvalidAudience = errors.New("token is not intended for this audience")
+	ErrMissingClaim    = errors.New("token is missing a required claim")
+	ErrTokenTooOld     = errors.New("token is older than the maximum age")
+
+	ErrInvalidClaims = errors.New("invalid custom claims")
+	ErrReservedClaim = errors.New("custom claim name is already in use")
 )
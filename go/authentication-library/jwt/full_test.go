@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -12,7 +13,7 @@ func TestDecode(t *testing.T) {
 	// create a JWT using data previously parsed on the jwt.io website
 	j := jwtioStruct
 	validFor := j.ExpirationTime - j.IssuedAtTime
-	token := NewToken(j.Issuer, j.Audience, j.UserID, j.JwtID, j.KeyID, validFor)
+	token := NewToken(j.Issuer, j.Audience[0], j.UserID, j.JwtID, j.LegacyKeyID, validFor)
 	jwt, err := token.Create(jwtioSecret())
 	if err != nil {
 		t.Fail()
@@ -74,7 +75,7 @@ func TestDecode(t *testing.T) {
 		err := Decode(test[0], jwtioSecret(), &got)
 
 		// if the struct is not empty OR no errors
-		if (got.Payload != Payload{}) || err == nil {
+		if !reflect.DeepEqual(got.Payload, Payload{}) || err == nil {
 			t.Error("test ", i, ":\n", test[1])
 		}
 	}
@@ -1,3 +1,5 @@
 module github.com/markstanden/jwt
 
 go 1.16
+
+require golang.org/x/crypto v0.0.0-20210415154028-4f45737414dc
@@ -0,0 +1,23 @@
+package hash
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+)
+
+// HS256 uses HMAC Sha256 to hash the provided message using
+// the provided secret.
+func HS256(message, secret string) (hash []byte) {
+	hmac := hmac.New(sha256.New, []byte(secret))
+	hmac.Write([]byte(message))
+	return hmac.Sum(nil)
+}
+
+// HS384 uses HMAC Sha384 to hash the provided message using
+// the provided secret.
+func HS384(message, secret string) (hash []byte) {
+	hmac := hmac.New(sha512.New384, []byte(secret))
+	hmac.Write([]byte(message))
+	return hmac.Sum(nil)
+}
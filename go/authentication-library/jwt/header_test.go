@@ -0,0 +1,140 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+/*
+	keyedSecret returns a secret callback that only knows the secret for one key ID,
+	and records the key IDs it was asked for
+*/
+func keyedSecret(keyID string, requested *[]string) func(string) string {
+	return func(k string) string {
+		*requested = append(*requested, k)
+		if k == keyID {
+			return "secretcode"
+		}
+		return ""
+	}
+}
+
+func TestDecodeHeaderKeyID(t *testing.T) {
+	token := NewToken("iss", "aud", "sub", "jti", "key-1", 3600)
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	var requested []string
+	got := Token{Config: Config{Lifespan: 3600}}
+	if err := Decode(jwt, keyedSecret("key-1", &requested), &got); err != nil {
+		t.Fatalf("failed to decode token: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "key-1" || got.KeyID != "key-1" || got.LegacyKeyID != "" {
+		t.Errorf("incorrect key ID:\nWanted: key-1\nGot: %v, header %q, payload %q", requested, got.KeyID, got.LegacyKeyID)
+	}
+}
+
+/*
+	The jwt.io token was created with the key ID in the payload,
+	so is only accepted when AllowPayloadKeyID is set
+*/
+func TestDecodePayloadKeyID(t *testing.T) {
+	legacyKeyID := jwtioStruct.LegacyKeyID
+
+	tests := []struct {
+		desc      string
+		options   []DecodeOption
+		requested string
+		err       error
+	}{
+		{desc: "Header kid only", options: nil, requested: "", err: ErrFailedSecret},
+		{desc: "Compatibility mode", options: []DecodeOption{AllowPayloadKeyID()}, requested: legacyKeyID},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var requested []string
+			got := Token{Config: Config{ValidFrom: 1600000000, Lifespan: 100000000}}
+			err := Decode(jwtioToken, keyedSecret(legacyKeyID, &requested), &got, test.options...)
+			if test.err == nil && errors.Is(err, ErrExpiredToken) {
+				err = nil
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if len(requested) != 1 || requested[0] != test.requested {
+				t.Errorf("incorrect key ID requested:\nWanted: %q\nGot: %q", test.requested, requested)
+			}
+		})
+	}
+}
+
+func TestDecodeHeaderChecks(t *testing.T) {
+	tests := []struct {
+		desc    string
+		header  Header
+		options []DecodeOption
+		valid   bool
+	}{
+		{desc: "JWT", header: Header{TokenType: "JWT"}, valid: true},
+		{desc: "Access token", header: Header{TokenType: "at+jwt"}, valid: true},
+		{desc: "Access token media type", header: Header{TokenType: "application/AT+JWT"}, valid: true},
+		{desc: "Lower case jwt", header: Header{TokenType: "jwt"}, valid: true},
+		{desc: "Other type", header: Header{TokenType: "JOSE"}, valid: false},
+		{desc: "Missing type", header: Header{}, valid: false},
+		{desc: "Missing type allowed", header: Header{}, options: []DecodeOption{WithTypes("JWT", "")}, valid: true},
+		{desc: "Access tokens only", header: Header{TokenType: "JWT"}, options: []DecodeOption{WithTypes("at+jwt")}, valid: false},
+		{desc: "Content type", header: Header{TokenType: "JWT", ContentType: "application/json"}, valid: true},
+		{desc: "Nested token", header: Header{TokenType: "JWT", ContentType: "JWT"}, valid: false},
+		{desc: "Critical extension", header: Header{TokenType: "JWT", Critical: []string{"exp"}}, valid: false},
+		{desc: "Empty critical list", header: Header{TokenType: "JWT", Critical: []string{}}, valid: false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			token := NewToken("iss", "aud", "sub", "jti", "kid", 3600)
+			test.header.KeyID = "kid"
+			token.Header = test.header
+
+			jwt, err := token.Create(jwtioSecret())
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+
+			// Create omits an empty crit list, so sign the header directly
+			if test.header.Critical != nil && len(test.header.Critical) == 0 {
+				jwt = resign(t, `{"alg":"HS512","typ":"JWT","kid":"kid","crit":[]}`, jwt)
+			}
+
+			got := Token{Config: Config{Lifespan: 3600}}
+			err = Decode(jwt, jwtioSecret(), &got, test.options...)
+			if test.valid && err != nil {
+				t.Errorf("failed to decode valid token: %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", ErrInvalidToken, err)
+			}
+		})
+	}
+}
+
+/*
+	resign replaces the header of the jwt, and signs it again using HS512
+*/
+func resign(t *testing.T, header, jwt string) string {
+	sections := strings.Split(jwt, ".")
+	body := b64.FromBytes([]byte(header)) + "." + sections[1]
+
+	signer, err := NewHMAC(HS512, "secretcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := signer.Sign(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body + "." + b64.FromBytes(signature)
+}
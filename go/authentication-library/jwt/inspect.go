@@ -0,0 +1,74 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+/*
+	UnverifiedToken is the header and payload of a JWT decoded by Inspect.
+	NONE of its contents have been checked, and may have been forged.
+*/
+type UnverifiedToken struct {
+	Header  Header
+	Payload Payload
+
+	// Claims holds every claim in the payload, including custom claims.
+	// Numbers are held as json.Number, so times are not rounded.
+	Claims map[string]interface{}
+
+	// RawHeader and RawPayload are the decoded JSON sections of the token
+	RawHeader  []byte
+	RawPayload []byte
+
+	// Signature is the decoded signature, which has not been checked
+	Signature []byte
+}
+
+/*
+	Inspect decodes the header and payload of a JWT WITHOUT checking the
+	signature, or any of the claims.
+
+	UNSAFE: anyone can create a token with any contents, so the result must
+	never be used to authenticate or authorise a request, use Decode.
+	Inspect is for debugging rejected tokens, without pasting them into
+	a third party website.
+
+	The token is decoded as far as possible, so if the header or payload
+	do not match the registered types, the token is returned with the error.
+	Tokens with an empty signature, such as "alg": "none", are decoded.
+*/
+func Inspect(untrustedJWT string) (token UnverifiedToken, err error) {
+
+	sections := strings.Split(untrustedJWT, ".")
+	if len(sections) != 3 {
+		return UnverifiedToken{}, invalid(ReasonMalformed, "")
+	}
+
+	if token.RawHeader, err = b64.ToBytes(sections[0]); err != nil {
+		return UnverifiedToken{}, invalid(ReasonMalformed, "")
+	}
+	if token.RawPayload, err = b64.ToBytes(sections[1]); err != nil {
+		return UnverifiedToken{}, invalid(ReasonMalformed, "")
+	}
+	if token.Signature, err = b64.ToBytes(sections[2]); err != nil {
+		return UnverifiedToken{}, invalid(ReasonMalformed, "")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(token.RawPayload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&token.Claims); err != nil {
+		return UnverifiedToken{}, invalid(ReasonMalformed, "")
+	}
+
+	if err := json.Unmarshal(token.RawHeader, &token.Header); err != nil {
+		return token, invalid(ReasonBadHeader, "")
+	}
+	if err := json.Unmarshal(token.RawPayload, &token.Payload); err != nil {
+		return token, invalid(ReasonMalformed, "")
+	}
+	return token, nil
+}
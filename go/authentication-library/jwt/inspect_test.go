@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+func TestInspect(t *testing.T) {
+	got, err := Inspect(jwtioToken)
+	if err != nil {
+		t.Fatalf("failed to inspect token: %v", err)
+	}
+
+	want := Header{Algorithm: HS512, TokenType: "JWT"}
+	if !reflect.DeepEqual(got.Header, want) {
+		t.Errorf("incorrect header:\nWanted: %+v\nGot: %+v", want, got.Header)
+	}
+	if !reflect.DeepEqual(got.Payload, jwtioStruct) {
+		t.Errorf("incorrect payload:\nWanted: %+v\nGot: %+v", jwtioStruct, got.Payload)
+	}
+	if got.Claims["exp"] != json.Number("1650000000") || got.Claims["sub"] != "1234567890" {
+		t.Errorf("incorrect claims: %v", got.Claims)
+	}
+	if len(got.Signature) != 64 {
+		t.Errorf("incorrect signature length:\nWanted: 64\nGot: %v", len(got.Signature))
+	}
+}
+
+/*
+	Inspect does not check the signature, or the claims, so shows forged and invalid tokens
+*/
+func TestInspectUnverified(t *testing.T) {
+	header := b64.FromBytes([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	tests := []struct {
+		desc   string
+		jwt    string
+		reason Reason
+		sub    interface{}
+	}{
+		{desc: "Unsigned", jwt: header + "." + b64.FromBytes([]byte(`{"sub":"admin","exp":1}`)) + ".", sub: "admin"},
+		{desc: "Invalid signature", jwt: jwtioToken[:len(jwtioToken)-4] + "AAAA", sub: "1234567890"},
+		{desc: "Wrong claim type", jwt: header + "." + b64.FromBytes([]byte(`{"sub":"admin","exp":"tomorrow"}`)) + ".", reason: ReasonMalformed, sub: "admin"},
+		{desc: "Two sections", jwt: header + "." + header, reason: ReasonMalformed},
+		{desc: "Not JSON", jwt: header + "." + header[1:] + ".", reason: ReasonMalformed},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := Inspect(test.jwt)
+			if test.reason == "" && err != nil {
+				t.Fatalf("failed to inspect token: %v", err)
+			}
+			if test.reason != "" && !errors.Is(err, &ValidationError{Reason: test.reason}) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.reason, err)
+			}
+			if got.Claims["sub"] != test.sub {
+				t.Errorf("incorrect subject:\nWanted: %v\nGot: %v", test.sub, got.Claims["sub"])
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+/*
+	JWE key management and content encryption algorithm names,
+	as used in the "alg" and "enc" header fields (RFC 7518)
+*/
+const (
+	// Dir encrypts the content directly with the key derived from the secret
+	Dir = "dir"
+
+	// A256KW encrypts the content with a random key, wrapped by the key derived from the secret
+	A256KW = "A256KW"
+
+	// A256GCM is AES-256 in Galois/Counter Mode
+	A256GCM = "A256GCM"
+)
+
+/*
+	JWEHeader is the protected header of an encrypted token (RFC 7516)
+*/
+type JWEHeader struct {
+	// Algorithm - "alg" - The key management algorithm, Dir or A256KW
+	Algorithm string `json:"alg"`
+
+	// Encryption - "enc" - The content encryption algorithm, A256GCM
+	// This is set automatically if left empty
+	Encryption string `json:"enc"`
+
+	// KeyID - "kid" - Key ID
+	// The version of the secret the encryption key is derived from
+	KeyID string `json:"kid,omitempty"`
+
+	// TokenType - "typ" - The type of token
+	TokenType string `json:"typ,omitempty"`
+
+	// ContentType - "cty" - The type of the encrypted content, "JWT" for a nested signed token
+	ContentType string `json:"cty,omitempty"`
+
+	// Compression - "zip" - Compression is not supported, so tokens with a "zip" are rejected
+	Compression string `json:"zip,omitempty"`
+
+	// Critical - "crit" - No extensions are supported, so tokens with "crit" are rejected
+	Critical []string `json:"crit,omitempty"`
+}
+
+/*
+	CreateEncrypted creates a nested JWT, signed with Create and then encrypted
+	using the key management algorithm keyManagement (Dir or A256KW) and A256GCM.
+	The signing and encryption keys are both obtained by calling the callback with the token's KeyID.
+*/
+func (t *Token) CreateEncrypted(getRemoteSecret func(keyID string) string, keyManagement string) (jwe string, err error) {
+	jwt, err := t.Create(getRemoteSecret)
+	if err != nil {
+		return "", err
+	}
+
+	header := JWEHeader{
+		Algorithm:   keyManagement,
+		KeyID:       t.signingKeyID(),
+		TokenType:   "JWT",
+		ContentType: "JWT",
+	}
+	return Encrypt([]byte(jwt), header, getRemoteSecret)
+}
+
+/*
+	DecodeEncrypted decrypts a nested JWT created by CreateEncrypted,
+	and decodes the signed token within, as Decode.
+	Both the encryption and signing keys are obtained from the passwordLookup callback.
+	The token must have a "cty" of "JWT".
+*/
+func DecodeEncrypted(untrustedJWE string, passwordLookup func(keyID string) (secret string), token *Token, options ...DecodeOption) (err error) {
+	plaintext, header, err := Decrypt(untrustedJWE, passwordLookup)
+	if err != nil {
+		return err
+	}
+
+	if mediaType(header.ContentType) != "jwt" {
+		return invalid(ReasonBadHeader, "cty")
+	}
+	return Decode(string(plaintext), passwordLookup, token, options...)
+}
+
+/*
+	Encrypt returns the JWE compact serialization of the plaintext (RFC 7516).
+	The header's Algorithm must be Dir or A256KW, and the Encryption A256GCM, which is set if empty.
+	The encryption key is derived from the secret returned by the callback for the header's KeyID,
+	so the same secret can be used to sign and encrypt a token.
+*/
+func Encrypt(plaintext []byte, header JWEHeader, getRemoteSecret func(keyID string) string) (jwe string, err error) {
+
+	if header.Encryption == "" {
+		header.Encryption = A256GCM
+	}
+	if header.Encryption != A256GCM {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Encryption)
+	}
+	if header.Algorithm != Dir && header.Algorithm != A256KW {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, header.Algorithm)
+	}
+
+	secret := getRemoteSecret(header.KeyID)
+	if secret == "" {
+		return "", ErrFailedSecret
+	}
+	key := encryptionKey(secret, header.Algorithm)
+
+	/*
+		dir uses the key as the content encryption key,
+		A256KW generates a new content encryption key and wraps it with the key
+	*/
+	cek := key
+	var encryptedKey []byte
+	if header.Algorithm == A256KW {
+		cek = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, cek); err != nil {
+			return "", err
+		}
+		encryptedKey, err = aesKeyWrap(key, cek)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	jsonHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := b64.FromBytes(jsonHeader)
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return "", err
+	}
+
+	/*
+		the protected header is authenticated as the additional data,
+		and the authentication tag is appended to the sealed ciphertext
+	*/
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	return strings.Join([]string{
+		protected,
+		b64.FromBytes(encryptedKey),
+		b64.FromBytes(iv),
+		b64.FromBytes(ciphertext),
+		b64.FromBytes(tag),
+	}, "."), nil
+}
+
+/*
+	Decrypt checks and decrypts a JWE compact serialization created by Encrypt,
+	returning the plaintext and the protected header.
+	The decryption key is derived from the secret returned by the callback for the header's KeyID.
+	Errors are returned as a *ValidationError, as Decode.
+*/
+func Decrypt(untrustedJWE string, passwordLookup func(keyID string) (secret string)) (plaintext []byte, header JWEHeader, err error) {
+
+	/*
+		A JWE has five sections, the protected header, encrypted key,
+		initialization vector, ciphertext and authentication tag
+	*/
+	sections := strings.Split(untrustedJWE, ".")
+	if len(sections) != 5 {
+		return nil, JWEHeader{}, invalid(ReasonMalformed, "")
+	}
+
+	decoded := make([][]byte, len(sections))
+	for i, section := range sections {
+		if decoded[i], err = b64.ToBytes(section); err != nil {
+			return nil, JWEHeader{}, invalid(ReasonMalformed, "")
+		}
+	}
+	encryptedKey, iv, ciphertext, tag := decoded[1], decoded[2], decoded[3], decoded[4]
+
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, JWEHeader{}, invalid(ReasonMalformed, "")
+	}
+	if err := checkJWEHeaderValid(header); err != nil {
+		return nil, JWEHeader{}, err
+	}
+
+	if passwordLookup == nil {
+		return nil, JWEHeader{}, &ValidationError{Reason: ReasonUnknownKeyID, Claim: "kid", Err: ErrFailedSecret}
+	}
+	secret := passwordLookup(header.KeyID)
+	if secret == "" {
+		return nil, JWEHeader{}, &ValidationError{Reason: ReasonUnknownKeyID, Claim: "kid", Err: ErrFailedSecret}
+	}
+	key := encryptionKey(secret, header.Algorithm)
+
+	cek := key
+	switch header.Algorithm {
+	case Dir:
+		/* the encrypted key must be empty when using direct encryption */
+		if len(encryptedKey) != 0 {
+			return nil, JWEHeader{}, invalid(ReasonMalformed, "")
+		}
+	case A256KW:
+		cek, err = aesKeyUnwrap(key, encryptedKey)
+		if err != nil || len(cek) != 32 {
+			return nil, JWEHeader{}, invalid(ReasonDecryptionFailed, "")
+		}
+	}
+
+	gcm, err := newGCM(cek)
+	if err != nil {
+		return nil, JWEHeader{}, invalid(ReasonDecryptionFailed, "")
+	}
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, JWEHeader{}, invalid(ReasonMalformed, "")
+	}
+
+	plaintext, err = gcm.Open(nil, iv, append(ciphertext, tag...), []byte(sections[0]))
+	if err != nil {
+		return nil, JWEHeader{}, invalid(ReasonDecryptionFailed, "")
+	}
+	return plaintext, header, nil
+}
+
+/*
+	checkJWEHeaderValid checks the JWE header uses the supported algorithms,
+	and no compression or extensions
+*/
+func checkJWEHeaderValid(h JWEHeader) error {
+	if h.Algorithm != Dir && h.Algorithm != A256KW {
+		return invalid(ReasonBadHeader, "alg")
+	}
+	if h.Encryption != A256GCM {
+		return invalid(ReasonBadHeader, "enc")
+	}
+	if h.Compression != "" {
+		return invalid(ReasonBadHeader, "zip")
+	}
+	if h.Critical != nil {
+		return invalid(ReasonBadHeader, "crit")
+	}
+	return nil
+}
+
+/*
+	encryptionKey derives the 256 bit key for the key management algorithm from the secret.
+	Each algorithm has its own key, which differs from the HMAC signing key.
+*/
+func encryptionKey(secret, algorithm string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("JWE " + algorithm))
+	return mac.Sum(nil)
+}
+
+/*
+	newGCM returns AES-GCM for the 256 bit content encryption key
+*/
+func newGCM(cek []byte) (cipher.AEAD, error) {
+	if len(cek) != 32 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
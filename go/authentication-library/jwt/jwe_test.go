@@ -0,0 +1,238 @@
+package jwt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+// RFC 3394 section 4 test vectors
+func TestAESKeyWrap(t *testing.T) {
+	tests := []struct {
+		desc    string
+		kek     string
+		key     string
+		wrapped string
+	}{
+		{
+			desc:    "128 bit key with 128 bit KEK",
+			kek:     "000102030405060708090A0B0C0D0E0F",
+			key:     "00112233445566778899AABBCCDDEEFF",
+			wrapped: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			desc:    "256 bit key with 256 bit KEK",
+			kek:     "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			key:     "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			wrapped: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			kek, _ := hex.DecodeString(test.kek)
+			key, _ := hex.DecodeString(test.key)
+			want, _ := hex.DecodeString(test.wrapped)
+
+			got, err := aesKeyWrap(kek, key)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Fatalf("incorrect wrapped key:\nWanted: %X\nGot: %X (%v)", want, got, err)
+			}
+
+			unwrapped, err := aesKeyUnwrap(kek, got)
+			if err != nil || !bytes.Equal(unwrapped, key) {
+				t.Fatalf("incorrect unwrapped key:\nWanted: %X\nGot: %X (%v)", key, unwrapped, err)
+			}
+
+			got[len(got)-1] ^= 1
+			if _, err := aesKeyUnwrap(kek, got); !errors.Is(err, errKeyUnwrap) {
+				t.Errorf("altered key unwrapped:\nWanted: %v\nGot: %v", errKeyUnwrap, err)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	plaintext := []byte(`{"email":"user@example.com","tenant":"acme"}`)
+
+	for _, alg := range []string{Dir, A256KW} {
+		t.Run(alg, func(t *testing.T) {
+			var requested []string
+			jwe, err := Encrypt(plaintext, JWEHeader{Algorithm: alg, KeyID: "key-1"}, jwtioSecret())
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+
+			sections := strings.Split(jwe, ".")
+			if len(sections) != 5 {
+				t.Fatalf("incorrect number of sections:\nWanted: 5\nGot: %v", len(sections))
+			}
+			if (alg == Dir) != (sections[1] == "") {
+				t.Errorf("incorrect encrypted key for %v: %q", alg, sections[1])
+			}
+			if strings.Contains(jwe, b64.FromBytes(plaintext)) {
+				t.Errorf("plaintext visible in token")
+			}
+
+			got, header, err := Decrypt(jwe, keyedSecret("key-1", &requested))
+			if err != nil {
+				t.Fatalf("failed to decrypt: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("incorrect plaintext:\nWanted: %s\nGot: %s", plaintext, got)
+			}
+			want := JWEHeader{Algorithm: alg, Encryption: A256GCM, KeyID: "key-1"}
+			if !reflect.DeepEqual(header, want) || !reflect.DeepEqual(requested, []string{"key-1"}) {
+				t.Errorf("incorrect header:\nWanted: %+v\nGot: %+v, requested %v", want, header, requested)
+			}
+
+			again, err := Encrypt(plaintext, JWEHeader{Algorithm: alg, KeyID: "key-1"}, jwtioSecret())
+			if err != nil || again == jwe {
+				t.Errorf("encrypting twice produced the same token: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptErrors(t *testing.T) {
+	tests := []struct {
+		desc   string
+		header JWEHeader
+		secret func(string) string
+		err    error
+	}{
+		{desc: "Unsupported key management", header: JWEHeader{Algorithm: "RSA-OAEP"}, err: ErrUnsupportedAlgorithm},
+		{desc: "Signing algorithm", header: JWEHeader{Algorithm: HS512}, err: ErrUnsupportedAlgorithm},
+		{desc: "Unsupported encryption", header: JWEHeader{Algorithm: Dir, Encryption: "A128CBC-HS256"}, err: ErrUnsupportedAlgorithm},
+		{desc: "Missing secret", header: JWEHeader{Algorithm: Dir}, secret: func(string) string { return "" }, err: ErrFailedSecret},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.secret == nil {
+				test.secret = jwtioSecret()
+			}
+			if _, err := Encrypt([]byte("plaintext"), test.header, test.secret); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	jwe, err := Encrypt([]byte("plaintext"), JWEHeader{Algorithm: A256KW, KeyID: "kid"}, jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	sections := strings.Split(jwe, ".")
+
+	// withHeader replaces the protected header, which invalidates the tag unless the header is rejected first
+	withHeader := func(header string) string {
+		return b64.FromBytes([]byte(header)) + "." + strings.Join(sections[1:], ".")
+	}
+	// altered flips a bit in one of the sections
+	altered := func(i int) string {
+		s := append([]string{}, sections...)
+		bs, _ := b64.ToBytes(s[i])
+		bs[0] ^= 1
+		s[i] = b64.FromBytes(bs)
+		return strings.Join(s, ".")
+	}
+
+	tests := []struct {
+		desc   string
+		jwe    string
+		secret func(string) string
+		reason Reason
+		claim  string
+		err    error
+	}{
+		{desc: "Signed token", jwe: jwtioToken, reason: ReasonMalformed, err: ErrInvalidToken},
+		{desc: "Invalid base64", jwe: jwe + "!", reason: ReasonMalformed, err: ErrInvalidToken},
+		{desc: "Key management none", jwe: withHeader(`{"alg":"none","enc":"A256GCM","kid":"kid"}`), reason: ReasonBadHeader, claim: "alg", err: ErrInvalidToken},
+		{desc: "Unsupported encryption", jwe: withHeader(`{"alg":"A256KW","enc":"A128GCM","kid":"kid"}`), reason: ReasonBadHeader, claim: "enc", err: ErrInvalidToken},
+		{desc: "Compressed", jwe: withHeader(`{"alg":"A256KW","enc":"A256GCM","kid":"kid","zip":"DEF"}`), reason: ReasonBadHeader, claim: "zip", err: ErrInvalidToken},
+		{desc: "Critical extension", jwe: withHeader(`{"alg":"A256KW","enc":"A256GCM","kid":"kid","crit":["exp"]}`), reason: ReasonBadHeader, claim: "crit", err: ErrInvalidToken},
+		{desc: "Altered header", jwe: withHeader(`{"alg":"A256KW","enc":"A256GCM","kid":"kid","typ":"JWT"}`), reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Dir with an encrypted key", jwe: withHeader(`{"alg":"dir","enc":"A256GCM","kid":"kid"}`), reason: ReasonMalformed, err: ErrInvalidToken},
+		{desc: "Altered encrypted key", jwe: altered(1), reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Altered iv", jwe: altered(2), reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Altered ciphertext", jwe: altered(3), reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Altered tag", jwe: altered(4), reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Wrong secret", jwe: jwe, secret: func(string) string { return "othersecret" }, reason: ReasonDecryptionFailed, err: ErrInvalidToken},
+		{desc: "Unknown kid", jwe: jwe, secret: func(string) string { return "" }, reason: ReasonUnknownKeyID, claim: "kid", err: ErrFailedSecret},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.secret == nil {
+				test.secret = jwtioSecret()
+			}
+			plaintext, _, err := Decrypt(test.jwe, test.secret)
+			if plaintext != nil {
+				t.Errorf("plaintext returned for an invalid token: %s", plaintext)
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("not a ValidationError:\nWanted: %v\nGot: %v", test.reason, err)
+			}
+			if ve.Reason != test.reason || ve.Claim != test.claim {
+				t.Errorf("incorrect reason:\nWanted: %v %q\nGot: %v %q", test.reason, test.claim, ve.Reason, ve.Claim)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("incorrect underlying error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestNestedToken(t *testing.T) {
+	for _, alg := range []string{Dir, A256KW} {
+		t.Run(alg, func(t *testing.T) {
+			token := NewToken("iss", "aud", "sub", "jti", "key-1", 3600)
+			token.Extra = map[string]interface{}{"email": "user@example.com"}
+
+			jwe, err := token.CreateEncrypted(jwtioSecret(), alg)
+			if err != nil {
+				t.Fatalf("failed to create token: %v", err)
+			}
+			headerBytes, _ := b64.ToBytes(strings.Split(jwe, ".")[0])
+			var header JWEHeader
+			if err := json.Unmarshal(headerBytes, &header); err != nil || header.ContentType != "JWT" || header.KeyID != "key-1" {
+				t.Errorf("incorrect JWE header: %s", headerBytes)
+			}
+
+			var requested []string
+			got := Token{Config: Config{Lifespan: 3600}}
+			if err := DecodeEncrypted(jwe, keyedSecret("key-1", &requested), &got, WithAudience("aud")); err != nil {
+				t.Fatalf("failed to decode token: %v", err)
+			}
+			if got.UserID != "sub" || got.Extra["email"] != "user@example.com" {
+				t.Errorf("incorrect payload: %+v, %v", got.Payload, got.Extra)
+			}
+			if !reflect.DeepEqual(requested, []string{"key-1", "key-1"}) {
+				t.Errorf("incorrect key IDs requested:\nWanted: [key-1 key-1]\nGot: %v", requested)
+			}
+
+			// the signed token's checks still apply
+			err = DecodeEncrypted(jwe, jwtioSecret(), &Token{Config: Config{Lifespan: 3600}}, WithAudience("other"))
+			if !errors.Is(err, ErrInvalidAudience) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", ErrInvalidAudience, err)
+			}
+		})
+	}
+
+	// the content of an encrypted token must be a signed token
+	jwe, err := Encrypt([]byte(jwtioToken), JWEHeader{Algorithm: Dir, KeyID: "kid"}, jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	err = DecodeEncrypted(jwe, jwtioSecret(), &Token{})
+	if !errors.Is(err, &ValidationError{Reason: ReasonBadHeader, Claim: "cty"}) {
+		t.Errorf("unexpected error:\nWanted: %v\nGot: %v", ReasonBadHeader, err)
+	}
+}
@@ -0,0 +1,239 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found in key set")
+	ErrInvalidJWKS = errors.New("invalid JWK set")
+)
+
+/*
+	JWK is a JSON Web Key (RFC 7517) holding a public key used to verify tokens.
+	Only public keys are represented, so a JWK can always be published safely.
+*/
+type JWK struct {
+	// KeyType - "kty" - "RSA", "EC" or "OKP" (RFC 8037)
+	KeyType string `json:"kty"`
+
+	// KeyID - "kid" - matches the "kid" of the tokens signed by the key
+	KeyID string `json:"kid,omitempty"`
+
+	// Use - "use" - "sig" for keys used to verify signatures
+	Use string `json:"use,omitempty"`
+
+	// Algorithm - "alg" - the algorithm the key is used with
+	Algorithm string `json:"alg,omitempty"`
+
+	// Curve - "crv" - the curve of an "EC" or "OKP" key
+	Curve string `json:"crv,omitempty"`
+
+	// RSA modulus and exponent, base64 URL encoded big endian integers
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Elliptic curve point, base64 URL encoded.  Only X is used for an "OKP" key
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+}
+
+/*
+	JWKS is a JSON Web Key Set, the document published to allow
+	other services to verify our tokens
+*/
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+/*
+	NewJWK returns the JWK for a public key, to be published for the algorithm.
+	publicKey must be an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey,
+	and suitable for the algorithm.
+*/
+func NewJWK(keyID, alg string, publicKey crypto.PublicKey) (k JWK, err error) {
+	k = JWK{KeyID: keyID, Use: "sig", Algorithm: alg}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if _, err := NewRSAVerifier(alg, key); err != nil {
+			return JWK{}, err
+		}
+		k.KeyType = "RSA"
+		k.N = b64.FromBytes(key.N.Bytes())
+		k.E = b64.FromBytes(big.NewInt(int64(key.E)).Bytes())
+
+	case *ecdsa.PublicKey:
+		e, err := NewECDSAVerifier(alg, key)
+		if err != nil {
+			return JWK{}, err
+		}
+		size := e.size()
+		k.KeyType = "EC"
+		k.Curve = key.Curve.Params().Name
+		k.X = b64.FromBytes(key.X.FillBytes(make([]byte, size)))
+		k.Y = b64.FromBytes(key.Y.FillBytes(make([]byte, size)))
+
+	case ed25519.PublicKey:
+		if alg != EdDSA {
+			return JWK{}, fmt.Errorf("%w: %q is not an Ed25519 algorithm", ErrUnsupportedAlgorithm, alg)
+		}
+		if _, err := NewEd25519Verifier(key); err != nil {
+			return JWK{}, err
+		}
+		k.KeyType = "OKP"
+		k.Curve = "Ed25519"
+		k.X = b64.FromBytes(key)
+
+	default:
+		return JWK{}, fmt.Errorf("%w: %T is not a supported public key", ErrInvalidKey, publicKey)
+	}
+	return k, nil
+}
+
+/*
+	PublicKey returns the public key held by the JWK,
+	an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey
+*/
+func (k JWK) PublicKey() (publicKey crypto.PublicKey, err error) {
+	switch k.KeyType {
+	case "RSA":
+		n, errN := b64.ToBytes(k.N)
+		e, errE := b64.ToBytes(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: malformed RSA key %q", ErrInvalidKey, k.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidKey, k.Curve)
+		}
+		x, errX := b64.ToBytes(k.X)
+		y, errY := b64.ToBytes(k.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("%w: malformed EC key %q", ErrInvalidKey, k.KeyID)
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: EC key %q is not on the curve", ErrInvalidKey, k.KeyID)
+		}
+		return key, nil
+
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidKey, k.Curve)
+		}
+		x, err := b64.ToBytes(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: malformed Ed25519 key %q", ErrInvalidKey, k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidKey, k.KeyType)
+}
+
+/*
+	Verifier returns a Verifier using the JWK's public key for the algorithm.
+	If the JWK specifies an algorithm it must match.
+*/
+func (k JWK) Verifier(alg string) (v Verifier, err error) {
+	if k.Algorithm != "" && k.Algorithm != alg {
+		return nil, fmt.Errorf("%w: key %q is for %v, not %v", ErrInvalidKey, k.KeyID, k.Algorithm, alg)
+	}
+
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return NewRSAVerifier(alg, key)
+	case *ecdsa.PublicKey:
+		return NewECDSAVerifier(alg, key)
+	case ed25519.PublicKey:
+		if alg != EdDSA {
+			return nil, fmt.Errorf("%w: %q is not an Ed25519 algorithm", ErrUnsupportedAlgorithm, alg)
+		}
+		return NewEd25519Verifier(key)
+	}
+	return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidKey, k.KeyType)
+}
+
+/*
+	KeySet holds the signature keys of a JWK Set, by key ID.
+	Its Lookup method is a KeyLookup, so a KeySet can be passed to Decode using WithKeyLookup.
+*/
+type KeySet struct {
+	keys map[string]JWK
+}
+
+/*
+	ParseJWKS parses a JWK Set document into a KeySet.
+	Keys that are not for signatures, or are of an unsupported type,
+	are ignored as RFC 7517 requires.  Returns ErrInvalidJWKS if the document
+	is malformed, or two signature keys share a key ID.
+*/
+func ParseJWKS(document []byte) (ks KeySet, err error) {
+	var set JWKS
+	if err := json.Unmarshal(document, &set); err != nil {
+		return KeySet{}, fmt.Errorf("%w: %v", ErrInvalidJWKS, err)
+	}
+	return NewKeySet(set)
+}
+
+/*
+	NewKeySet returns a KeySet of the signature keys of the JWK Set
+*/
+func NewKeySet(set JWKS) (ks KeySet, err error) {
+	ks = KeySet{keys: make(map[string]JWK)}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if _, err := k.PublicKey(); err != nil {
+			continue
+		}
+		if _, ok := ks.keys[k.KeyID]; ok {
+			return KeySet{}, fmt.Errorf("%w: duplicate key ID %q", ErrInvalidJWKS, k.KeyID)
+		}
+		ks.keys[k.KeyID] = k
+	}
+	return ks, nil
+}
+
+/*
+	Len returns the number of keys in the KeySet
+*/
+func (ks KeySet) Len() int {
+	return len(ks.keys)
+}
+
+/*
+	Lookup returns a Verifier for the key with the key ID, for the algorithm.
+	Returns ErrKeyNotFound if the key set has no key with the ID.
+*/
+func (ks KeySet) Lookup(keyID, alg string) (v Verifier, err error) {
+	k, ok := ks.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, keyID)
+	}
+	return k.Verifier(alg)
+}
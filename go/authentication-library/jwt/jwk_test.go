@@ -0,0 +1,129 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+/*
+	RFC 8037 Appendix A.2, the public key for the A.4 signing key
+*/
+func TestNewJWKEd25519Vector(t *testing.T) {
+	seed, _ := b64.ToBytes("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	key := ed25519.NewKeyFromSeed(seed)
+
+	k, err := NewJWK("rfc8037", EdDSA, key.Public())
+	if err != nil {
+		t.Fatalf("failed to create JWK: %v", err)
+	}
+	want := JWK{KeyType: "OKP", KeyID: "rfc8037", Use: "sig", Algorithm: EdDSA, Curve: "Ed25519", X: "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}
+	if k != want {
+		t.Errorf("incorrect JWK:\nWanted: %+v\nGot: %+v", want, k)
+	}
+}
+
+func TestJWKSRoundTrip(t *testing.T) {
+	var set JWKS
+	signers := make(map[string]Signer)
+
+	for alg, pair := range testSigners(t) {
+		signer := pair[0].(Signer)
+
+		var publicKey interface{}
+		switch s := signer.(type) {
+		case RSA:
+			publicKey = s.publicKey
+		case ECDSA:
+			publicKey = s.publicKey
+		case Ed25519:
+			publicKey = s.publicKey
+		default:
+			/* symmetric keys are never published */
+			if _, err := NewJWK(alg, alg, []byte("secretcode")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("created a JWK for an HMAC secret:\nWanted: %v\nGot: %v", ErrInvalidKey, err)
+			}
+			continue
+		}
+
+		k, err := NewJWK("key-"+alg, alg, publicKey)
+		if err != nil {
+			t.Fatalf("NewJWK(%v): %v", alg, err)
+		}
+		set.Keys = append(set.Keys, k)
+		signers[alg] = signer
+	}
+
+	document, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+	ks, err := ParseJWKS(document)
+	if err != nil {
+		t.Fatalf("failed to parse JWKS: %v", err)
+	}
+	if ks.Len() != len(signers) {
+		t.Fatalf("incorrect number of keys:\nWanted: %d\nGot: %d", len(signers), ks.Len())
+	}
+
+	for alg, signer := range signers {
+		t.Run(alg, func(t *testing.T) {
+			token := NewToken("iss", "aud", "sub", "jti", "key-"+alg, 3600)
+			jwt, err := token.Sign(signer)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			got := Token{Config: Config{Lifespan: 3600}}
+			if err := Decode(jwt, nil, &got, WithAlgorithms(alg), WithKeyLookup(ks.Lookup)); err != nil {
+				t.Errorf("failed to decode token using the key set: %v", err)
+			}
+
+			// a token claiming another key's ID must fail, as the key is for a different algorithm
+			for other := range signers {
+				if other == alg {
+					continue
+				}
+				if _, err := ks.Lookup("key-"+other, alg); err == nil {
+					t.Errorf("key for %v returned for %v", other, alg)
+				}
+			}
+		})
+	}
+
+	if _, err := ks.Lookup("missing", EdDSA); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("unexpected error for a missing key:\nWanted: %v\nGot: %v", ErrKeyNotFound, err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		desc     string
+		document string
+		keys     int
+		err      error
+	}{
+		{desc: "Empty set", document: `{"keys":[]}`, keys: 0},
+		{desc: "Single key", document: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"a","use":"sig","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`, keys: 1},
+		{desc: "Encryption key ignored", document: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"a","use":"enc","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`, keys: 0},
+		{desc: "Symmetric key ignored", document: `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`, keys: 0},
+		{desc: "Malformed key ignored", document: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"a","x":"short"}]}`, keys: 0},
+		{desc: "EC point off the curve ignored", document: `{"keys":[{"kty":"EC","crv":"P-256","kid":"a","x":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA","y":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAE"}]}`, keys: 0},
+		{desc: "Duplicate key ID", document: `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"a","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},{"kty":"OKP","crv":"Ed25519","kid":"a","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`, err: ErrInvalidJWKS},
+		{desc: "Not JSON", document: `keys`, err: ErrInvalidJWKS},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			ks, err := ParseJWKS([]byte(test.document))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if ks.Len() != test.keys {
+				t.Errorf("incorrect number of keys:\nWanted: %d\nGot: %d", test.keys, ks.Len())
+			}
+		})
+	}
+}
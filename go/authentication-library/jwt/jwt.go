@@ -2,14 +2,32 @@ package jwt
 
 /*
 	Token is the struct that holds all of the data to be written to the JWT
-	Header is an embedded struct containing the header section of the JWT (alg, typ)
-	Payload is an embedded struct containing the indentifying information of issuer (iss), user (sub), jwt (jti), and secret key (kid)
+	Header is an embedded struct containing the header section of the JWT (alg, typ, kid)
+	Payload is an embedded struct containing the indentifying information of issuer (iss), user (sub) and jwt (jti)
+	Claims and Extra hold any custom (private) claims, such as roles or scopes
+	The header and payload are marshalled separately when creating a token, and are tagged so
+	their fields, both of which may include a "kid", are kept apart if the Token itself is marshalled.
 */
 type Token struct {
-	Header
-	Payload
+	Header  `json:"header"`
+	Payload `json:"payload"`
 	Config
-	Log Log
+
+	/*
+		Claims is an optional pointer to a struct of custom claims, i.e. &MyClaims{Roles: roles}
+		Its JSON fields are added to the payload alongside the registered claims when the token is created,
+		and to decode a token's custom claims, set Claims to a pointer to an empty struct before calling Decode.
+		Custom claims must not use the registered claim names (iss, aud, sub, jti, kid, iat, nbf, exp).
+	*/
+	Claims interface{} `json:"-"`
+
+	/*
+		Extra holds custom claims without a struct.
+		When creating a token its values are added to the payload, and must not repeat a claim in Claims.
+		When decoding, Extra holds every claim that is not a registered claim,
+		including those also decoded into Claims.
+	*/
+	Extra map[string]interface{} `json:"-"`
 }
 
 /*
@@ -19,12 +37,27 @@ type Token struct {
 */
 type Header struct {
 	// Algorithm - "alg" - The encoding algorithm used to sign the token
-	// This is "HS512" and is set automatically
+	// This is set automatically to the algorithm of the Signer, "HS512" for Create
 	Algorithm string `json:"alg"`
 
 	// TokenType - "typ" - The type of token to be produced
-	// This is set to "JWT" automatically
+	// This is set to "JWT" automatically, or "at+jwt" for OAuth 2.0 access tokens (RFC 9068)
 	TokenType string `json:"typ"`
+
+	// KeyID - "kid" - Key ID
+	// The version of the secret, or the key, used to sign the token.
+	// Decode passes it to the secret callback or KeyLookup to find the key.
+	KeyID string `json:"kid,omitempty"`
+
+	// ContentType - "cty" - Content Type
+	// A signed token must not itself have a "cty" of "JWT", so such tokens are rejected.
+	// Nested, signed then encrypted, tokens use the JWE header, see CreateEncrypted and DecodeEncrypted.
+	ContentType string `json:"cty,omitempty"`
+
+	// Critical - "crit" - Critical header parameters
+	// The extensions a recipient must understand to process the token.
+	// No extensions are supported, so tokens with "crit" are rejected, as RFC 7515 requires.
+	Critical []string `json:"crit,omitempty"`
 }
 
 // Payload contains the data stored within the JWT
@@ -39,11 +72,11 @@ type Payload struct {
 	Issuer string `json:"iss"`
 
 	// Audience - "aud" - audience
-	// who the JWT is intended for.
+	// who the JWT is intended for, a single string or an array of strings.
 	// The token will be rejected if the principal processing
 	// the claim does not identify itself with
-	// the value listed here.
-	Audience string `json:"aud"`
+	// one of the values listed here, see WithAudience.
+	Audience Audience `json:"aud"`
 
 	// UserID - "sub" - subject
 	// who the JWT was supplied to.
@@ -54,10 +87,12 @@ type Payload struct {
 	// The unique identifier for this particular token
 	JwtID string `json:"jti"`
 
-	// KeyID - "kid" - Key ID
+	// LegacyKeyID - "kid" - Key ID
 	// ** Public Claim **
-	// The version of the secret used to hash the signature.
-	KeyID string `json:"kid"`
+	// The version of the secret used to hash the signature, as placed in the
+	// payload by earlier versions of this package.  The key ID now belongs in the Header,
+	// and this is only read by Decode when AllowPayloadKeyID is set.
+	LegacyKeyID string `json:"kid,omitempty"`
 
 	// IssuedAtTime - "iat" - issued at time
 	// the time the JWT was issued
@@ -90,8 +125,11 @@ type Config struct {
 }
 
 /*
-	Log is an array of messages added to as the
-	token moves through creation or verification.
-	Used mainly for fault finding
+	setDefaults sets ValidFrom to the 1st Jan 2021 if it hasn't been set
 */
-type Log []string
+func (c *Config) setDefaults() {
+	if c.ValidFrom == 0 {
+		/* 01 Jan 2021 00:00 UTC */
+		c.ValidFrom = 1609459200
+	}
+}
@@ -24,12 +24,12 @@ const jwtioJSON = `
 
 var jwtioStruct = Payload{
 	Issuer:   "github.com/markstanden",
-	Audience: "github.com/markstanden/authentication",
+	Audience: Audience{"github.com/markstanden/authentication"},
 
 	UserID: "1234567890",
 	JwtID:  "nV1M2B2Zl-SC04GaZJp7qDqP43GnC1ZgttT0E8dvh-jsePF0l5p0EEkKMH8wIz5M2zlzr5GL3R-T89mK-NRwAQ==",
 
-	KeyID: "MWIxSYn_QdX2mPFFiwfu2LusOiXidMPjD_is0Kr4BKvvsbgAAE23LnVdjI8UAFW1Fz-9LJPOqK9LAnzWpXpQpw==",
+	LegacyKeyID: "MWIxSYn_QdX2mPFFiwfu2LusOiXidMPjD_is0Kr4BKvvsbgAAE23LnVdjI8UAFW1Fz-9LJPOqK9LAnzWpXpQpw==",
 
 	IssuedAtTime:   1600000000,
 	NotBeforeTime:  1600000000,
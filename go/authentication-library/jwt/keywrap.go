@@ -0,0 +1,93 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+/*
+	errKeyUnwrap is returned when a wrapped key fails its integrity check
+*/
+var errKeyUnwrap = errors.New("failed to unwrap key")
+
+/*
+	keyWrapIV is the default initial value of the AES Key Wrap algorithm
+*/
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+/*
+	aesKeyWrap wraps the key with the key encryption key (kek)
+	using the AES Key Wrap algorithm (RFC 3394), as used by A256KW.
+	The key must be a multiple of 8 bytes, and at least 16 bytes long.
+*/
+func aesKeyWrap(kek, key []byte) (wrapped []byte, err error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	n := len(key) / 8
+	r := make([]byte, len(key))
+	copy(r, key)
+
+	a := make([]byte, 8)
+	copy(a, keyWrapIV)
+
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(b, a)
+			copy(b[8:], r[i*8:])
+			block.Encrypt(b, b)
+
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r[i*8:], b[8:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+/*
+	aesKeyUnwrap reverses aesKeyWrap, returning errKeyUnwrap if the
+	wrapped key was not wrapped with the kek or has been altered
+*/
+func aesKeyUnwrap(kek, wrapped []byte) (key []byte, err error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errKeyUnwrap
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	n := len(wrapped)/8 - 1
+	r := make([]byte, len(wrapped)-8)
+	copy(r, wrapped[8:])
+
+	a := make([]byte, 8)
+	copy(a, wrapped[:8])
+
+	b := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n - 1; i >= 0; i-- {
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(b, binary.BigEndian.Uint64(a)^t)
+			copy(b[8:], r[i*8:])
+			block.Decrypt(b, b)
+
+			copy(a, b[:8])
+			copy(r[i*8:], b[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errKeyUnwrap
+	}
+	return r, nil
+}
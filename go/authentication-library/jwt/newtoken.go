@@ -1,10 +1,6 @@
 package jwt
 
-import (
-	"fmt"
-
-	"github.com/markstanden/jwt/time"
-)
+import "github.com/markstanden/jwt/time"
 
 /*
 	NewToken creates a new jwt token struct, with sane defaults for header and payload time values.
@@ -16,6 +12,16 @@ import (
 	validFor (int64)
 */
 func NewToken(issuer, audience, userID, jwtID, keyID string, validFor int64) (token *Token) {
+	return NewTokenWithClock(time.System, issuer, audience, userID, jwtID, keyID, validFor)
+}
+
+/*
+	NewTokenWithClock creates a new jwt token struct as NewToken,
+	with the time values set from the supplied clock rather than the system time.
+*/
+func NewTokenWithClock(clock time.Clock, issuer, audience, userID, jwtID, keyID string, validFor int64) (token *Token) {
+
+	now := time.Unix(clock)
 
 	/*
 		If a negative value of expiration time is provided
@@ -28,20 +34,24 @@ func NewToken(issuer, audience, userID, jwtID, keyID string, validFor int64) (to
 	h := Header{
 		Algorithm: "HS512",
 		TokenType: "JWT",
+		KeyID:     keyID,
 	}
+	var aud Audience
+	if audience != "" {
+		aud = Audience{audience}
+	}
+
 	p := Payload{
 		Issuer:         issuer,
-		Audience:       audience,
+		Audience:       aud,
 		UserID:         userID,
 		JwtID:          jwtID,
-		KeyID:          keyID,
-		IssuedAtTime:   time.GetUnix(),
-		NotBeforeTime:  time.GetUnix(),
-		ExpirationTime: time.GetUnix() + validFor,
+		IssuedAtTime:   now,
+		NotBeforeTime:  now,
+		ExpirationTime: now + validFor,
 	}
 	c := Config{
 		Lifespan: validFor,
 	}
-	l := Log{fmt.Sprintf("%d", time.GetUnix()) + " Token Created"}
-	return &Token{h, p, c, l}
+	return &Token{Header: h, Payload: p, Config: c}
 }
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 
 	"github.com/markstanden/jwt/time"
@@ -11,7 +12,7 @@ func TestNewToken(t *testing.T) {
 	// create a JWT using data previously parsed on the jwt.io website
 	j := jwtioStruct
 	validFor := j.ExpirationTime - j.IssuedAtTime
-	test := NewToken(j.Issuer, j.Audience, j.UserID, j.JwtID, j.KeyID, int64(validFor))
+	test := NewToken(j.Issuer, j.Audience[0], j.UserID, j.JwtID, j.LegacyKeyID, int64(validFor))
 
 	// The NewToken func will generate time and expiry dates based on the current time,
 	// so check they are being set correctly then override to the supplied data used to
@@ -34,9 +35,26 @@ func TestNewToken(t *testing.T) {
 	test.NotBeforeTime = j.NotBeforeTime
 	test.ExpirationTime = j.ExpirationTime
 
-	if test.Payload != j {
+	// the key ID is set in the header, rather than the payload of the jwt.io token
+	if test.Header.KeyID != j.LegacyKeyID {
+		t.Errorf("key ID not set in the header: Wanted %v	 Got %v\n", j.LegacyKeyID, test.Header.KeyID)
+	}
+	j.LegacyKeyID = ""
+
+	if !reflect.DeepEqual(test.Payload, j) {
 		t.Errorf("created struct is not as expected : \nWanted \n%v\n Got \n%v\n", j, test.Payload)
 	}
 
 	fmt.Println("Created jwt.io test Token struct OK.")
 }
+
+func TestNewTokenWithClock(t *testing.T) {
+	const issued = 1700000000
+	test := NewTokenWithClock(time.Fixed(issued), "iss", "aud", "sub", "jti", "kid", 3600)
+
+	want := [3]int64{issued, issued, issued + 3600}
+	got := [3]int64{test.IssuedAtTime, test.NotBeforeTime, test.ExpirationTime}
+	if got != want {
+		t.Errorf("time claims not set from the clock:\nWanted: %v\nGot: %v", want, got)
+	}
+}
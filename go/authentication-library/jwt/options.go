@@ -0,0 +1,262 @@
+package jwt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	jwttime "github.com/markstanden/jwt/time"
+)
+
+/*
+	KeyLookup returns the Verifier for the key that signed a token,
+	identified by the token's key ID and algorithm.
+	It is only called for algorithms in the Decode allowlist.
+*/
+type KeyLookup func(keyID, algorithm string) (Verifier, error)
+
+/*
+	DecodeOption configures the checks made by Decode
+*/
+type DecodeOption func(*decodeOptions)
+
+type decodeOptions struct {
+	algorithms   []string
+	types        []string
+	lookup       KeyLookup
+	payloadKeyID bool
+
+	clock  jwttime.Clock
+	leeway int64
+
+	issuers  []string
+	audience string
+	required []string
+	maxAge   int64
+}
+
+/*
+	WithAlgorithms sets the algorithms Decode accepts, rejecting any token
+	with an "alg" header outside of the list before its signature is checked.
+	Only list the algorithms you issue, so an attacker can't sign a token with
+	an algorithm you don't expect, such as HS256 using your RSA public key
+	as the secret.  Decode accepts only HS512 by default.
+*/
+func WithAlgorithms(algorithms ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.algorithms = algorithms
+	}
+}
+
+/*
+	WithKeyLookup sets the lookup Decode uses to find the Verifier for a token,
+	replacing the HMAC secret lookup passed to Decode.
+*/
+func WithKeyLookup(lookup KeyLookup) DecodeOption {
+	return func(o *decodeOptions) {
+		o.lookup = lookup
+	}
+}
+
+/*
+	WithTypes sets the "typ" header values Decode accepts.
+	The comparison ignores case and an "application/" prefix, as RFC 7515 allows.
+	Decode accepts "JWT" and "at+jwt" by default, include "" to accept tokens without a "typ".
+*/
+func WithTypes(types ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.types = types
+	}
+}
+
+/*
+	AllowPayloadKeyID accepts tokens created by earlier versions of this package,
+	which placed the "kid" in the payload rather than the header.
+	The payload "kid" is only used if the header has no "kid".
+*/
+func AllowPayloadKeyID() DecodeOption {
+	return func(o *decodeOptions) {
+		o.payloadKeyID = true
+	}
+}
+
+/*
+	WithClock sets the clock used to check the token's time claims,
+	the system time by default
+*/
+func WithClock(clock jwttime.Clock) DecodeOption {
+	return func(o *decodeOptions) {
+		o.clock = clock
+	}
+}
+
+/*
+	WithLeeway allows for clock skew between the server issuing tokens and
+	the server checking them.  Tokens issued, or valid from, up to the leeway
+	in the future are accepted, as are tokens that expired within the leeway.
+	Keep the leeway to a few seconds, it extends the life of every token.
+*/
+func WithLeeway(leeway time.Duration) DecodeOption {
+	return func(o *decodeOptions) {
+		o.leeway = int64(leeway / time.Second)
+	}
+}
+
+/*
+	WithIssuer rejects tokens with ErrInvalidIssuer unless
+	the "iss" claim is one of the issuers
+*/
+func WithIssuer(issuers ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.issuers = issuers
+	}
+}
+
+/*
+	WithAudience rejects tokens with ErrInvalidAudience unless
+	the audience is included in the "aud" claim
+*/
+func WithAudience(audience string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.audience = audience
+	}
+}
+
+/*
+	RequireClaims rejects tokens with ErrMissingClaim unless every
+	one of the named claims is present, and not null, in the payload.
+	Both registered and custom claims can be required.
+*/
+func RequireClaims(claims ...string) DecodeOption {
+	return func(o *decodeOptions) {
+		o.required = append(o.required, claims...)
+	}
+}
+
+/*
+	MaxAge rejects tokens with ErrTokenTooOld if they were issued ("iat")
+	longer ago than the maximum age, regardless of their expiration time.
+*/
+func MaxAge(maxAge time.Duration) DecodeOption {
+	return func(o *decodeOptions) {
+		o.maxAge = int64(maxAge / time.Second)
+	}
+}
+
+/*
+	checkClaims performs the optional issuer, audience, required claim
+	and maximum age checks on the validated token.
+	payload is the token's decoded JSON payload, and now the current unix time.
+*/
+func (o decodeOptions) checkClaims(t *Token, payload []byte, now int64) error {
+	if len(o.issuers) > 0 && !contains(o.issuers, t.Issuer) {
+		return &ValidationError{Reason: ReasonInvalidClaim, Claim: "iss", Err: ErrInvalidIssuer}
+	}
+
+	if o.audience != "" && !t.Audience.Contains(o.audience) {
+		return &ValidationError{Reason: ReasonInvalidClaim, Claim: "aud", Err: ErrInvalidAudience}
+	}
+
+	if len(o.required) > 0 {
+		var claims map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return invalid(ReasonMalformed, "")
+		}
+		for _, name := range o.required {
+			if value, ok := claims[name]; !ok || string(value) == "null" {
+				return &ValidationError{Reason: ReasonMissingClaim, Claim: name, Err: ErrMissingClaim}
+			}
+		}
+	}
+
+	if o.maxAge > 0 && now-t.IssuedAtTime > o.maxAge+o.leeway {
+		return &ValidationError{Reason: ReasonExpired, Claim: "iat", Err: ErrTokenTooOld}
+	}
+	return nil
+}
+
+/*
+	newDecodeOptions returns the defaults, HS512 only, with HMAC secrets
+	obtained from the passwordLookup callback, overridden by the options
+*/
+func newDecodeOptions(passwordLookup func(keyID string) (secret string), options []DecodeOption) decodeOptions {
+	o := decodeOptions{
+		clock:      jwttime.System,
+		algorithms: []string{HS512},
+		types:      []string{"JWT", "at+jwt"},
+		lookup:     secretLookup(passwordLookup),
+	}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
+}
+
+/*
+	allowed returns true if the algorithm is in the allowlist
+*/
+func (o decodeOptions) allowed(algorithm string) bool {
+	return contains(o.algorithms, algorithm)
+}
+
+/*
+	typeAllowed returns true if the "typ" header is one of the accepted types
+*/
+func (o decodeOptions) typeAllowed(typ string) bool {
+	for _, t := range o.types {
+		if mediaType(t) == mediaType(typ) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	mediaType returns the "typ" or "cty" in lower case, without the optional "application/" prefix
+*/
+func mediaType(typ string) string {
+	return strings.TrimPrefix(strings.ToLower(typ), "application/")
+}
+
+/*
+	keyID returns the key ID used to find the key that signed the token
+*/
+func (o decodeOptions) keyID(t Token) string {
+	if t.KeyID == "" && o.payloadKeyID {
+		return t.LegacyKeyID
+	}
+	return t.KeyID
+}
+
+/*
+	contains returns true if the list includes the value
+*/
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	secretLookup adapts a secret callback to a KeyLookup for the HMAC algorithms
+*/
+func secretLookup(passwordLookup func(keyID string) (secret string)) KeyLookup {
+	return func(keyID, algorithm string) (Verifier, error) {
+		if passwordLookup == nil {
+			return nil, ErrFailedSecret
+		}
+		secret := passwordLookup(keyID)
+		if secret == "" {
+			return nil, ErrFailedSecret
+		}
+		h, err := NewHMAC(algorithm, secret)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFailedSecret, err)
+		}
+		return h, nil
+	}
+}
@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	jwttime "github.com/markstanden/jwt/time"
+)
+
+func TestDecodeClaimOptions(t *testing.T) {
+	token := NewToken("https://auth.example.com", "https://api.example.com", "sub", "jti", "kid", 3600)
+	token.IssuedAtTime -= 600
+	token.NotBeforeTime -= 600
+	token.ExpirationTime -= 600
+	token.Extra = map[string]interface{}{"scope": "read", "tenant": nil}
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	tests := []struct {
+		desc    string
+		options []DecodeOption
+		err     error
+	}{
+		{desc: "No options", options: nil},
+		{desc: "Issuer", options: []DecodeOption{WithIssuer("https://auth.example.com")}},
+		{desc: "One of several issuers", options: []DecodeOption{WithIssuer("https://other.example.com", "https://auth.example.com")}},
+		{desc: "Wrong issuer", options: []DecodeOption{WithIssuer("https://other.example.com")}, err: ErrInvalidIssuer},
+		{desc: "Audience", options: []DecodeOption{WithAudience("https://api.example.com")}},
+		{desc: "Wrong audience", options: []DecodeOption{WithAudience("https://web.example.com")}, err: ErrInvalidAudience},
+		{desc: "Required registered claims", options: []DecodeOption{RequireClaims("sub", "jti", "exp")}},
+		{desc: "Required custom claim", options: []DecodeOption{RequireClaims("scope")}},
+		{desc: "Missing claim", options: []DecodeOption{RequireClaims("email_verified")}, err: ErrMissingClaim},
+		{desc: "Null claim", options: []DecodeOption{RequireClaims("tenant")}, err: ErrMissingClaim},
+		{desc: "Within max age", options: []DecodeOption{MaxAge(time.Hour)}},
+		{desc: "Too old", options: []DecodeOption{MaxAge(5 * time.Minute)}, err: ErrTokenTooOld},
+		{desc: "All checks", options: []DecodeOption{
+			WithIssuer("https://auth.example.com"),
+			WithAudience("https://api.example.com"),
+			RequireClaims("scope"),
+			MaxAge(time.Hour),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := Token{Config: Config{Lifespan: 3600}}
+			err := Decode(jwt, jwtioSecret(), &got, test.options...)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if test.err != nil && !reflect.DeepEqual(got.Payload, Payload{}) {
+				t.Errorf("payload returned for a token that failed a check: %+v", got.Payload)
+			}
+		})
+	}
+}
+
+func TestDecodeTimeClaims(t *testing.T) {
+	const issued = 1700000000
+	const lifespan = 3600
+	token := NewTokenWithClock(jwttime.Fixed(issued), "iss", "aud", "sub", "jti", "kid", lifespan)
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	tests := []struct {
+		desc      string
+		now       int64
+		validFrom int64
+		options   []DecodeOption
+		err       error
+	}{
+		{desc: "At issue", now: issued},
+		{desc: "Before expiry", now: issued + lifespan},
+		{desc: "Expired", now: issued + lifespan + 1, err: ErrExpiredToken},
+		{desc: "Expired long ago", now: issued + 10*lifespan, err: ErrExpiredToken},
+		{desc: "Expired within leeway", now: issued + lifespan + 5, options: []DecodeOption{WithLeeway(5 * time.Second)}},
+		{desc: "Expired beyond leeway", now: issued + lifespan + 6, options: []DecodeOption{WithLeeway(5 * time.Second)}, err: ErrExpiredToken},
+		{desc: "Not yet valid", now: issued - 1, err: ErrInvalidToken},
+		{desc: "Not yet valid within leeway", now: issued - 5, options: []DecodeOption{WithLeeway(5 * time.Second)}},
+		{desc: "Not yet valid beyond leeway", now: issued - 6, options: []DecodeOption{WithLeeway(5 * time.Second)}, err: ErrInvalidToken},
+		{desc: "Issued before ValidFrom", now: issued + 10, validFrom: issued + 1, err: ErrInvalidToken},
+		{desc: "Issued at ValidFrom", now: issued + 10, validFrom: issued},
+		{desc: "Within max age", now: issued + 300, options: []DecodeOption{MaxAge(5 * time.Minute)}},
+		{desc: "Too old", now: issued + 301, options: []DecodeOption{MaxAge(5 * time.Minute)}, err: ErrTokenTooOld},
+		{desc: "Max age within leeway", now: issued + 305, options: []DecodeOption{MaxAge(5 * time.Minute), WithLeeway(5 * time.Second)}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := Token{Config: Config{ValidFrom: test.validFrom, Lifespan: lifespan}}
+			options := append([]DecodeOption{WithClock(jwttime.Fixed(test.now))}, test.options...)
+			err := Decode(jwt, jwtioSecret(), &got, options...)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
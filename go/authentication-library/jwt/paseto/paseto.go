@@ -0,0 +1,269 @@
+/*
+	Package paseto creates and decodes PASETO v4 tokens, an alternative
+	to JWTs without algorithm agility (https://github.com/paseto-standard/paseto-spec).
+	The claims of a jwt.Token are used as the token's payload, with the times
+	as RFC 3339 strings, and are checked using the same rules as jwt.Decode.
+	The token's KeyID is held in the footer, as {"kid":"..."}.
+*/
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/markstanden/jwt"
+	"github.com/markstanden/jwt/b64"
+)
+
+// Errors
+var (
+	errMalformed    = errors.New("malformed token")
+	errInvalidToken = errors.New("invalid authentication tag or signature")
+	errInvalidKey   = jwt.ErrInvalidKey
+)
+
+/*
+	maxFooterSize limits the footer read before the token has been authenticated
+*/
+const maxFooterSize = 1024
+
+/*
+	timeClaims are the registered claims held as unix time by jwt.Payload,
+	and as RFC 3339 strings by PASETO
+*/
+var timeClaims = []string{"iat", "nbf", "exp"}
+
+/*
+	Keys returns the keys used by Decode, for the key ID in the token's footer.
+	A token is rejected if the function for its purpose is nil,
+	so set only the purposes that are expected.
+*/
+type Keys struct {
+	// Local returns the 32 byte shared key for v4.local tokens
+	Local func(keyID string) (key []byte, err error)
+
+	// Public returns the Ed25519 public key for v4.public tokens
+	Public func(keyID string) (key ed25519.PublicKey, err error)
+}
+
+/*
+	footer is the JSON footer of the token, holding the key ID
+*/
+type footer struct {
+	KeyID string `json:"kid,omitempty"`
+}
+
+/*
+	CreateLocal creates a v4.local token of the token's claims,
+	encrypted with the 32 byte shared key
+*/
+func CreateLocal(t *jwt.Token, key []byte) (token string, err error) {
+	message, f, err := marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	n := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, n); err != nil {
+		return "", err
+	}
+	return encrypt(key, n, message, f, nil)
+}
+
+/*
+	CreatePublic creates a v4.public token of the token's claims,
+	signed with the Ed25519 private key
+*/
+func CreatePublic(t *jwt.Token, privateKey ed25519.PrivateKey) (token string, err error) {
+	message, f, err := marshal(t)
+	if err != nil {
+		return "", err
+	}
+	return sign(privateKey, message, f, nil)
+}
+
+/*
+	Decode takes an untrusted v4.local or v4.public token and checks it for validity:
+		- Checks the header, rejecting purposes without a function in keys
+		- Checks the authentication tag or signature, using the key for the key ID in the footer
+		- Checks the claims with jwt.DecodePayload, so the token's Config and the options apply
+	The token is filled if it is valid, and the errors returned are as jwt.Decode.
+*/
+func Decode(untrustedToken string, keys Keys, token *jwt.Token, options ...jwt.DecodeOption) (err error) {
+
+	keyID, err := readKeyID(untrustedToken)
+	if err != nil {
+		return err
+	}
+
+	var message []byte
+	switch {
+	case strings.HasPrefix(untrustedToken, V4Local) && keys.Local != nil:
+		key, err := keys.Local(keyID)
+		if err != nil {
+			return unknownKeyID(err)
+		}
+		message, _, err = decrypt(key, untrustedToken, nil)
+		if err != nil {
+			return validationError(err)
+		}
+
+	case strings.HasPrefix(untrustedToken, V4Public) && keys.Public != nil:
+		key, err := keys.Public(keyID)
+		if err != nil {
+			return unknownKeyID(err)
+		}
+		message, _, err = verify(key, untrustedToken, nil)
+		if err != nil {
+			return validationError(err)
+		}
+
+	default:
+		return &jwt.ValidationError{Reason: jwt.ReasonBadHeader, Err: jwt.ErrInvalidToken}
+	}
+
+	payload, err := unmarshalTimes(message)
+	if err != nil {
+		return err
+	}
+
+	ut := jwt.Token{Config: token.Config, Claims: token.Claims}
+	err = jwt.DecodePayload(payload, &ut, options...)
+	if err != nil && !errors.Is(err, jwt.ErrExpiredToken) {
+		return err
+	}
+
+	/* the token is valid, or valid but expired */
+	ut.KeyID = keyID
+	*token = ut
+	return err
+}
+
+/*
+	marshal returns the token's payload, with the times as RFC 3339 strings,
+	and the footer holding the token's KeyID
+*/
+func marshal(t *jwt.Token) (message, f []byte, err error) {
+	payload, err := t.MarshalPayload()
+	if err != nil {
+		return nil, nil, err
+	}
+	message, err = marshalTimes(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t.KeyID != "" {
+		if f, err = json.Marshal(footer{KeyID: t.KeyID}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return message, f, nil
+}
+
+/*
+	marshalTimes replaces the unix times in the JSON payload with RFC 3339 strings
+*/
+func marshalTimes(payload []byte) (message []byte, err error) {
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	for _, name := range timeClaims {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", jwt.ErrInvalidClaims, name)
+		}
+		claims[name], _ = json.Marshal(time.Unix(unix, 0).UTC().Format(time.RFC3339))
+	}
+	return json.Marshal(claims)
+}
+
+/*
+	unmarshalTimes replaces the RFC 3339 times in the authenticated message
+	with unix times, as expected by jwt.DecodePayload
+*/
+func unmarshalTimes(message []byte) (payload []byte, err error) {
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(message, &claims); err != nil {
+		return nil, &jwt.ValidationError{Reason: jwt.ReasonMalformed, Err: jwt.ErrInvalidToken}
+	}
+	for _, name := range timeClaims {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, &jwt.ValidationError{Reason: jwt.ReasonInvalidClaim, Claim: name, Err: jwt.ErrInvalidToken}
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, &jwt.ValidationError{Reason: jwt.ReasonInvalidClaim, Claim: name, Err: jwt.ErrInvalidToken}
+		}
+		claims[name] = json.RawMessage(strconv.FormatInt(t.Unix(), 10))
+	}
+	return json.Marshal(claims)
+}
+
+/*
+	readKeyID returns the key ID from the untrusted footer,
+	which must be checked once the key is found.
+	Returns an empty key ID if there is no footer.
+*/
+func readKeyID(untrustedToken string) (keyID string, err error) {
+
+	/* the version, purpose, body and optional footer */
+	sections := strings.Split(untrustedToken, ".")
+	if len(sections) == 3 {
+		return "", nil
+	}
+	if len(sections) != 4 || len(sections[3]) > maxFooterSize {
+		return "", &jwt.ValidationError{Reason: jwt.ReasonMalformed, Err: jwt.ErrInvalidToken}
+	}
+
+	raw, err := b64.ToBytes(sections[3])
+	if err != nil {
+		return "", &jwt.ValidationError{Reason: jwt.ReasonMalformed, Err: jwt.ErrInvalidToken}
+	}
+
+	var f footer
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return "", &jwt.ValidationError{Reason: jwt.ReasonMalformed, Claim: "kid", Err: jwt.ErrInvalidToken}
+	}
+	return f.KeyID, nil
+}
+
+/*
+	unknownKeyID returns the error for a failed key lookup
+*/
+func unknownKeyID(err error) error {
+	if !errors.Is(err, jwt.ErrFailedSecret) {
+		err = fmt.Errorf("%w: %v", jwt.ErrFailedSecret, err)
+	}
+	return &jwt.ValidationError{Reason: jwt.ReasonUnknownKeyID, Claim: "kid", Err: err}
+}
+
+/*
+	validationError returns the jwt.ValidationError for an error from decrypt or verify
+*/
+func validationError(err error) error {
+	switch err {
+	case errInvalidToken:
+		return &jwt.ValidationError{Reason: jwt.ReasonBadSignature, Err: jwt.ErrInvalidToken}
+	case errInvalidKey:
+		return unknownKeyID(err)
+	}
+	return &jwt.ValidationError{Reason: jwt.ReasonMalformed, Err: jwt.ErrInvalidToken}
+}
@@ -0,0 +1,177 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/jwt"
+	"github.com/markstanden/jwt/b64"
+	jwttime "github.com/markstanden/jwt/time"
+)
+
+const issued = 1700000000
+
+/*
+	testKeys returns the keys for "key-1", for both purposes,
+	and the private key for signing public tokens
+*/
+func testKeys() (keys Keys, localKey []byte, privateKey ed25519.PrivateKey) {
+	localKey = bytes.Repeat([]byte{7}, keySize)
+	privateKey = ed25519.NewKeyFromSeed(bytes.Repeat([]byte{9}, ed25519.SeedSize))
+
+	keys = Keys{
+		Local: func(keyID string) ([]byte, error) {
+			if keyID != "key-1" {
+				return nil, jwt.ErrFailedSecret
+			}
+			return localKey, nil
+		},
+		Public: func(keyID string) (ed25519.PublicKey, error) {
+			if keyID != "key-1" {
+				return nil, errors.New("key not found")
+			}
+			return privateKey.Public().(ed25519.PublicKey), nil
+		},
+	}
+	return keys, localKey, privateKey
+}
+
+/*
+	testTokens returns a v4.local and a v4.public token of the same claims, issued at issued
+*/
+func testTokens(t *testing.T, keyID string) (local, public string) {
+	_, localKey, privateKey := testKeys()
+
+	token := jwt.NewTokenWithClock(jwttime.Fixed(issued), "iss", "aud", "sub", "jti", keyID, 3600)
+	token.Extra = map[string]interface{}{"scope": "read"}
+
+	local, err := CreateLocal(token, localKey)
+	if err != nil {
+		t.Fatalf("failed to create local token: %v", err)
+	}
+	public, err = CreatePublic(token, privateKey)
+	if err != nil {
+		t.Fatalf("failed to create public token: %v", err)
+	}
+	return local, public
+}
+
+func TestCreateAndDecode(t *testing.T) {
+	keys, _, _ := testKeys()
+	local, public := testTokens(t, "key-1")
+
+	for desc, token := range map[string]string{"Local": local, "Public": public} {
+		token := token
+		t.Run(desc, func(t *testing.T) {
+			sections := strings.Split(token, ".")
+			if len(sections) != 4 || sections[3] != b64.FromBytes([]byte(`{"kid":"key-1"}`)) {
+				t.Fatalf("incorrect footer: %v", token)
+			}
+
+			got := jwt.Token{Config: jwt.Config{Lifespan: 3600}}
+			err := Decode(token, keys, &got, jwt.WithClock(jwttime.Fixed(issued+60)), jwt.WithAudience("aud"))
+			if err != nil {
+				t.Fatalf("failed to decode token: %v", err)
+			}
+
+			want := jwt.Payload{
+				Issuer:         "iss",
+				Audience:       jwt.Audience{"aud"},
+				UserID:         "sub",
+				JwtID:          "jti",
+				IssuedAtTime:   issued,
+				NotBeforeTime:  issued,
+				ExpirationTime: issued + 3600,
+			}
+			if !reflect.DeepEqual(got.Payload, want) || got.KeyID != "key-1" || got.Extra["scope"] != "read" {
+				t.Errorf("incorrect token:\nWanted: %+v\nGot: %+v, kid %q, extra %v", want, got.Payload, got.KeyID, got.Extra)
+			}
+		})
+	}
+}
+
+func TestPayloadTimes(t *testing.T) {
+	_, public := testTokens(t, "key-1")
+
+	body, _ := b64.ToBytes(strings.Split(public, ".")[2])
+	var claims map[string]interface{}
+	if err := json.Unmarshal(body[:len(body)-signatureSize], &claims); err != nil {
+		t.Fatalf("failed to unmarshal claims: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"iat": "2023-11-14T22:13:20Z",
+		"nbf": "2023-11-14T22:13:20Z",
+		"exp": "2023-11-14T23:13:20Z",
+	}
+	for name, value := range want {
+		if claims[name] != value {
+			t.Errorf("incorrect %v:\nWanted: %v\nGot: %v", name, value, claims[name])
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	keys, _, _ := testKeys()
+	local, public := testTokens(t, "key-1")
+	otherLocal, otherPublic := testTokens(t, "key-2")
+
+	tests := []struct {
+		desc    string
+		token   string
+		keys    Keys
+		now     int64
+		options []jwt.DecodeOption
+		reason  jwt.Reason
+		claim   string
+		err     error
+	}{
+		{desc: "JWT", token: "eyJhbGciOiJub25lIn0.e30.", reason: jwt.ReasonBadHeader, err: jwt.ErrInvalidToken},
+		{desc: "Other version", token: "v2" + local[2:], reason: jwt.ReasonBadHeader, err: jwt.ErrInvalidToken},
+		{desc: "Local not accepted", token: local, keys: Keys{Public: keys.Public}, reason: jwt.ReasonBadHeader, err: jwt.ErrInvalidToken},
+		{desc: "Public not accepted", token: public, keys: Keys{Local: keys.Local}, reason: jwt.ReasonBadHeader, err: jwt.ErrInvalidToken},
+		{desc: "Public token as local", token: strings.Replace(public, V4Public, V4Local, 1), reason: jwt.ReasonBadSignature, err: jwt.ErrInvalidToken},
+		{desc: "Unknown local kid", token: otherLocal, reason: jwt.ReasonUnknownKeyID, claim: "kid", err: jwt.ErrFailedSecret},
+		{desc: "Unknown public kid", token: otherPublic, reason: jwt.ReasonUnknownKeyID, claim: "kid", err: jwt.ErrFailedSecret},
+		{desc: "Swapped footer", token: otherLocal[:strings.LastIndex(otherLocal, ".")] + local[strings.LastIndex(local, "."):], reason: jwt.ReasonBadSignature, err: jwt.ErrInvalidToken},
+		{desc: "Invalid footer", token: public + "x", reason: jwt.ReasonMalformed, err: jwt.ErrInvalidToken},
+		{desc: "Expired", token: local, now: issued + 3601, reason: jwt.ReasonExpired, claim: "exp", err: jwt.ErrExpiredToken},
+		{desc: "Not yet valid", token: public, now: issued - 1, reason: jwt.ReasonNotYetValid, claim: "iat", err: jwt.ErrInvalidToken},
+		{desc: "Wrong audience", token: public, options: []jwt.DecodeOption{jwt.WithAudience("other")}, reason: jwt.ReasonInvalidClaim, claim: "aud", err: jwt.ErrInvalidAudience},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.keys.Local == nil && test.keys.Public == nil {
+				test.keys = keys
+			}
+			if test.now == 0 {
+				test.now = issued
+			}
+			options := append([]jwt.DecodeOption{jwt.WithClock(jwttime.Fixed(test.now))}, test.options...)
+
+			got := jwt.Token{Config: jwt.Config{Lifespan: 3600}}
+			err := Decode(test.token, test.keys, &got, options...)
+
+			var ve *jwt.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("not a ValidationError:\nWanted: %v\nGot: %v", test.reason, err)
+			}
+			if ve.Reason != test.reason || ve.Claim != test.claim {
+				t.Errorf("incorrect reason:\nWanted: %v %q\nGot: %v %q", test.reason, test.claim, ve.Reason, ve.Claim)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("incorrect underlying error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+
+			// only expired tokens return their claims
+			if filled := got.UserID != ""; filled != errors.Is(err, jwt.ErrExpiredToken) {
+				t.Errorf("incorrect payload returned: %+v", got.Payload)
+			}
+		})
+	}
+}
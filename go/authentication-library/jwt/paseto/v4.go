@@ -0,0 +1,237 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"encoding/binary"
+	"strings"
+
+	"github.com/markstanden/jwt/b64"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+/*
+	Token headers, the version and purpose of the token.
+	Each version has a single algorithm for each purpose,
+	so there is no algorithm to choose, or to confuse.
+*/
+const (
+	// V4Local tokens are encrypted and authenticated with a shared key, using XChaCha20 and BLAKE2b
+	V4Local = "v4.local."
+
+	// V4Public tokens are signed with Ed25519, the claims are readable by anyone
+	V4Public = "v4.public."
+)
+
+/*
+	Sizes in bytes of the v4 keys, nonce, authentication tag and signature
+*/
+const (
+	keySize       = 32
+	nonceSize     = 32
+	tagSize       = 32
+	signatureSize = ed25519.SignatureSize
+)
+
+/*
+	encrypt returns the v4.local token of the message, using the
+	32 byte key and 32 byte random nonce n.
+	The footer is authenticated but not encrypted, and the implicit assertion
+	is authenticated, but not included in the token.
+*/
+func encrypt(key, n, message, footer, implicit []byte) (token string, err error) {
+	if len(key) != keySize || len(n) != nonceSize {
+		return "", errInvalidKey
+	}
+
+	/*
+		Split the key into an encryption key and nonce, and an authentication key,
+		unique to this token's nonce
+	*/
+	encryptionKey, n2, authKey := splitKey(key, n)
+
+	stream, err := chacha20.NewUnauthenticatedCipher(encryptionKey, n2)
+	if err != nil {
+		return "", err
+	}
+	c := make([]byte, len(message))
+	stream.XORKeyStream(c, message)
+
+	t := mac(authKey, pae([]byte(V4Local), n, c, footer, implicit))
+
+	body := make([]byte, 0, len(n)+len(c)+len(t))
+	body = append(append(append(body, n...), c...), t...)
+	return join(V4Local, body, footer), nil
+}
+
+/*
+	decrypt checks the authentication tag of the v4.local token,
+	returning the decrypted message and the footer
+*/
+func decrypt(key []byte, token string, implicit []byte) (message, footer []byte, err error) {
+	if len(key) != keySize {
+		return nil, nil, errInvalidKey
+	}
+
+	body, footer, err := split(V4Local, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < nonceSize+tagSize {
+		return nil, nil, errMalformed
+	}
+	n, c, t := body[:nonceSize], body[nonceSize:len(body)-tagSize], body[len(body)-tagSize:]
+
+	encryptionKey, n2, authKey := splitKey(key, n)
+
+	/* the tag must be checked before decrypting */
+	if !hmac.Equal(t, mac(authKey, pae([]byte(V4Local), n, c, footer, implicit))) {
+		return nil, nil, errInvalidToken
+	}
+
+	stream, err := chacha20.NewUnauthenticatedCipher(encryptionKey, n2)
+	if err != nil {
+		return nil, nil, err
+	}
+	message = make([]byte, len(c))
+	stream.XORKeyStream(message, c)
+	return message, footer, nil
+}
+
+/*
+	sign returns the v4.public token of the message, signed with the Ed25519 private key
+*/
+func sign(privateKey ed25519.PrivateKey, message, footer, implicit []byte) (token string, err error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return "", errInvalidKey
+	}
+
+	signature := ed25519.Sign(privateKey, pae([]byte(V4Public), message, footer, implicit))
+
+	body := make([]byte, 0, len(message)+len(signature))
+	body = append(append(body, message...), signature...)
+	return join(V4Public, body, footer), nil
+}
+
+/*
+	verify checks the signature of the v4.public token,
+	returning the message and the footer
+*/
+func verify(publicKey ed25519.PublicKey, token string, implicit []byte) (message, footer []byte, err error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, nil, errInvalidKey
+	}
+
+	body, footer, err := split(V4Public, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < signatureSize {
+		return nil, nil, errMalformed
+	}
+	message, signature := body[:len(body)-signatureSize], body[len(body)-signatureSize:]
+
+	if !ed25519.Verify(publicKey, pae([]byte(V4Public), message, footer, implicit), signature) {
+		return nil, nil, errInvalidToken
+	}
+	return message, footer, nil
+}
+
+/*
+	splitKey derives the encryption key, the XChaCha20 nonce and the
+	authentication key for the nonce n from the key
+*/
+func splitKey(key, n []byte) (encryptionKey, n2, authKey []byte) {
+	tmp := blake2bMAC(key, 56, []byte("paseto-encryption-key"), n)
+	authKey = blake2bMAC(key, 32, []byte("paseto-auth-key-for-aead"), n)
+	return tmp[:32], tmp[32:], authKey
+}
+
+/*
+	mac returns the 32 byte BLAKE2b authentication tag of the message
+*/
+func mac(authKey, message []byte) []byte {
+	return blake2bMAC(authKey, tagSize, message)
+}
+
+/*
+	blake2bMAC returns the keyed BLAKE2b hash of the joined parts, of the size in bytes
+*/
+func blake2bMAC(key []byte, size int, parts ...[]byte) []byte {
+	h, err := blake2b.New(size, key)
+	if err != nil {
+		/* the sizes and keys are fixed, so can't fail */
+		panic(err)
+	}
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+/*
+	pae is the Pre-Authentication Encoding of the pieces, which
+	encodes the number and length of each piece, so they can't be
+	rearranged to produce the same signature or tag.
+*/
+func pae(pieces ...[]byte) []byte {
+	size := 8
+	for _, piece := range pieces {
+		size += 8 + len(piece)
+	}
+	out := make([]byte, 0, size)
+	out = appendLE64(out, len(pieces))
+	for _, piece := range pieces {
+		out = appendLE64(out, len(piece))
+		out = append(out, piece...)
+	}
+	return out
+}
+
+/*
+	appendLE64 appends n as a 64 bit little endian integer, with the most significant bit cleared
+*/
+func appendLE64(out []byte, n int) []byte {
+	var le [8]byte
+	binary.LittleEndian.PutUint64(le[:], uint64(n)&(1<<63-1))
+	return append(out, le[:]...)
+}
+
+/*
+	join returns the token of the header, the base64 URL encoded body,
+	and the footer, if there is one
+*/
+func join(header string, body, footer []byte) string {
+	token := header + b64.FromBytes(body)
+	if len(footer) > 0 {
+		token += "." + b64.FromBytes(footer)
+	}
+	return token
+}
+
+/*
+	split checks the token's header, returning the decoded body and footer
+*/
+func split(header, token string) (body, footer []byte, err error) {
+	if !strings.HasPrefix(token, header) {
+		return nil, nil, errMalformed
+	}
+
+	sections := strings.Split(token[len(header):], ".")
+	if len(sections) > 2 {
+		return nil, nil, errMalformed
+	}
+
+	body, err = b64.ToBytes(sections[0])
+	if err != nil {
+		return nil, nil, errMalformed
+	}
+	if len(sections) == 2 {
+		/* an empty footer is omitted, along with its full stop */
+		if footer, err = b64.ToBytes(sections[1]); err != nil || len(footer) == 0 {
+			return nil, nil, errMalformed
+		}
+	}
+	return body, footer, nil
+}
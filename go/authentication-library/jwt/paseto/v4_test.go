@@ -0,0 +1,143 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// PASETO v4 test vectors 4-E-1 and 4-S-1
+const (
+	vectorLocalKey   = "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f"
+	vectorLocalToken = "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg"
+	vectorLocal      = `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`
+
+	vectorPublicSeed  = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774"
+	vectorPublicKey   = "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	vectorPublicToken = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+	vectorPublic      = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+)
+
+func TestPAE(t *testing.T) {
+	tests := []struct {
+		desc   string
+		pieces [][]byte
+		want   string
+	}{
+		{desc: "No pieces", pieces: nil, want: "0000000000000000"},
+		{desc: "Empty piece", pieces: [][]byte{{}}, want: "01000000000000000000000000000000"},
+		{desc: "Two empty pieces", pieces: [][]byte{{}, {}}, want: "020000000000000000000000000000000000000000000000"},
+		{desc: "Test", pieces: [][]byte{[]byte("test")}, want: "0100000000000000040000000000000074657374"},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := hex.EncodeToString(pae(test.pieces...)); got != test.want {
+				t.Errorf("incorrect encoding:\nWanted: %v\nGot: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalVector(t *testing.T) {
+	key, _ := hex.DecodeString(vectorLocalKey)
+
+	token, err := encrypt(key, make([]byte, nonceSize), []byte(vectorLocal), nil, nil)
+	if err != nil || token != vectorLocalToken {
+		t.Fatalf("incorrect token:\nWanted: %v\nGot: %v (%v)", vectorLocalToken, token, err)
+	}
+
+	message, footer, err := decrypt(key, vectorLocalToken, nil)
+	if err != nil || string(message) != vectorLocal || footer != nil {
+		t.Errorf("incorrect message:\nWanted: %v\nGot: %s, footer %q (%v)", vectorLocal, message, footer, err)
+	}
+}
+
+func TestPublicVector(t *testing.T) {
+	seed, _ := hex.DecodeString(vectorPublicSeed)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	publicKey, _ := hex.DecodeString(vectorPublicKey)
+
+	token, err := sign(privateKey, []byte(vectorPublic), nil, nil)
+	if err != nil || token != vectorPublicToken {
+		t.Fatalf("incorrect token:\nWanted: %v\nGot: %v (%v)", vectorPublicToken, token, err)
+	}
+
+	message, footer, err := verify(publicKey, vectorPublicToken, nil)
+	if err != nil || string(message) != vectorPublic || footer != nil {
+		t.Errorf("incorrect message:\nWanted: %v\nGot: %s, footer %q (%v)", vectorPublic, message, footer, err)
+	}
+}
+
+func TestFooterAndImplicitAssertion(t *testing.T) {
+	key, _ := hex.DecodeString(vectorLocalKey)
+	seed, _ := hex.DecodeString(vectorPublicSeed)
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	footer := []byte(`{"kid":"key-1"}`)
+	implicit := []byte("tenant-1")
+
+	local, err := encrypt(key, bytes.Repeat([]byte{1}, nonceSize), []byte(vectorLocal), footer, implicit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := sign(privateKey, []byte(vectorPublic), footer, implicit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		desc   string
+		header string
+		token  string
+		open   func(token string, implicit []byte) (message, footer []byte, err error)
+	}{
+		{desc: "Local", header: V4Local, token: local, open: func(token string, implicit []byte) ([]byte, []byte, error) {
+			return decrypt(key, token, implicit)
+		}},
+		{desc: "Public", header: V4Public, token: public, open: func(token string, implicit []byte) ([]byte, []byte, error) {
+			return verify(publicKey, token, implicit)
+		}},
+	}
+	for _, test := range tests {
+		token, open, header := test.token, test.open, test.header
+		t.Run(test.desc, func(t *testing.T) {
+			_, got, err := open(token, implicit)
+			if err != nil || !bytes.Equal(got, footer) {
+				t.Fatalf("incorrect footer:\nWanted: %s\nGot: %s (%v)", footer, got, err)
+			}
+
+			if _, _, err := open(token, []byte("tenant-2")); err != errInvalidToken {
+				t.Errorf("wrong implicit assertion accepted:\nWanted: %v\nGot: %v", errInvalidToken, err)
+			}
+
+			withoutFooter := token[:strings.LastIndex(token, ".")]
+			if _, _, err := open(withoutFooter, implicit); err != errInvalidToken {
+				t.Errorf("removed footer accepted:\nWanted: %v\nGot: %v", errInvalidToken, err)
+			}
+
+			body := []byte(withoutFooter)
+			body[len(header)+5] ^= 1
+			if _, _, err := open(string(body)+token[len(withoutFooter):], implicit); err == nil {
+				t.Errorf("altered token accepted")
+			}
+		})
+	}
+}
+
+func TestWrongPurpose(t *testing.T) {
+	key, _ := hex.DecodeString(vectorLocalKey)
+	publicKey, _ := hex.DecodeString(vectorPublicKey)
+
+	if _, _, err := decrypt(key, vectorPublicToken, nil); err != errMalformed {
+		t.Errorf("public token decrypted:\nWanted: %v\nGot: %v", errMalformed, err)
+	}
+	if _, _, err := verify(publicKey, vectorLocalToken, nil); err != errMalformed {
+		t.Errorf("local token verified:\nWanted: %v\nGot: %v", errMalformed, err)
+	}
+	if _, _, err := decrypt(key[:16], vectorLocalToken, nil); err != errInvalidKey {
+		t.Errorf("short key accepted:\nWanted: %v\nGot: %v", errInvalidKey, err)
+	}
+}
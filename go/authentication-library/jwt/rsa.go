@@ -0,0 +1,89 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+)
+
+/*
+	minRSABits is the smallest RSA modulus accepted, as required by RFC 7518
+*/
+const minRSABits = 2048
+
+/*
+	RSA signs and verifies tokens with RS256 (PKCS #1 v1.5) or PS256 (PSS).
+	A Signer holds the private key, a Verifier only needs the public key.
+*/
+type RSA struct {
+	alg        string
+	privateKey *rsa.PrivateKey
+	publicKey  *rsa.PublicKey
+}
+
+/*
+	NewRSASigner returns an RSA Signer, which can also verify its own tokens.
+	Returns ErrUnsupportedAlgorithm if alg is not RS256 or PS256,
+	and ErrInvalidKey if the key is missing or shorter than 2048 bits.
+*/
+func NewRSASigner(alg string, key *rsa.PrivateKey) (r RSA, err error) {
+	if key == nil {
+		return RSA{}, fmt.Errorf("%w: missing RSA private key", ErrInvalidKey)
+	}
+	r, err = NewRSAVerifier(alg, &key.PublicKey)
+	if err != nil {
+		return RSA{}, err
+	}
+	r.privateKey = key
+	return r, nil
+}
+
+/*
+	NewRSAVerifier returns an RSA Verifier for the public key.
+	Returns ErrUnsupportedAlgorithm if alg is not RS256 or PS256,
+	and ErrInvalidKey if the key is missing or shorter than 2048 bits.
+*/
+func NewRSAVerifier(alg string, key *rsa.PublicKey) (r RSA, err error) {
+	if alg != RS256 && alg != PS256 {
+		return RSA{}, fmt.Errorf("%w: %q is not an RSA algorithm", ErrUnsupportedAlgorithm, alg)
+	}
+	if key == nil || key.N == nil {
+		return RSA{}, fmt.Errorf("%w: missing RSA public key", ErrInvalidKey)
+	}
+	if key.N.BitLen() < minRSABits {
+		return RSA{}, fmt.Errorf("%w: %d bit RSA key, at least %d bits required", ErrInvalidKey, key.N.BitLen(), minRSABits)
+	}
+	return RSA{alg: alg, publicKey: key}, nil
+}
+
+func (r RSA) Algorithm() string {
+	return r.alg
+}
+
+func (r RSA) Sign(signingInput string) (signature []byte, err error) {
+	if r.privateKey == nil {
+		return nil, fmt.Errorf("%w: RSA verifier has no private key", ErrInvalidKey)
+	}
+	digest := sha256.Sum256([]byte(signingInput))
+	if r.alg == PS256 {
+		return rsa.SignPSS(rand.Reader, r.privateKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	}
+	return rsa.SignPKCS1v15(rand.Reader, r.privateKey, crypto.SHA256, digest[:])
+}
+
+func (r RSA) Verify(signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var err error
+	if r.alg == PS256 {
+		err = rsa.VerifyPSS(r.publicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	} else {
+		err = rsa.VerifyPKCS1v15(r.publicKey, crypto.SHA256, digest[:], signature)
+	}
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
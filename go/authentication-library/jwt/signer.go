@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"fmt"
+
+	"github.com/markstanden/jwt/hash"
+)
+
+/*
+	Algorithm names, as used in the "alg" header field (RFC 7518, RFC 8037)
+*/
+const (
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	RS256 = "RS256"
+	PS256 = "PS256"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	EdDSA = "EdDSA"
+)
+
+/*
+	Signer signs tokens.
+	Sign is passed the JWT signing input, the encoded header and payload
+	joined by a full stop, and returns the raw (unencoded) signature.
+*/
+type Signer interface {
+	Algorithm() string
+	Sign(signingInput string) (signature []byte, err error)
+}
+
+/*
+	Verifier checks token signatures.
+	Verify returns ErrInvalidSignature if the signature does not match the signing input.
+*/
+type Verifier interface {
+	Algorithm() string
+	Verify(signingInput string, signature []byte) error
+}
+
+/*
+	HMAC signs and verifies tokens with a shared secret, using HS256, HS384 or HS512.
+	Anyone able to verify an HMAC token is able to create one, so use an
+	asymmetric algorithm when tokens are verified by other services.
+*/
+type HMAC struct {
+	alg    string
+	secret string
+}
+
+/*
+	NewHMAC returns an HMAC Signer and Verifier for the algorithm.
+	Returns ErrUnsupportedAlgorithm if alg is not HS256, HS384 or HS512
+	and ErrInvalidKey if the secret is empty.
+*/
+func NewHMAC(alg, secret string) (h HMAC, err error) {
+	switch alg {
+	case HS256, HS384, HS512:
+	default:
+		return HMAC{}, fmt.Errorf("%w: %q is not an HMAC algorithm", ErrUnsupportedAlgorithm, alg)
+	}
+	if secret == "" {
+		return HMAC{}, fmt.Errorf("%w: empty secret", ErrInvalidKey)
+	}
+	return HMAC{alg: alg, secret: secret}, nil
+}
+
+func (h HMAC) Algorithm() string {
+	return h.alg
+}
+
+func (h HMAC) Sign(signingInput string) (signature []byte, err error) {
+	switch h.alg {
+	case HS256:
+		return hash.HS256(signingInput, h.secret), nil
+	case HS384:
+		return hash.HS384(signingInput, h.secret), nil
+	case HS512:
+		return hash.HS512(signingInput, h.secret), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, h.alg)
+}
+
+/*
+	Verify re-hashes the signing input using the secret,
+	and compares it to the supplied signature using a time safe comparison.
+*/
+func (h HMAC) Verify(signingInput string, signature []byte) error {
+	expected, err := h.Sign(signingInput)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
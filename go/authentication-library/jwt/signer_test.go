@@ -0,0 +1,284 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/markstanden/jwt/b64"
+)
+
+var (
+	testRSAOnce sync.Once
+	testRSAKey  *rsa.PrivateKey
+)
+
+/*
+	rsaKey generates a single RSA key for the tests, as generation is slow
+*/
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	testRSAOnce.Do(func() {
+		testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	if testRSAKey == nil {
+		t.Fatal("failed to generate RSA key")
+	}
+	return testRSAKey
+}
+
+func ecdsaKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate ECDSA key")
+	}
+	return key
+}
+
+func ed25519Key(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("failed to generate Ed25519 key")
+	}
+	return key
+}
+
+/*
+	testSigners returns a Signer and a Verifier, holding only the public key,
+	for each supported algorithm
+*/
+func testSigners(t *testing.T) map[string][2]interface{} {
+	signers := make(map[string][2]interface{})
+
+	for _, alg := range []string{HS256, HS384, HS512} {
+		h, err := NewHMAC(alg, "secretcode")
+		if err != nil {
+			t.Fatalf("NewHMAC(%v): %v", alg, err)
+		}
+		signers[alg] = [2]interface{}{h, h}
+	}
+
+	for _, alg := range []string{RS256, PS256} {
+		s, err := NewRSASigner(alg, rsaKey(t))
+		if err != nil {
+			t.Fatalf("NewRSASigner(%v): %v", alg, err)
+		}
+		v, err := NewRSAVerifier(alg, &rsaKey(t).PublicKey)
+		if err != nil {
+			t.Fatalf("NewRSAVerifier(%v): %v", alg, err)
+		}
+		signers[alg] = [2]interface{}{s, v}
+	}
+
+	for alg, curve := range map[string]elliptic.Curve{ES256: elliptic.P256(), ES384: elliptic.P384()} {
+		key := ecdsaKey(t, curve)
+		s, err := NewECDSASigner(alg, key)
+		if err != nil {
+			t.Fatalf("NewECDSASigner(%v): %v", alg, err)
+		}
+		v, err := NewECDSAVerifier(alg, &key.PublicKey)
+		if err != nil {
+			t.Fatalf("NewECDSAVerifier(%v): %v", alg, err)
+		}
+		signers[alg] = [2]interface{}{s, v}
+	}
+
+	key := ed25519Key(t)
+	s, err := NewEd25519Signer(key)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer: %v", err)
+	}
+	v, err := NewEd25519Verifier(key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier: %v", err)
+	}
+	signers[EdDSA] = [2]interface{}{s, v}
+
+	return signers
+}
+
+func TestSigners(t *testing.T) {
+	const input = "eyJhbGciOiJub25lIn0.eyJzdWIiOiIxMjM0NTY3ODkwIn0"
+
+	for alg, pair := range testSigners(t) {
+		t.Run(alg, func(t *testing.T) {
+			signer := pair[0].(Signer)
+			verifier := pair[1].(Verifier)
+
+			if signer.Algorithm() != alg || verifier.Algorithm() != alg {
+				t.Errorf("incorrect algorithm:\nWanted: %v\nGot: %v, %v", alg, signer.Algorithm(), verifier.Algorithm())
+			}
+
+			signature, err := signer.Sign(input)
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+			if err := verifier.Verify(input, signature); err != nil {
+				t.Errorf("failed to verify a valid signature: %v", err)
+			}
+
+			if err := verifier.Verify(input+"x", signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("verified a signature for a modified input:\nWanted: %v\nGot: %v", ErrInvalidSignature, err)
+			}
+
+			tampered := append([]byte{}, signature...)
+			tampered[len(tampered)/2] ^= 0x01
+			if err := verifier.Verify(input, tampered); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("verified a modified signature:\nWanted: %v\nGot: %v", ErrInvalidSignature, err)
+			}
+
+			if err := verifier.Verify(input, signature[:len(signature)-1]); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("verified a truncated signature:\nWanted: %v\nGot: %v", ErrInvalidSignature, err)
+			}
+		})
+	}
+}
+
+/*
+	RFC 8037 Appendix A.4, an Ed25519 signature is deterministic
+	so the published signature can be reproduced
+*/
+func TestEd25519Vector(t *testing.T) {
+	seed, _ := b64.ToBytes("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	signer, err := NewEd25519Signer(ed25519.NewKeyFromSeed(seed))
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+
+	signature, err := signer.Sign("eyJhbGciOiJFZERTQSJ9.RXhhbXBsZSBvZiBFZDI1NTE5IHNpZ25pbmc")
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	want := "hgyY0il_MGCjP0JzlnLWG1PPOt7-09PGcvMg3AIbQR6dWbhijcNR4ki4iylGjg5BhVsPt9g7sVvpAr_MuM0KAg"
+	if got := b64.FromBytes(signature); got != want {
+		t.Errorf("incorrect signature:\nWanted: %v\nGot: %v", want, got)
+	}
+}
+
+func TestSignerErrors(t *testing.T) {
+	weakRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal("failed to generate RSA key")
+	}
+
+	tests := []struct {
+		desc string
+		err  error
+		new  func() error
+	}{
+		{desc: "HMAC none", err: ErrUnsupportedAlgorithm, new: func() error { _, err := NewHMAC("none", "secret"); return err }},
+		{desc: "HMAC RS256", err: ErrUnsupportedAlgorithm, new: func() error { _, err := NewHMAC(RS256, "secret"); return err }},
+		{desc: "HMAC empty secret", err: ErrInvalidKey, new: func() error { _, err := NewHMAC(HS512, ""); return err }},
+		{desc: "RSA HS256", err: ErrUnsupportedAlgorithm, new: func() error { _, err := NewRSASigner(HS256, rsaKey(t)); return err }},
+		{desc: "RSA nil key", err: ErrInvalidKey, new: func() error { _, err := NewRSAVerifier(RS256, nil); return err }},
+		{desc: "RSA 1024 bit key", err: ErrInvalidKey, new: func() error { _, err := NewRSASigner(RS256, weakRSA); return err }},
+		{desc: "ECDSA wrong curve", err: ErrInvalidKey, new: func() error { _, err := NewECDSASigner(ES384, ecdsaKey(t, elliptic.P256())); return err }},
+		{desc: "ECDSA ES512", err: ErrUnsupportedAlgorithm, new: func() error { _, err := NewECDSASigner("ES512", ecdsaKey(t, elliptic.P521())); return err }},
+		{desc: "Ed25519 short key", err: ErrInvalidKey, new: func() error { _, err := NewEd25519Verifier(make([]byte, 31)); return err }},
+		{desc: "Verifier cannot sign", err: ErrInvalidKey, new: func() error {
+			v, _ := NewEd25519Verifier(ed25519Key(t).Public().(ed25519.PublicKey))
+			_, err := v.Sign("input")
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if err := test.new(); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestDecodeAlgorithms(t *testing.T) {
+	signers := testSigners(t)
+
+	for alg, pair := range signers {
+		t.Run(alg, func(t *testing.T) {
+			signer := pair[0].(Signer)
+			verifier := pair[1].(Verifier)
+
+			j := jwtioStruct
+			token := NewToken(j.Issuer, j.Audience[0], j.UserID, j.JwtID, j.LegacyKeyID, 3600)
+			jwt, err := token.Sign(signer)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			lookup := func(keyID, algorithm string) (Verifier, error) {
+				if keyID != j.LegacyKeyID {
+					return nil, errors.New("unknown key")
+				}
+				return verifier, nil
+			}
+
+			got := Token{Config: Config{Lifespan: 3600}}
+			if err := Decode(jwt, nil, &got, WithAlgorithms(alg), WithKeyLookup(lookup)); err != nil {
+				t.Fatalf("failed to decode token: %v", err)
+			}
+			if got.Algorithm != alg || got.UserID != j.UserID {
+				t.Errorf("incorrect token decoded:\nWanted: %v %v\nGot: %v %v", alg, j.UserID, got.Algorithm, got.UserID)
+			}
+
+			// the default allowlist is HS512 only
+			got = Token{Config: Config{Lifespan: 3600}}
+			err = Decode(jwt, nil, &got, WithKeyLookup(lookup))
+			if (alg == HS512) != (err == nil) {
+				t.Errorf("unexpected result with the default allowlist: %v", err)
+			}
+
+			// a verifier for a different algorithm must not be used
+			other := signers[HS256][1].(Verifier)
+			if alg == HS256 {
+				other = signers[HS384][1].(Verifier)
+			}
+			got = Token{Config: Config{Lifespan: 3600}}
+			err = Decode(jwt, nil, &got, WithAlgorithms(alg), WithKeyLookup(func(string, string) (Verifier, error) { return other, nil }))
+			if !errors.Is(err, ErrInvalidToken) || !reflect.DeepEqual(got.Payload, Payload{}) {
+				t.Errorf("decoded with a verifier for the wrong algorithm:\nWanted: %v\nGot: %v", ErrInvalidToken, err)
+			}
+		})
+	}
+}
+
+/*
+	An attacker who knows the RSA public key can sign an HS256 token using the
+	public key as the HMAC secret.  Restricting the algorithms to RS256 must
+	reject the token before the key lookup can be confused into returning the secret.
+*/
+func TestDecodeAlgorithmConfusion(t *testing.T) {
+	publicKey := &rsaKey(t).PublicKey
+	forged, err := NewHMAC(HS256, publicKey.N.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := NewToken("iss", "aud", "admin", "jti", "kid", 3600)
+	jwt, err := token.Sign(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(jwt, "eyJhbGciOiJIUzI1NiIs") {
+		t.Fatalf("token not signed with HS256: %v", jwt)
+	}
+
+	lookups := 0
+	naiveSecret := func(keyID string) string {
+		lookups++
+		return publicKey.N.String()
+	}
+
+	got := Token{Config: Config{Lifespan: 3600}}
+	if err := Decode(jwt, naiveSecret, &got, WithAlgorithms(RS256)); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("accepted a token with an algorithm outside of the allowlist:\nWanted: %v\nGot: %v", ErrInvalidToken, err)
+	}
+	if lookups != 0 {
+		t.Errorf("key lookup called for a disallowed algorithm")
+	}
+}
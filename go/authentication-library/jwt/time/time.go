@@ -4,8 +4,44 @@ import (
 	"time"
 )
 
+// Clock provides the current time.
+// Token creation and validation take a Clock, so tests can fix the time,
+// and check expired or not yet valid tokens deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the Clock using the system time
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed returns a Clock that always returns the provided unix time
+func Fixed(unix int64) Clock {
+	return fixedClock(unix)
+}
+
+type fixedClock int64
+
+func (c fixedClock) Now() time.Time {
+	return time.Unix(int64(c), 0)
+}
+
+// Unix returns the clock's current time as UTC unix time,
+// using the System clock if the clock is nil
+func Unix(c Clock) int64 {
+	if c == nil {
+		c = System
+	}
+	return c.Now().UTC().Unix()
+}
+
 func GetUnix() int64 {
-	return time.Now().UTC().Unix()
+	return Unix(System)
 }
 
 // withinRange checks the number lies between the low and high
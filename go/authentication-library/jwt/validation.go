@@ -0,0 +1,110 @@
+package jwt
+
+import "fmt"
+
+/*
+	Reason is a machine readable code for why Decode rejected a token,
+	suitable for use as a metric label or log field
+*/
+type Reason string
+
+// Reasons
+const (
+	// ReasonMalformed - the token is not three base64 URL encoded JSON sections
+	ReasonMalformed Reason = "malformed"
+
+	// ReasonBadHeader - the "alg", "typ", "cty" or "crit" header is not accepted
+	ReasonBadHeader Reason = "bad_header"
+
+	// ReasonBadSignature - the signature does not match the header and payload
+	ReasonBadSignature Reason = "bad_signature"
+
+	// ReasonExpired - the token has passed its "exp", or is older than its lifespan or MaxAge
+	ReasonExpired Reason = "expired"
+
+	// ReasonNotYetValid - the token's "iat" or "nbf" is in the future
+	ReasonNotYetValid Reason = "not_yet_valid"
+
+	// ReasonIssuedBeforeValidFrom - a time claim is before the server started issuing tokens
+	ReasonIssuedBeforeValidFrom Reason = "issued_before_valid_from"
+
+	// ReasonDecryptionFailed - an encrypted token could not be decrypted with the key for its "kid"
+	ReasonDecryptionFailed Reason = "decryption_failed"
+
+	// ReasonUnknownKeyID - no key could be found for the token's "kid"
+	ReasonUnknownKeyID Reason = "unknown_kid"
+
+	// ReasonInvalidClaim - a claim holds a value the server would not have issued,
+	// or the "iss" or "aud" is not accepted
+	ReasonInvalidClaim Reason = "invalid_claim"
+
+	// ReasonMissingClaim - a claim required by RequireClaims is missing
+	ReasonMissingClaim Reason = "missing_claim"
+)
+
+/*
+	ValidationError is returned by Decode when a token is rejected.
+	Err is the underlying error, usually one of ErrInvalidToken, ErrExpiredToken or ErrFailedSecret,
+	so errors.Is(err, ErrExpiredToken) continues to work, and errors.As gives the reason:
+
+		var ve *jwt.ValidationError
+		if errors.As(err, &ve) {
+			metrics.Inc(string(ve.Reason))
+		}
+
+	errors.Is also matches a ValidationError target with the same Reason,
+	and the same Claim if the target's Claim is set.
+*/
+type ValidationError struct {
+	// Reason is the cause of the failure
+	Reason Reason
+
+	// Claim is the name of the offending header parameter or claim, i.e. "exp", if known
+	Claim string
+
+	// Err is the underlying error
+	Err error
+}
+
+/*
+	Error returns the underlying error with the reason and claim
+*/
+func (e *ValidationError) Error() string {
+	if e.Claim == "" {
+		return fmt.Sprintf("%v (%s)", e.Err, e.Reason)
+	}
+	return fmt.Sprintf("%v (%s: %q)", e.Err, e.Reason, e.Claim)
+}
+
+/*
+	Unwrap returns the underlying error
+*/
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+/*
+	Is matches a *ValidationError target with the same Reason,
+	and the same Claim, unless the target's Claim is empty
+*/
+func (e *ValidationError) Is(target error) bool {
+	t, ok := target.(*ValidationError)
+	if !ok {
+		return false
+	}
+	return t.Reason == e.Reason && (t.Claim == "" || t.Claim == e.Claim)
+}
+
+/*
+	invalid returns a ValidationError for an invalid token
+*/
+func invalid(reason Reason, claim string) *ValidationError {
+	return &ValidationError{Reason: reason, Claim: claim, Err: ErrInvalidToken}
+}
+
+/*
+	expired returns a ValidationError for a valid, but expired token
+*/
+func expired(claim string) *ValidationError {
+	return &ValidationError{Reason: ReasonExpired, Claim: claim, Err: ErrExpiredToken}
+}
@@ -0,0 +1,96 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/markstanden/jwt/time"
+)
+
+func TestValidationErrors(t *testing.T) {
+	const issued = 1700000000
+	const lifespan = 3600
+
+	token := NewTokenWithClock(time.Fixed(issued), "iss", "aud", "sub", "jti", "kid", lifespan)
+	jwt, err := token.Create(jwtioSecret())
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	sections := strings.Split(jwt, ".")
+
+	tests := []struct {
+		desc      string
+		jwt       string
+		secret    func(string) string
+		now       int64
+		validFrom int64
+		options   []DecodeOption
+		reason    Reason
+		claim     string
+		err       error
+	}{
+		{desc: "Malformed", jwt: sections[0] + "." + sections[1], reason: ReasonMalformed, err: ErrInvalidToken},
+		{desc: "Bad header", jwt: resign(t, `{"alg":"HS512","typ":"JOSE","kid":"kid"}`, jwt), reason: ReasonBadHeader, claim: "typ", err: ErrInvalidToken},
+		{desc: "Algorithm not allowed", jwt: jwt, options: []DecodeOption{WithAlgorithms(HS256)}, reason: ReasonBadHeader, claim: "alg", err: ErrInvalidToken},
+		{desc: "Bad signature", jwt: sections[0] + "." + sections[1] + "." + sections[2][1:] + "A", reason: ReasonBadSignature, err: ErrInvalidToken},
+		{desc: "Unknown kid", jwt: jwt, secret: func(string) string { return "" }, reason: ReasonUnknownKeyID, claim: "kid", err: ErrFailedSecret},
+		{desc: "Expired", jwt: jwt, now: issued + lifespan + 1, reason: ReasonExpired, claim: "exp", err: ErrExpiredToken},
+		{desc: "Not yet valid", jwt: jwt, now: issued - 1, reason: ReasonNotYetValid, claim: "iat", err: ErrInvalidToken},
+		{desc: "Issued before ValidFrom", jwt: jwt, validFrom: issued + 1, reason: ReasonIssuedBeforeValidFrom, claim: "iat", err: ErrInvalidToken},
+		{desc: "Wrong issuer", jwt: jwt, options: []DecodeOption{WithIssuer("other")}, reason: ReasonInvalidClaim, claim: "iss", err: ErrInvalidIssuer},
+		{desc: "Missing claim", jwt: jwt, options: []DecodeOption{RequireClaims("scope")}, reason: ReasonMissingClaim, claim: "scope", err: ErrMissingClaim},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if test.secret == nil {
+				test.secret = jwtioSecret()
+			}
+			if test.now == 0 {
+				test.now = issued
+			}
+			options := append([]DecodeOption{WithClock(time.Fixed(test.now))}, test.options...)
+
+			got := Token{Config: Config{ValidFrom: test.validFrom, Lifespan: lifespan}}
+			err := Decode(test.jwt, test.secret, &got, options...)
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("not a ValidationError:\nWanted: %v\nGot: %v", test.reason, err)
+			}
+			if ve.Reason != test.reason || ve.Claim != test.claim {
+				t.Errorf("incorrect reason:\nWanted: %v %q\nGot: %v %q", test.reason, test.claim, ve.Reason, ve.Claim)
+			}
+			if !errors.Is(err, test.err) {
+				t.Errorf("incorrect underlying error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if !errors.Is(err, &ValidationError{Reason: test.reason}) {
+				t.Errorf("errors.Is does not match the reason %v: %v", test.reason, err)
+			}
+		})
+	}
+}
+
+func TestValidationErrorIs(t *testing.T) {
+	err := error(expired("exp"))
+
+	tests := []struct {
+		desc   string
+		target error
+		want   bool
+	}{
+		{desc: "Sentinel", target: ErrExpiredToken, want: true},
+		{desc: "Other sentinel", target: ErrInvalidToken, want: false},
+		{desc: "Reason", target: &ValidationError{Reason: ReasonExpired}, want: true},
+		{desc: "Reason and claim", target: &ValidationError{Reason: ReasonExpired, Claim: "exp"}, want: true},
+		{desc: "Other claim", target: &ValidationError{Reason: ReasonExpired, Claim: "iat"}, want: false},
+		{desc: "Other reason", target: &ValidationError{Reason: ReasonNotYetValid}, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := errors.Is(err, test.target); got != test.want {
+				t.Errorf("errors.Is(%v, %v)\nWanted: %v\nGot: %v", err, test.target, test.want, got)
+			}
+		})
+	}
+}
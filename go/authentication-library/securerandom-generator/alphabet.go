@@ -0,0 +1,186 @@
+package securerandom
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidAlphabet is returned for alphabets that are too small, too large,
+// or that repeat a character, any of which would bias the output
+var ErrInvalidAlphabet = errors.New("securerandom: invalid alphabet")
+
+// Alphabet is the set of characters that random codes are drawn from.
+// Create custom alphabets with NewAlphabet.
+type Alphabet struct {
+	chars []rune
+}
+
+// Preset alphabets for codes that people read back and type
+var (
+	// Digits for numeric one time passwords
+	Digits = mustAlphabet("0123456789")
+
+	// CrockfordBase32 omits I, L, O and U, so codes can't be misread,
+	// and NormalizeCrockford can correct common mistakes when they are typed.
+	CrockfordBase32 = mustAlphabet("0123456789ABCDEFGHJKMNPQRSTVWXYZ")
+
+	// HumanSafe is mixed case letters and digits, without the characters
+	// that are easily confused: 0 O o, 1 I l
+	HumanSafe = mustAlphabet("23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz")
+)
+
+// NewAlphabet returns an Alphabet of the characters in chars,
+// which must contain between 2 and 256 unique characters.
+func NewAlphabet(chars string) (Alphabet, error) {
+	if !utf8.ValidString(chars) {
+		return Alphabet{}, fmt.Errorf("%w: invalid UTF-8", ErrInvalidAlphabet)
+	}
+
+	runes := []rune(chars)
+	if len(runes) < 2 || len(runes) > 256 {
+		return Alphabet{}, fmt.Errorf("%w: %d characters, must be between 2 and 256", ErrInvalidAlphabet, len(runes))
+	}
+
+	seen := make(map[rune]bool, len(runes))
+	for _, r := range runes {
+		if seen[r] {
+			return Alphabet{}, fmt.Errorf("%w: repeated character %q", ErrInvalidAlphabet, r)
+		}
+		seen[r] = true
+	}
+	return Alphabet{chars: runes}, nil
+}
+
+func mustAlphabet(chars string) Alphabet {
+	a, err := NewAlphabet(chars)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// String returns the characters of the alphabet
+func (a Alphabet) String() string {
+	return string(a.chars)
+}
+
+// Bits returns the entropy, in bits, of each character drawn from the alphabet
+func (a Alphabet) Bits() float64 {
+	if len(a.chars) == 0 {
+		return 0
+	}
+	return math.Log2(float64(len(a.chars)))
+}
+
+// FromAlphabet returns a string of length characters drawn uniformly from the alphabet.
+// Random bytes are masked to the smallest power of two covering the alphabet,
+// and values beyond the alphabet are rejected and redrawn, so there is no modulo bias.
+func (g Generator) FromAlphabet(a Alphabet, length uint) (code string, err error) {
+	if len(a.chars) < 2 {
+		return "", fmt.Errorf("%w: empty alphabet", ErrInvalidAlphabet)
+	}
+
+	indexes, err := g.indexes(len(a.chars), length)
+	if err != nil {
+		return "", err
+	}
+
+	out := make([]rune, length)
+	for i, index := range indexes {
+		out[i] = a.chars[index]
+	}
+	return string(out), nil
+}
+
+// indexes returns count integers drawn uniformly from [0, n), for n up to 65536.
+// Random values, one byte wide or two for larger n, are masked to the smallest
+// power of two covering n, and values of n or more are rejected and redrawn.
+func (g Generator) indexes(n int, count uint) (indexes []int, err error) {
+	mask := 1
+	for mask < n {
+		mask <<= 1
+	}
+	mask--
+
+	width := uint(1)
+	if n > 256 {
+		width = 2
+	}
+
+	indexes = make([]int, 0, count)
+	for uint(len(indexes)) < count {
+
+		// Draw enough bytes for the remaining values, allowing for rejections
+		bs, err := g.Bytes(2 * width * (count - uint(len(indexes))))
+		if err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < len(bs); j += int(width) {
+			i := int(bs[j])
+			if width == 2 {
+				i = i<<8 | int(bs[j+1])
+			}
+			i &= mask
+			if i >= n {
+				continue
+			}
+			indexes = append(indexes, i)
+			if uint(len(indexes)) == count {
+				break
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// Grouped returns a code of groups of groupSize characters drawn from the alphabet,
+// joined by the separator, i.e. Grouped(CrockfordBase32, 2, 4, "-") returns "XXXX-XXXX"
+func (g Generator) Grouped(a Alphabet, groups, groupSize uint, separator string) (code string, err error) {
+	chars, err := g.FromAlphabet(a, groups*groupSize)
+	if err != nil {
+		return "", err
+	}
+
+	runes := []rune(chars)
+	parts := make([]string, groups)
+	for i := range parts {
+		parts[i] = string(runes[uint(i)*groupSize : uint(i+1)*groupSize])
+	}
+	return strings.Join(parts, separator), nil
+}
+
+// OTP returns a numeric one time password of the given number of digits, i.e. 6 or 8.
+// Leading zeros are kept, so the result should be handled as a string.
+func (g Generator) OTP(digits uint) (otp string, err error) {
+	return g.FromAlphabet(Digits, digits)
+}
+
+// RecoveryCode returns a Crockford base32 code in the format XXXX-XXXX-XXXX-XXXX,
+// 80 bits of entropy that can be read back and typed without ambiguity.
+func (g Generator) RecoveryCode() (code string, err error) {
+	return g.Grouped(CrockfordBase32, 4, 4, "-")
+}
+
+// NormalizeCrockford converts a typed Crockford base32 code back to its
+// canonical form, before comparing it with the issued code.
+// Case and separators are ignored, and the commonly confused characters
+// I and L are read as 1, and O as 0.
+func NormalizeCrockford(code string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(code) {
+		switch r {
+		case '-', ' ':
+			continue
+		case 'I', 'L':
+			r = '1'
+		case 'O':
+			r = '0'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,158 @@
+package securerandom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewAlphabet(t *testing.T) {
+	var largest string
+	for r := rune(0x100); r < 0x200; r++ {
+		largest += string(r)
+	}
+
+	tests := []struct {
+		desc  string
+		chars string
+		err   error
+	}{
+		{desc: "Binary", chars: "01"},
+		{desc: "Unicode", chars: "αβγδ"},
+		{desc: "Largest", chars: largest},
+		{desc: "Empty", chars: "", err: ErrInvalidAlphabet},
+		{desc: "Single character", chars: "a", err: ErrInvalidAlphabet},
+		{desc: "Repeated character", chars: "abca", err: ErrInvalidAlphabet},
+		{desc: "Invalid UTF-8", chars: "ab\xff", err: ErrInvalidAlphabet},
+		{desc: "Too large", chars: strings.Repeat("x", 257), err: ErrInvalidAlphabet},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := NewAlphabet(test.chars); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestFromAlphabet(t *testing.T) {
+	tests := []struct {
+		desc     string
+		alphabet Alphabet
+		length   uint
+	}{
+		{desc: "Digits", alphabet: Digits, length: 8},
+		{desc: "Crockford", alphabet: CrockfordBase32, length: 26},
+		{desc: "Human Safe", alphabet: HumanSafe, length: 100},
+		{desc: "Zero length", alphabet: Digits, length: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			code, err := Generator{}.FromAlphabet(test.alphabet, test.length)
+			if err != nil {
+				t.Fatalf("failed to create code: %v", err)
+			}
+			if uint(len([]rune(code))) != test.length {
+				t.Errorf("incorrect length: wanted %d, got %d - %v", test.length, len(code), code)
+			}
+			for _, r := range code {
+				if !strings.ContainsRune(test.alphabet.String(), r) {
+					t.Errorf("character %q not in alphabet", r)
+				}
+			}
+		})
+	}
+
+	if _, err := (Generator{}).FromAlphabet(Alphabet{}, 8); !errors.Is(err, ErrInvalidAlphabet) {
+		t.Errorf("zero value alphabet used: %v", err)
+	}
+}
+
+func TestFromAlphabetRejection(t *testing.T) {
+
+	// Digits are masked to 0-15, so 10-15 must be rejected
+	// rather than wrapped around to bias the low digits
+	source := []byte{10, 15, 3, 0x1f, 255, 9, 26, 11, 7, 4, 0, 0}
+	g := Generator{Source: bytes.NewReader(source)}
+
+	otp, err := g.FromAlphabet(Digits, 4)
+	if err != nil {
+		t.Fatalf("failed to create code: %v", err)
+	}
+	if otp != "3974" {
+		t.Errorf("incorrect rejection sampling:\nWanted: 3974\nGot: %v", otp)
+	}
+}
+
+func TestFromAlphabetUniform(t *testing.T) {
+
+	// Every character of a 10 character alphabet should appear
+	// close to 10% of the time
+	const n = 100000
+	code, err := Generator{}.FromAlphabet(Digits, n)
+	if err != nil {
+		t.Fatalf("failed to create code: %v", err)
+	}
+	counts := make(map[rune]int)
+	for _, r := range code {
+		counts[r]++
+	}
+	for _, r := range Digits.String() {
+		if counts[r] < n/10*9/10 || counts[r] > n/10*11/10 {
+			t.Errorf("biased output, %q appeared %d times in %d", r, counts[r], n)
+		}
+	}
+}
+
+func TestGeneratorCodes(t *testing.T) {
+	g := Generator{}
+
+	otp, err := g.OTP(6)
+	if err != nil || !regexp.MustCompile(`^[0-9]{6}$`).MatchString(otp) {
+		t.Errorf("invalid OTP: %q %v", otp, err)
+	}
+
+	recovery, err := g.RecoveryCode()
+	if err != nil || !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{4}(-[0-9A-HJKMNP-TV-Z]{4}){3}$`).MatchString(recovery) {
+		t.Errorf("invalid recovery code: %q %v", recovery, err)
+	}
+
+	grouped, err := g.Grouped(HumanSafe, 3, 5, " ")
+	if err != nil || len(grouped) != 17 || strings.Count(grouped, " ") != 2 {
+		t.Errorf("invalid grouped code: %q %v", grouped, err)
+	}
+
+	if _, err := (Generator{Source: bytes.NewReader(nil)}).OTP(6); !errors.Is(err, ErrEntropy) {
+		t.Errorf("unexpected error from a failing source: %v", err)
+	}
+}
+
+func TestNormalizeCrockford(t *testing.T) {
+	tests := map[string]string{
+		"ABCD-EFGH":   "ABCDEFGH",
+		"abcd efgh":   "ABCDEFGH",
+		"OIL0-1abc":   "01101ABC",
+		"7ZQ9-4M2P-X": "7ZQ94M2PX",
+	}
+	for typed, want := range tests {
+		if got := NormalizeCrockford(typed); got != want {
+			t.Errorf("NormalizeCrockford(%q):\nWanted: %q\nGot: %q", typed, want, got)
+		}
+	}
+}
+
+func ExampleGenerator_RecoveryCode() {
+	// create a recovery code to show the user once, storing only its hash
+	code, err := Generator{}.RecoveryCode()
+	if err != nil {
+		return
+	}
+
+	// when the user types the code back, normalise it before comparing
+	typed := strings.ToLower(code)
+	fmt.Println(NormalizeCrockford(typed) == NormalizeCrockford(code))
+	// Output: true
+}
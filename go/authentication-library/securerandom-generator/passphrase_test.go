@@ -0,0 +1,128 @@
+package securerandom
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWordlist(t *testing.T) {
+	if len(effLarge) != 7776 {
+		t.Fatalf("incorrect wordlist length: wanted 7776, got %d", len(effLarge))
+	}
+	if effLarge[0] != "abacus" || effLarge[7775] != "zoom" {
+		t.Errorf("incorrect wordlist bounds: %q ... %q", effLarge[0], effLarge[7775])
+	}
+	seen := make(map[string]bool)
+	for _, word := range effLarge {
+		if seen[word] || strings.ContainsAny(word, "\t\r ") {
+			t.Errorf("invalid word in wordlist: %q", word)
+		}
+		seen[word] = true
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	tests := []struct {
+		desc    string
+		options PassphraseOptions
+		words   int
+		entropy float64
+	}{
+		{desc: "Default", options: DefaultPassphrase(), words: 6, entropy: 77.5},
+		{desc: "Temporary password", options: TemporaryPassword(), words: 4, entropy: 55.0},
+		{desc: "Single word", options: PassphraseOptions{Words: 1, Separator: " "}, words: 1, entropy: 12.9},
+		{desc: "Digit and symbol", options: PassphraseOptions{Words: 5, Separator: ".", Digit: true, Symbol: true}, words: 5, entropy: 71.7},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if math.Abs(test.options.Entropy()-test.entropy) > 0.1 {
+				t.Errorf("incorrect entropy:\nWanted: %.1f\nGot: %.1f", test.entropy, test.options.Entropy())
+			}
+
+			passphrase, err := Generator{}.Passphrase(test.options)
+			if err != nil {
+				t.Fatalf("failed to create passphrase: %v", err)
+			}
+
+			words := splitPassphrase(passphrase, test.options.Separator)
+			if len(words) != test.words {
+				t.Fatalf("incorrect number of words:\nWanted: %d\nGot: %d - %q", test.words, len(words), passphrase)
+			}
+
+			for _, word := range words {
+				trimmed := strings.TrimRight(word, Digits.String()+passphraseSymbols)
+				if !contains(effLarge, trimmed) {
+					t.Errorf("%q is not in the wordlist", trimmed)
+				}
+			}
+			if test.options.Digit != (strings.ContainsAny(passphrase, Digits.String())) {
+				t.Errorf("digit option %v ignored: %q", test.options.Digit, passphrase)
+			}
+			if test.options.Symbol != (strings.ContainsAny(passphrase, passphraseSymbols)) {
+				t.Errorf("symbol option %v ignored: %q", test.options.Symbol, passphrase)
+			}
+		})
+	}
+}
+
+func TestPassphraseDeterministic(t *testing.T) {
+
+	// 0x00 0x00 selects "abacus", 0x1e 0x5f selects "zoom", the 7776th word,
+	// and 0x1e 0x60 is outside the wordlist so is rejected.
+	// Then the digit 7 and the symbol "!" are added to the second word
+	source := []byte{
+		0x00, 0x00, 0x1e, 0x60, 0x1e, 0x5f, 0x00, 0x00,
+		0x01, 0x00,
+		0x07, 0x00,
+		0x01, 0x00,
+		0x00, 0x00,
+	}
+	g := Generator{Source: bytes.NewReader(source)}
+
+	passphrase, err := g.Passphrase(PassphraseOptions{Words: 2, Separator: " ", Digit: true, Symbol: true})
+	if err != nil {
+		t.Fatalf("failed to create passphrase: %v", err)
+	}
+	if want := "abacus zoom7!"; passphrase != want {
+		t.Errorf("incorrect passphrase:\nWanted: %q\nGot: %q", want, passphrase)
+	}
+}
+
+func TestPassphraseErrors(t *testing.T) {
+	if _, err := (Generator{}).Passphrase(PassphraseOptions{}); !errors.Is(err, ErrInvalidPassphrase) {
+		t.Errorf("unexpected error with no words:\nWanted: %v\nGot: %v", ErrInvalidPassphrase, err)
+	}
+
+	g := Generator{Source: iotest.ErrReader(errors.New("no entropy"))}
+	if _, err := g.Passphrase(DefaultPassphrase()); !errors.Is(err, ErrEntropy) {
+		t.Errorf("unexpected error from a failing source:\nWanted: %v\nGot: %v", ErrEntropy, err)
+	}
+}
+
+// splitPassphrase splits a passphrase into its words, rejoining
+// the wordlist's hyphenated words, such as "t-shirt", split by a hyphen separator
+func splitPassphrase(passphrase, separator string) (words []string) {
+	parts := strings.Split(passphrase, separator)
+	for i := 0; i < len(parts); i++ {
+		word := parts[i]
+		if i+1 < len(parts) && contains(effLarge, word+separator+strings.TrimRight(parts[i+1], Digits.String()+passphraseSymbols)) {
+			word += separator + parts[i+1]
+			i++
+		}
+		words = append(words, word)
+	}
+	return words
+}
+
+func contains(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
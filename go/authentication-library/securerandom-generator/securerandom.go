@@ -3,41 +3,61 @@ package securerandom
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
 	"math"
 )
 
-// modified version of example here:
-// https://golang.org/pkg/crypto/rand/#example_Read
+// ErrEntropy is returned when the Generator's Source
+// fails to provide the requested number of random bytes
+var ErrEntropy = errors.New("securerandom: failed to read from entropy source")
 
-// ByteSlice produces a slice of random bytes, of a provided length
-// also used as the base for other outputs
-// returns nil if it fails to make the []byte,
-// or if the created slice is the wrong size
-func ByteSlice(length uint) (bs []byte) {
+// Generator produces random byte slices and strings from its Source.
+// The zero value uses crypto/rand, and is safe for concurrent use.
+// Tests can supply a deterministic Source, or one that fails,
+// to exercise code that depends on random values.
+type Generator struct {
+	Source io.Reader
+}
 
-	bs = make([]byte, length)
-	r, err := rand.Read(bs)
-	if err != nil {
-		return nil
+// New returns a Generator using the operating system's
+// cryptographically secure random number generator
+func New() Generator {
+	return Generator{Source: rand.Reader}
+}
+
+// source returns the Generator's Source, defaulting to crypto/rand
+func (g Generator) source() io.Reader {
+	if g.Source == nil {
+		return rand.Reader
 	}
-	if r != int(length) {
-		return nil
+	return g.Source
+}
+
+// Bytes produces a slice of random bytes, of a provided length.
+// An error wrapping ErrEntropy is returned if the source fails
+// to fill the slice.
+func (g Generator) Bytes(length uint) (bs []byte, err error) {
+
+	bs = make([]byte, length)
+	if _, err := io.ReadFull(g.source(), bs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEntropy, err)
 	}
-	return bs
+	return bs, nil
 }
 
-// String returns a secure URL compliant string of a provided length
-// returns a zero value string if it fails to create the message
-func String(length uint) (message string) {
+// String returns a secure URL compliant string of a provided length.
+// An error wrapping ErrEntropy is returned if the source fails.
+func (g Generator) String(length uint) (message string, err error) {
 
 	// base64 uses 4 characters to represent 3 bytes so convert
 	b := ((3 * float64(length)) / 4)
 	requiredBytes := math.Ceil(b)
 
-	bs := ByteSlice(uint(requiredBytes))
-
-	if len(bs) == 0 {
-		return ""
+	bs, err := g.Bytes(uint(requiredBytes))
+	if err != nil {
+		return "", err
 	}
 
 	// encode to URL encoded base 64 string
@@ -47,5 +67,32 @@ func String(length uint) (message string) {
 	// since every byte produces 1.333 characters
 	// we get extra characters in our string if the length doesn't cleanly divide
 	// so trim the end
-	return message[:length]
+	return message[:length], nil
+}
+
+// modified version of example here:
+// https://golang.org/pkg/crypto/rand/#example_Read
+
+// ByteSlice produces a slice of random bytes, of a provided length
+// also used as the base for other outputs
+// returns nil if it fails to make the []byte,
+// or if the created slice is the wrong size.
+// Use Generator.Bytes to handle the error.
+func ByteSlice(length uint) (bs []byte) {
+	bs, err := Generator{}.Bytes(length)
+	if err != nil {
+		return nil
+	}
+	return bs
+}
+
+// String returns a secure URL compliant string of a provided length
+// returns a zero value string if it fails to create the message.
+// Use Generator.String to handle the error.
+func String(length uint) (message string) {
+	message, err := Generator{}.String(length)
+	if err != nil {
+		return ""
+	}
+	return message
 }
@@ -1,11 +1,14 @@
 package securerandom
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha512"
+	"errors"
 	"fmt"
 	"log"
 	"testing"
+	"testing/iotest"
 )
 
 func TestByteSlice(t *testing.T) {
@@ -38,6 +41,52 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestGenerator(t *testing.T) {
+
+	/*
+		A deterministic source produces repeatable values
+	*/
+	source := bytes.Repeat([]byte{0xfb, 0xff, 0x00}, 100)
+	g := Generator{Source: bytes.NewReader(source)}
+
+	bs, err := g.Bytes(6)
+	if err != nil || !bytes.Equal(bs, source[:6]) {
+		t.Errorf("incorrect bytes from source: %v, %v", bs, err)
+	}
+	s, err := g.String(8)
+	if err != nil || s != "-_8A-_8A" {
+		t.Errorf("incorrect string from source: %q, %v", s, err)
+	}
+
+	/*
+		Failing or exhausted sources return ErrEntropy
+	*/
+	failing := []struct {
+		desc string
+		g    Generator
+	}{
+		{desc: "Source error", g: Generator{Source: iotest.ErrReader(errors.New("no entropy"))}},
+		{desc: "Short source", g: Generator{Source: bytes.NewReader([]byte{1, 2, 3})}},
+	}
+	for _, test := range failing {
+		t.Run(test.desc, func(t *testing.T) {
+			if bs, err := test.g.Bytes(16); bs != nil || !errors.Is(err, ErrEntropy) {
+				t.Errorf("unexpected result from Bytes: %v, %v", bs, err)
+			}
+			if s, err := test.g.String(16); s != "" || !errors.Is(err, ErrEntropy) {
+				t.Errorf("unexpected result from String: %q, %v", s, err)
+			}
+		})
+	}
+
+	/*
+		The zero value uses crypto/rand
+	*/
+	if bs, err := (Generator{}).Bytes(32); err != nil || len(bs) != 32 {
+		t.Errorf("zero value Generator failed: %v", err)
+	}
+}
+
 func ExampleByteSlice() {
 	// create a secure slice of bytes for use as a cryto key in a hash
 
@@ -83,4 +132,4 @@ func ExampleString() {
 
 	// Use the new user
 	fmt.Printf("User ID: \n%v\nName: \n%v\nEmail:\n%v", u.id, u.name, u.email)
-}
\ No newline at end of file
+}
@@ -0,0 +1,95 @@
+package securerandom
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidULID is returned when parsing a string that is not a ULID
+var ErrInvalidULID = errors.New("securerandom: invalid ULID")
+
+// ULID is a Universally Unique Lexicographically Sortable Identifier,
+// the unix time in milliseconds followed by 80 random bits,
+// written as 26 Crockford base32 characters.
+type ULID [16]byte
+
+// crockfordDecode maps Crockford base32 characters, of either case, to their values
+var crockfordDecode = func() (table [256]byte) {
+	for i := range table {
+		table[i] = 0xff
+	}
+	for i, r := range CrockfordBase32.String() {
+		table[r] = byte(i)
+		table[r|0x20] = byte(i)
+	}
+	return table
+}()
+
+// ULID returns a new ULID for the current time
+func (g Generator) ULID() (id ULID, err error) {
+	bs, err := g.Bytes(10)
+	if err != nil {
+		return ULID{}, err
+	}
+	putMillis(id[:6], now())
+	copy(id[6:], bs)
+	return id, nil
+}
+
+// Time returns the time the ULID was created
+func (id ULID) Time() time.Time {
+	return millisTime(id[:6])
+}
+
+// String returns the 26 character Crockford base32 form of the ULID
+func (id ULID) String() string {
+	alphabet := CrockfordBase32.String()
+	hi := uint64(id[0])<<56 | uint64(id[1])<<48 | uint64(id[2])<<40 | uint64(id[3])<<32 |
+		uint64(id[4])<<24 | uint64(id[5])<<16 | uint64(id[6])<<8 | uint64(id[7])
+	lo := uint64(id[8])<<56 | uint64(id[9])<<48 | uint64(id[10])<<40 | uint64(id[11])<<32 |
+		uint64(id[12])<<24 | uint64(id[13])<<16 | uint64(id[14])<<8 | uint64(id[15])
+
+	// 26 characters of 5 bits hold 130 bits, so the first holds only 3
+	buf := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		buf[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf)
+}
+
+// ParseULID parses a 26 character ULID, ignoring case
+func ParseULID(s string) (id ULID, err error) {
+	if len(s) != 26 {
+		return ULID{}, fmt.Errorf("%w: %q", ErrInvalidULID, s)
+	}
+
+	// the first character holds 3 bits, larger values overflow 128 bits
+	if crockfordDecode[s[0]] > 7 {
+		return ULID{}, fmt.Errorf("%w: %q", ErrInvalidULID, s)
+	}
+
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		v := crockfordDecode[s[i]]
+		if v == 0xff {
+			return ULID{}, fmt.Errorf("%w: %q", ErrInvalidULID, s)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+
+	for i := 0; i < 8; i++ {
+		id[i] = byte(hi >> (56 - 8*i))
+		id[8+i] = byte(lo >> (56 - 8*i))
+	}
+	return id, nil
+}
+
+// ValidULID returns true if s is a ULID
+func ValidULID(s string) bool {
+	_, err := ParseULID(s)
+	return err == nil
+}
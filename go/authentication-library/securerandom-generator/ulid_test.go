@@ -0,0 +1,63 @@
+package securerandom
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestULID(t *testing.T) {
+
+	// example from the ULID specification
+	const example = "01ARYZ6S41TSV4RRFFQ69G5FAV"
+	id, err := ParseULID(example)
+	if err != nil {
+		t.Fatalf("failed to parse ULID: %v", err)
+	}
+	if id.String() != example {
+		t.Errorf("incorrect round trip:\nWanted: %v\nGot: %v", example, id)
+	}
+	if want := time.Unix(0, 1469918176385*int64(time.Millisecond)); !id.Time().Equal(want) {
+		t.Errorf("incorrect time:\nWanted: %v\nGot: %v", want, id.Time())
+	}
+
+	fixed := time.Unix(1645557742, 123*int64(time.Millisecond))
+	fixNow(t, fixed)
+	id, err = Generator{}.ULID()
+	if err != nil {
+		t.Fatalf("failed to create ULID: %v", err)
+	}
+	if !id.Time().Equal(fixed) {
+		t.Errorf("incorrect time:\nWanted: %v\nGot: %v", fixed, id.Time())
+	}
+	parsed, err := ParseULID(id.String())
+	if err != nil || parsed != id {
+		t.Errorf("incorrect round trip:\nWanted: %v\nGot: %v %v", id, parsed, err)
+	}
+}
+
+func TestParseULID(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		err   error
+	}{
+		{desc: "Valid", input: "01ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{desc: "Lower case", input: "01arz3ndektsv4rrffq69g5fav"},
+		{desc: "Largest", input: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{desc: "Overflow", input: "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", err: ErrInvalidULID},
+		{desc: "Too short", input: "01ARZ3NDEKTSV4RRFFQ69G5FA", err: ErrInvalidULID},
+		{desc: "Excluded letter", input: "01ARZ3NDEKTSV4RRFFQ69G5FAU", err: ErrInvalidULID},
+		{desc: "UUID", input: "017f22e2-79b0-7cc3-98c4-dc0", err: ErrInvalidULID},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := ParseULID(test.input); !errors.Is(err, test.err) {
+				t.Errorf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if ValidULID(test.input) != (test.err == nil) {
+				t.Errorf("ValidULID(%q) disagrees with ParseULID", test.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package securerandom
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidUUID is returned when parsing a string that is not
+// a canonical RFC 9562 UUID
+var ErrInvalidUUID = errors.New("securerandom: invalid UUID")
+
+// now returns the current time for time ordered identifiers.
+// It is a variable so the tests can fix the time.
+var now = time.Now
+
+// UUID is an RFC 9562 universally unique identifier
+type UUID [16]byte
+
+// UUIDv4 returns a version 4 UUID, with 122 random bits
+func (g Generator) UUIDv4() (id UUID, err error) {
+	bs, err := g.Bytes(16)
+	if err != nil {
+		return UUID{}, err
+	}
+	copy(id[:], bs)
+	id.setVersion(4)
+	return id, nil
+}
+
+// UUIDv7 returns a version 7 UUID, the unix time in milliseconds
+// followed by 74 random bits.  Version 7 UUIDs sort by the time they
+// were created, so make good database keys, and unlike a sequence
+// they can't be enumerated.
+func (g Generator) UUIDv7() (id UUID, err error) {
+	bs, err := g.Bytes(10)
+	if err != nil {
+		return UUID{}, err
+	}
+	putMillis(id[:6], now())
+	copy(id[6:], bs)
+	id.setVersion(7)
+	return id, nil
+}
+
+// setVersion sets the version nibble and the RFC 9562 variant bits
+func (id *UUID) setVersion(version byte) {
+	id[6] = id[6]&0x0f | version<<4
+	id[8] = id[8]&0x3f | 0x80
+}
+
+// Version returns the UUID version, i.e. 4 or 7
+func (id UUID) Version() int {
+	return int(id[6] >> 4)
+}
+
+// Time returns the creation time of a version 7 UUID,
+// and the zero time for other versions
+func (id UUID) Time() time.Time {
+	if id.Version() != 7 {
+		return time.Time{}
+	}
+	return millisTime(id[:6])
+}
+
+// String returns the canonical form of the UUID,
+// i.e. 0189f7e8-5b3c-7cde-8a1b-3c4d5e6f7a8b
+func (id UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf)
+}
+
+// ParseUUID parses a UUID in the canonical hyphenated form,
+// ignoring case.  Any version is accepted, but the variant must be RFC 9562.
+func ParseUUID(s string) (id UUID, err error) {
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return UUID{}, fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+	}
+
+	digits := strings.Replace(s, "-", "", 4)
+	if _, err := hex.Decode(id[:], []byte(digits)); err != nil || len(digits) != 32 {
+		return UUID{}, fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+	}
+	if id[8]&0xc0 != 0x80 {
+		return UUID{}, fmt.Errorf("%w: unsupported variant %q", ErrInvalidUUID, s)
+	}
+	return id, nil
+}
+
+// ValidUUID returns true if s is a canonical RFC 9562 UUID
+func ValidUUID(s string) bool {
+	_, err := ParseUUID(s)
+	return err == nil
+}
+
+// putMillis writes the unix time in milliseconds as a 48 bit big endian integer
+func putMillis(dst []byte, t time.Time) {
+	var bs [8]byte
+	binary.BigEndian.PutUint64(bs[:], uint64(t.UnixNano()/int64(time.Millisecond)))
+	copy(dst, bs[2:])
+}
+
+// millisTime reads a 48 bit big endian unix time in milliseconds
+func millisTime(src []byte) time.Time {
+	var bs [8]byte
+	copy(bs[2:], src)
+	ms := int64(binary.BigEndian.Uint64(bs[:]))
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
@@ -0,0 +1,119 @@
+package securerandom
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// fixNow sets the time used for time ordered identifiers for the test
+func fixNow(t *testing.T, fixed time.Time) {
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now = time.Now })
+}
+
+func TestUUIDv4(t *testing.T) {
+	canonical := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	seen := make(map[UUID]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := Generator{}.UUIDv4()
+		if err != nil {
+			t.Fatalf("failed to create UUID: %v", err)
+		}
+		if !canonical.MatchString(id.String()) || id.Version() != 4 {
+			t.Fatalf("invalid version 4 UUID: %v", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate UUID: %v", id)
+		}
+		seen[id] = true
+	}
+
+	// all 0xff bytes, so every version and variant bit must be set by the generator
+	id, err := Generator{Source: bytes.NewReader(bytes.Repeat([]byte{0xff}, 16))}.UUIDv4()
+	if err != nil || id.String() != "ffffffff-ffff-4fff-bfff-ffffffffffff" {
+		t.Errorf("incorrect version bits:\nWanted: ffffffff-ffff-4fff-bfff-ffffffffffff\nGot: %v %v", id, err)
+	}
+}
+
+func TestUUIDv7(t *testing.T) {
+
+	// RFC 9562 Appendix A.6 test vector
+	fixNow(t, time.Unix(0, 0x017F22E279B0*int64(time.Millisecond)))
+	random := []byte{0xCC, 0xC3, 0x18, 0xC4, 0xDC, 0x0C, 0x0C, 0x07, 0x39, 0x8F}
+
+	id, err := Generator{Source: bytes.NewReader(random)}.UUIDv7()
+	if err != nil {
+		t.Fatalf("failed to create UUID: %v", err)
+	}
+	if want := "017f22e2-79b0-7cc3-98c4-dc0c0c07398f"; id.String() != want {
+		t.Errorf("incorrect UUID:\nWanted: %v\nGot: %v", want, id)
+	}
+	if want := time.Unix(1645557742, 0); !id.Time().Equal(want) {
+		t.Errorf("incorrect time:\nWanted: %v\nGot: %v", want, id.Time())
+	}
+
+	// identifiers created in later milliseconds sort later
+	var ids []string
+	for i := 0; i < 5; i++ {
+		fixNow(t, time.Unix(1645557742, int64(i)*int64(time.Millisecond)))
+		id, err := Generator{}.UUIDv7()
+		if err != nil {
+			t.Fatalf("failed to create UUID: %v", err)
+		}
+		ids = append(ids, id.String())
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("UUIDs are not time ordered: %v", ids)
+	}
+}
+
+func TestUUIDRandomFailure(t *testing.T) {
+	g := Generator{Source: iotest.ErrReader(errors.New("no entropy"))}
+	if _, err := g.UUIDv4(); !errors.Is(err, ErrEntropy) {
+		t.Errorf("UUIDv4 unexpected error: %v", err)
+	}
+	if _, err := g.UUIDv7(); !errors.Is(err, ErrEntropy) {
+		t.Errorf("UUIDv7 unexpected error: %v", err)
+	}
+	if _, err := g.ULID(); !errors.Is(err, ErrEntropy) {
+		t.Errorf("ULID unexpected error: %v", err)
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	tests := []struct {
+		desc  string
+		input string
+		want  string
+		err   error
+	}{
+		{desc: "Version 7", input: "017f22e2-79b0-7cc3-98c4-dc0c0c07398f", want: "017f22e2-79b0-7cc3-98c4-dc0c0c07398f"},
+		{desc: "Upper case", input: "919108F7-52D1-4320-9BAC-F847DB4148A8", want: "919108f7-52d1-4320-9bac-f847db4148a8"},
+		{desc: "Empty", input: "", err: ErrInvalidUUID},
+		{desc: "Missing hyphens", input: "919108f752d143209bacf847db4148a8", err: ErrInvalidUUID},
+		{desc: "Misplaced hyphen", input: "919108f-752d1-4320-9bac-f847db4148a8", err: ErrInvalidUUID},
+		{desc: "Not hex", input: "919108g7-52d1-4320-9bac-f847db4148a8", err: ErrInvalidUUID},
+		{desc: "Extra hyphen", input: "919108f7-52d1-4320-9bac-f847db41-8a8", err: ErrInvalidUUID},
+		{desc: "Microsoft variant", input: "919108f7-52d1-4320-cbac-f847db4148a8", err: ErrInvalidUUID},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			id, err := ParseUUID(test.input)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error:\nWanted: %v\nGot: %v", test.err, err)
+			}
+			if err == nil && id.String() != test.want {
+				t.Errorf("incorrect UUID:\nWanted: %v\nGot: %v", test.want, id)
+			}
+			if ValidUUID(test.input) != (test.err == nil) {
+				t.Errorf("ValidUUID(%q) disagrees with ParseUUID", test.input)
+			}
+		})
+	}
+}